@@ -0,0 +1,440 @@
+// The gojson command: validates, formats and converts JSON files
+//
+//	gojson validate [files]
+//	gojson fmt [-c] [-i indent] [-s] [-l] [-w] [files]
+//	gojson to-array [files]
+//	gojson to-ndjson [files]
+//	gojson query [-c] [-r] [-s] [-n] filter [files]
+//
+// reads the standard input when there are no files; the exit code is 1 when
+// anything is invalid and 2 for a bad command line
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	json "github.com/jn0/go-json"
+)
+
+const usage = `usage: gojson <command> [flags] [files]
+
+commands:
+  validate   checks the files, prints the errors as file:line:column: message
+  fmt        reformats the files (indented by default, see gojson fmt -h)
+  to-array   makes an array of the NDJSON lines (one value per line)
+  to-ndjson  makes NDJSON lines of the array elements
+  query      runs a jq-like filter over JSON or NDJSON (see gojson query -h)
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// the command itself, with the exit code returned
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	c := &command{stdin: stdin, stdout: stdout, stderr: stderr}
+	switch args[0] {
+	case "validate":
+		return c.validate(args[1:])
+	case "fmt":
+		return c.format(args[1:])
+	case "to-array":
+		return c.toArray(args[1:])
+	case "to-ndjson":
+		return c.toNdjson(args[1:])
+	case "query":
+		return c.query(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	}
+	fmt.Fprintf(stderr, "gojson: unknown command %+q\n", args[0])
+	fmt.Fprint(stderr, usage)
+	return 2
+}
+
+type command struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// an input: a file or the standard input ("<stdin>")
+type input struct {
+	name string
+	text []byte
+}
+
+// reads the files (the standard input if none), reports the ones failed
+func (self *command) inputs(files []string) ([]input, bool) {
+	if len(files) == 0 {
+		b, e := io.ReadAll(self.stdin)
+		if e != nil {
+			fmt.Fprintf(self.stderr, "<stdin>: %v\n", e)
+			return nil, false
+		}
+		return []input{{"<stdin>", b}}, true
+	}
+	var r []input
+	ok := true
+	for _, f := range files {
+		b, e := os.ReadFile(f)
+		if e != nil {
+			fmt.Fprintf(self.stderr, "%v\n", e)
+			ok = false
+			continue
+		}
+		r = append(r, input{f, b})
+	}
+	return r, ok
+}
+
+func (self *command) flags(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(self.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(self.stderr, "usage: gojson %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// prints the error as file:line:column: message (or file: message)
+func (self *command) report(name string, e error) {
+	if pe, is := e.(*json.PositionError); is && pe.Line > 0 {
+		fmt.Fprintf(self.stderr, "%s:%v\n", name, e)
+	} else {
+		fmt.Fprintf(self.stderr, "%s: %v\n", name, e)
+	}
+}
+
+func exitCode(ok bool) int {
+	if ok {
+		return 0
+	}
+	return 1
+}
+
+/*----------------------------------------------------------------------------*/
+
+func (self *command) validate(args []string) int {
+	fs := self.flags("validate", "[files]")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	in, ok := self.inputs(fs.Args())
+	for _, x := range in {
+		if _, e := json.Parse(string(x.text)); e != nil {
+			self.report(x.name, e)
+			ok = false
+		}
+	}
+	return exitCode(ok)
+}
+
+func (self *command) format(args []string) int {
+	fs := self.flags("fmt", "[-c] [-i indent] [-s] [-l] [-w] [files]")
+	compact := fs.Bool("c", false, "compact output (no spaces at all)")
+	indent := fs.String("i", "  ", "the indentation")
+	sortKeys := fs.Bool("s", false, "sort the object members by key (floats get all their digits)")
+	list := fs.Bool("l", false, "list the files whose formatting differs, do not print them")
+	write := fs.Bool("w", false, "write the result back to the files, do not print them")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if (*list || *write) && fs.NArg() == 0 {
+		fmt.Fprintf(self.stderr, "gojson fmt: -l and -w need files\n")
+		return 2
+	}
+	if *compact {
+		*indent = ""
+	}
+	in, ok := self.inputs(fs.Args())
+	for _, x := range in {
+		v, e := json.Parse(string(x.text))
+		if e != nil {
+			self.report(x.name, e)
+			ok = false
+			continue
+		}
+		var s string
+		if *sortKeys {
+			if s, e = json.Format(v, *indent); e != nil {
+				self.report(x.name, e)
+				ok = false
+				continue
+			}
+		} else {
+			s = reformat(string(x.text), *indent)
+		}
+		s += "\n"
+		changed := s != string(x.text)
+		if *list && changed {
+			fmt.Fprintln(self.stdout, x.name)
+		}
+		if *write && changed {
+			if e := writeFile(x.name, []byte(s)); e != nil {
+				fmt.Fprintf(self.stderr, "%v\n", e)
+				ok = false
+			}
+		}
+		if !*list && !*write {
+			io.WriteString(self.stdout, s)
+		}
+	}
+	return exitCode(ok)
+}
+
+// replaces the file keeping its permissions
+func writeFile(name string, b []byte) error {
+	st, e := os.Stat(name)
+	if e != nil {
+		return e
+	}
+	return os.WriteFile(name, b, st.Mode().Perm())
+}
+
+// re-lays out the (valid) JSON text keeping the order of the members and the
+// literals as they are: only the spaces between the tokens change
+func reformat(s, indent string) string {
+	var b strings.Builder
+	colon := ":"
+	if indent != "" {
+		colon = ": "
+	}
+	depth := 0
+	newline := func() {
+		if indent != "" {
+			b.WriteString("\n" + strings.Repeat(indent, depth))
+		}
+	}
+	// the next token, not a space
+	next := func(i int) int {
+		for i < len(s) && strings.IndexByte(" \t\r\n", s[i]) >= 0 {
+			i++
+		}
+		return i
+	}
+	for i := next(0); i < len(s); i = next(i) {
+		switch c := s[i]; c {
+		case '{', '[':
+			i++
+			if j := next(i); j < len(s) && (s[j] == '}' || s[j] == ']') {
+				b.WriteByte(c)
+				b.WriteByte(s[j])
+				i = j + 1
+				continue
+			}
+			b.WriteByte(c)
+			depth++
+			newline()
+		case '}', ']':
+			depth--
+			newline()
+			b.WriteByte(c)
+			i++
+		case ',':
+			b.WriteByte(c)
+			newline()
+			i++
+		case ':':
+			b.WriteString(colon)
+			i++
+		case '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			b.WriteString(s[i : j+1])
+			i = j + 1
+		default: // numbers and literals
+			j := i
+			for j < len(s) && strings.IndexByte(" \t\r\n,:]}", s[j]) < 0 {
+				j++
+			}
+			b.WriteString(s[i:j])
+			i = j
+		}
+	}
+	return b.String()
+}
+
+/*----------------------------------------------------------------------------*/
+
+// the values of the non-blank lines, reports the bad ones
+func (self *command) ndjson(x input) ([]json.JsonValue, bool) {
+	var r []json.JsonValue
+	ok := true
+	sc := bufio.NewScanner(bytes.NewReader(x.text))
+	sc.Buffer(nil, len(x.text)+1)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		v, e := json.Parse(line)
+		if e != nil {
+			if pe, is := e.(*json.PositionError); is && pe.Line > 0 {
+				pe.Line = n // one line per value
+			}
+			self.report(x.name, e)
+			ok = false
+			continue
+		}
+		r = append(r, v)
+	}
+	return r, ok
+}
+
+// one value per non-blank line into one array
+func (self *command) toArray(args []string) int {
+	fs := self.flags("to-array", "[files]")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	in, ok := self.inputs(fs.Args())
+	r := json.JsonArray{}
+	for _, x := range in {
+		v, good := self.ndjson(x)
+		r = append(r, v...)
+		ok = ok && good
+	}
+	if !ok {
+		return 1
+	}
+	s, e := json.Format(&r, "  ")
+	if e != nil {
+		fmt.Fprintf(self.stderr, "%v\n", e)
+		return 1
+	}
+	fmt.Fprintln(self.stdout, s)
+	return 0
+}
+
+// the elements of the arrays, one compact value per line
+func (self *command) toNdjson(args []string) int {
+	fs := self.flags("to-ndjson", "[files]")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	in, ok := self.inputs(fs.Args())
+	for _, x := range in {
+		v, e := json.Parse(string(x.text))
+		if e != nil {
+			self.report(x.name, e)
+			ok = false
+			continue
+		}
+		a, e := json.AsArray(v)
+		if e != nil {
+			self.report(x.name, e)
+			ok = false
+			continue
+		}
+		for _, o := range *a {
+			s, e := json.Format(o, "")
+			if e != nil {
+				self.report(x.name, e)
+				ok = false
+				break
+			}
+			fmt.Fprintln(self.stdout, s)
+		}
+	}
+	return exitCode(ok)
+}
+
+/*----------------------------------------------------------------------------*/
+
+// the values of the input: one JSON text or (when the first line is a value
+// by itself) NDJSON
+func (self *command) values(x input) ([]json.JsonValue, bool) {
+	v, e := json.Parse(string(x.text))
+	if e == nil {
+		return []json.JsonValue{v}, true
+	}
+	first := strings.TrimSpace(string(x.text))
+	if i := strings.IndexByte(first, '\n'); i >= 0 {
+		if _, e := json.Parse(first[:i]); e == nil {
+			return self.ndjson(x)
+		}
+	}
+	self.report(x.name, e)
+	return nil, false
+}
+
+// runs the filter over every input value (or over them all as an array, or
+// over null) and prints the outputs
+func (self *command) query(args []string) int {
+	fs := self.flags("query", "[-c] [-r] [-s] [-n] filter [files]")
+	compact := fs.Bool("c", false, "compact output, one value per line")
+	raw := fs.Bool("r", false, "print the strings as they are, not as JSON")
+	slurp := fs.Bool("s", false, "run the filter once over an array of all the input values")
+	null := fs.Bool("n", false, "run the filter once over null, read no input")
+	if fs.Parse(args) != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+	filter, e := json.Compile(fs.Arg(0))
+	if e != nil {
+		fmt.Fprintf(self.stderr, "gojson query: %v\n", e)
+		return 2
+	}
+	indent := "  "
+	if *compact {
+		indent = ""
+	}
+	var values []json.JsonValue
+	ok := true
+	if *null {
+		values = []json.JsonValue{nil}
+	} else {
+		var in []input
+		in, ok = self.inputs(fs.Args()[1:])
+		all := json.JsonArray{}
+		for _, x := range in {
+			v, good := self.values(x)
+			all = append(all, v...)
+			ok = ok && good
+		}
+		values = all
+		if *slurp {
+			values = []json.JsonValue{&all}
+		}
+	}
+	for _, v := range values {
+		r, e := filter.Run(v)
+		for _, o := range r {
+			if s, is := o.(*json.JsonString); is && *raw && s != nil {
+				fmt.Fprintln(self.stdout, string(*s))
+				continue
+			}
+			s, e := json.Format(o, indent)
+			if e != nil {
+				fmt.Fprintf(self.stderr, "gojson query: %v\n", e)
+				ok = false
+				continue
+			}
+			fmt.Fprintln(self.stdout, s)
+		}
+		if e != nil {
+			fmt.Fprintf(self.stderr, "gojson query: %v\n", e)
+			ok = false
+		}
+	}
+	return exitCode(ok)
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGojson(t *testing.T) {
+	exec := func(stdin string, args ...string) (int, string, string) {
+		var out, err bytes.Buffer
+		code := run(args, strings.NewReader(stdin), &out, &err)
+		return code, out.String(), err.String()
+	}
+
+	if code, _, _ := exec(""); code != 2 {
+		t.Errorf("no command: exit %d", code)
+	}
+	if code, _, _ := exec("", "frobnicate"); code != 2 {
+		t.Errorf("unknown command: exit %d", code)
+	}
+	if code, _, _ := exec("", "fmt", "-x"); code != 2 {
+		t.Errorf("unknown flag: exit %d", code)
+	}
+	if code, _, _ := exec("{}", "fmt", "-w"); code != 2 {
+		t.Errorf("-w on stdin: exit %d", code)
+	}
+
+	if code, _, err := exec(`{ "a": [1, 2] }`, "validate"); code != 0 || err != "" {
+		t.Errorf("validate: exit %d, %q", code, err)
+	}
+	if code, _, err := exec("{\n  \"a\": [1, 2 3]\n}", "validate"); code != 1 || !strings.HasPrefix(err, "<stdin>:2:14: ") {
+		t.Errorf("validate bad: exit %d, %q", code, err)
+	}
+
+	text := `{"z": [1, 2.50, {}], "a": {"x": "\u00e9", "y": []}}`
+	if code, out, _ := exec(text, "fmt"); code != 0 ||
+		out != "{\n  \"z\": [\n    1,\n    2.50,\n    {}\n  ],\n  \"a\": {\n    \"x\": \"\\u00e9\",\n    \"y\": []\n  }\n}\n" {
+		t.Errorf("fmt: exit %d\n%s", code, out)
+	}
+	if code, out, _ := exec(text, "fmt", "-c"); code != 0 || out != `{"z":[1,2.50,{}],"a":{"x":"\u00e9","y":[]}}`+"\n" {
+		t.Errorf("fmt -c: exit %d, %s", code, out)
+	}
+	if code, out, _ := exec(text, "fmt", "-s", "-i", "\t"); code != 0 ||
+		out != "{\n\t\"a\": {\n\t\t\"x\": \"é\",\n\t\t\"y\": []\n\t},\n\t\"z\": [\n\t\t1,\n\t\t2.5,\n\t\t{}\n\t]\n}\n" {
+		t.Errorf("fmt -s: exit %d\n%s", code, out)
+	}
+
+	dir := t.TempDir()
+	good, bad, ugly := filepath.Join(dir, "good.json"), filepath.Join(dir, "bad.json"), filepath.Join(dir, "ugly.json")
+	os.WriteFile(good, []byte("[\n  1,\n  2\n]\n"), 0600)
+	os.WriteFile(bad, []byte("[1,,2]"), 0600)
+	os.WriteFile(ugly, []byte("[1,  2]"), 0600)
+	if code, out, err := exec("", "fmt", "-l", good, bad, ugly); code != 1 || out != ugly+"\n" || !strings.HasPrefix(err, bad+":1:4: ") {
+		t.Errorf("fmt -l: exit %d, %q, %q", code, out, err)
+	}
+	if code, _, _ := exec("", "fmt", "-w", good, ugly); code != 0 {
+		t.Errorf("fmt -w: exit %d", code)
+	}
+	if b, _ := os.ReadFile(ugly); string(b) != "[\n  1,\n  2\n]\n" {
+		t.Errorf("fmt -w: %q", b)
+	}
+	if code, out, _ := exec("", "fmt", "-l", good, ugly); code != 0 || out != "" {
+		t.Errorf("fmt -l after -w: exit %d, %q", code, out)
+	}
+
+	if code, out, _ := exec("{\"a\":1}\n\n[true, null]\n\"s\"\n", "to-array"); code != 0 ||
+		out != "[\n  {\n    \"a\": 1\n  },\n  [\n    true,\n    null\n  ],\n  \"s\"\n]\n" {
+		t.Errorf("to-array: exit %d\n%s", code, out)
+	}
+	if code, out, err := exec("1\n2\n{\"a\" 1}\n", "to-array"); code != 1 || out != "" || !strings.HasPrefix(err, "<stdin>:3:6: ") {
+		t.Errorf("to-array bad: exit %d, %q, %q", code, out, err)
+	}
+	if code, out, _ := exec(`[{"b": 1, "a": [2]}, "x", 1.0]`, "to-ndjson"); code != 0 || out != "{\"a\":[2],\"b\":1}\n\"x\"\n1.0\n" {
+		t.Errorf("to-ndjson: exit %d, %q", code, out)
+	}
+	if code, _, err := exec(`{"a": 1}`, "to-ndjson"); code != 1 || err == "" {
+		t.Errorf("to-ndjson of an object: exit %d, %q", code, err)
+	}
+
+	report := `{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]}`
+	if code, out, _ := exec(report, "query", "-c", ".items[] | {id}"); code != 0 || out != "{\"id\":1}\n{\"id\":2}\n" {
+		t.Errorf("query: exit %d, %q", code, out)
+	}
+	if code, out, _ := exec(report, "query", "-r", ".items[].name"); code != 0 || out != "a\nb\n" {
+		t.Errorf("query -r: exit %d, %q", code, out)
+	}
+	if code, out, _ := exec("{\"n\": 1}\n{\"n\": 2}\n", "query", "-s", "map(.n) | add"); code != 0 || out != "3\n" {
+		t.Errorf("query -s over NDJSON: exit %d, %q", code, out)
+	}
+	if code, out, _ := exec("{\"n\": 1}\n{\"n\": 2}\n", "query", ".n * 10"); code != 0 || out != "10\n20\n" {
+		t.Errorf("query over NDJSON: exit %d, %q", code, out)
+	}
+	if code, out, _ := exec("", "query", "-n", "[range(3)]"); code != 0 || out != "[\n  0,\n  1,\n  2\n]\n" {
+		t.Errorf("query -n: exit %d, %q", code, out)
+	}
+	if code, _, _ := exec("{}", "query", ".a |"); code != 2 {
+		t.Errorf("query with a bad filter: exit %d", code)
+	}
+	if code, out, err := exec("[1, 0]", "query", ".[] | 1 / ."); code != 1 || out != "1\n" || !strings.Contains(err, "divided by zero") {
+		t.Errorf("query failing: exit %d, %q, %q", code, out, err)
+	}
+	if code, _, err := exec("{\n  \"a\": x\n}", "query", "."); code != 1 || !strings.HasPrefix(err, "<stdin>:2:8: ") {
+		t.Errorf("query of bad JSON: exit %d, %q", code, err)
+	}
+}
@@ -0,0 +1,379 @@
+// Typed, error-returning accessors for the JsonObject and JsonArray members
+package json
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the reasons an accessor fails, use errors.Is() to tell them
+var (
+	ErrMissing = errors.New("missing value")    // no such key, index out of range
+	ErrNull    = errors.New("null value")       // the value is there, but it is null
+	ErrType    = errors.New("wrong value type") // the value is not of the type requested
+)
+
+// the member as int; JsonInt only
+func AsInt(v JsonValue) (int, error) {
+	if x, ok := v.(*JsonInt); ok && x != nil {
+		return int(*x), nil
+	}
+	return 0, accessError(v, "int")
+}
+
+// the member as float64; JsonFloat only
+func AsFloat(v JsonValue) (float64, error) {
+	if x, ok := v.(*JsonFloat); ok && x != nil {
+		return float64(*x), nil
+	}
+	return 0, accessError(v, "float")
+}
+
+// the member as float64; either JsonFloat or JsonInt (widened)
+func AsNumber(v JsonValue) (float64, error) {
+	if f, ok := pathNumber(v); ok {
+		return f, nil
+	}
+	return 0, accessError(v, "number")
+}
+
+// the member as string; an empty JsonString is fine here
+func AsString(v JsonValue) (string, error) {
+	if x, ok := v.(*JsonString); ok && x != nil {
+		return string(*x), nil
+	}
+	return "", accessError(v, "string")
+}
+
+func AsBool(v JsonValue) (bool, error) {
+	if x, ok := v.(*JsonBool); ok && x != nil {
+		return bool(*x), nil
+	}
+	return false, accessError(v, "bool")
+}
+
+func AsObject(v JsonValue) (*JsonObject, error) {
+	if x, ok := v.(*JsonObject); ok && x != nil {
+		return x, nil
+	}
+	return nil, accessError(v, "object")
+}
+
+func AsArray(v JsonValue) (*JsonArray, error) {
+	if x, ok := v.(*JsonArray); ok && x != nil {
+		return x, nil
+	}
+	return nil, accessError(v, "array")
+}
+
+func accessError(v JsonValue, what string) error {
+	if isNilValue(v) {
+		return fmt.Errorf("%w, not %s", ErrNull, what)
+	}
+	return fmt.Errorf("%w: %T is not %s", ErrType, v, what)
+}
+
+// null in the Go sense: the untyped nil or a nil pointer
+func isNilValue(v JsonValue) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case *JsonInt:
+		return x == nil
+	case *JsonFloat:
+		return x == nil
+	case *JsonBool:
+		return x == nil
+	case *JsonString:
+		return x == nil
+	case *JsonArray:
+		return x == nil
+	case *JsonObject:
+		return x == nil
+	}
+	return false
+}
+
+// tells which member failed
+func memberError(key string, e error) error {
+	return fmt.Errorf("member %+q: %w", key, e)
+}
+
+/*----------------------------------------------------------------------------*/
+
+// the member by its key
+func (self *JsonObject) Get(key string) (JsonValue, error) {
+	if !self.IsNull() {
+		if v, found := (*self)[key]; found {
+			return v, nil
+		}
+	}
+	return nil, memberError(key, ErrMissing)
+}
+
+func (self *JsonObject) get(key string, as func(JsonValue) error) error {
+	v, e := self.Get(key)
+	if e == nil {
+		if e = as(v); e != nil {
+			e = memberError(key, e)
+		}
+	}
+	return e
+}
+
+func (self *JsonObject) GetInt(key string) (r int, e error) {
+	e = self.get(key, func(v JsonValue) (e error) { r, e = AsInt(v); return })
+	return
+}
+
+func (self *JsonObject) GetFloat(key string) (r float64, e error) {
+	e = self.get(key, func(v JsonValue) (e error) { r, e = AsFloat(v); return })
+	return
+}
+
+// like GetFloat(), but a JsonInt is fine as well
+func (self *JsonObject) GetNumber(key string) (r float64, e error) {
+	e = self.get(key, func(v JsonValue) (e error) { r, e = AsNumber(v); return })
+	return
+}
+
+func (self *JsonObject) GetString(key string) (r string, e error) {
+	e = self.get(key, func(v JsonValue) (e error) { r, e = AsString(v); return })
+	return
+}
+
+func (self *JsonObject) GetBool(key string) (r bool, e error) {
+	e = self.get(key, func(v JsonValue) (e error) { r, e = AsBool(v); return })
+	return
+}
+
+func (self *JsonObject) GetObject(key string) (r *JsonObject, e error) {
+	e = self.get(key, func(v JsonValue) (e error) { r, e = AsObject(v); return })
+	return
+}
+
+func (self *JsonObject) GetArray(key string) (r *JsonArray, e error) {
+	e = self.get(key, func(v JsonValue) (e error) { r, e = AsArray(v); return })
+	return
+}
+
+// the ...Or() variants return the default on any error
+
+func (self *JsonObject) GetIntOr(key string, def int) int {
+	if r, e := self.GetInt(key); e == nil {
+		return r
+	}
+	return def
+}
+
+func (self *JsonObject) GetFloatOr(key string, def float64) float64 {
+	if r, e := self.GetFloat(key); e == nil {
+		return r
+	}
+	return def
+}
+
+func (self *JsonObject) GetNumberOr(key string, def float64) float64 {
+	if r, e := self.GetNumber(key); e == nil {
+		return r
+	}
+	return def
+}
+
+func (self *JsonObject) GetStringOr(key string, def string) string {
+	if r, e := self.GetString(key); e == nil {
+		return r
+	}
+	return def
+}
+
+func (self *JsonObject) GetBoolOr(key string, def bool) bool {
+	if r, e := self.GetBool(key); e == nil {
+		return r
+	}
+	return def
+}
+
+/*----------------------------------------------------------------------------*/
+
+// tells which element failed
+func elementError(i int, e error) error {
+	return fmt.Errorf("element %d: %w", i, e)
+}
+
+// the element by its index; negative ones count from the end
+func (self *JsonArray) Get(i int) (JsonValue, error) {
+	if !self.IsNull() {
+		j := i
+		if j < 0 {
+			j += len(*self)
+		}
+		if j >= 0 && j < len(*self) {
+			return (*self)[j], nil
+		}
+	}
+	return nil, elementError(i, ErrMissing)
+}
+
+func (self *JsonArray) get(i int, as func(JsonValue) error) error {
+	v, e := self.Get(i)
+	if e == nil {
+		if e = as(v); e != nil {
+			e = elementError(i, e)
+		}
+	}
+	return e
+}
+
+func (self *JsonArray) GetInt(i int) (r int, e error) {
+	e = self.get(i, func(v JsonValue) (e error) { r, e = AsInt(v); return })
+	return
+}
+
+func (self *JsonArray) GetFloat(i int) (r float64, e error) {
+	e = self.get(i, func(v JsonValue) (e error) { r, e = AsFloat(v); return })
+	return
+}
+
+// like GetFloat(), but a JsonInt is fine as well
+func (self *JsonArray) GetNumber(i int) (r float64, e error) {
+	e = self.get(i, func(v JsonValue) (e error) { r, e = AsNumber(v); return })
+	return
+}
+
+func (self *JsonArray) GetString(i int) (r string, e error) {
+	e = self.get(i, func(v JsonValue) (e error) { r, e = AsString(v); return })
+	return
+}
+
+func (self *JsonArray) GetBool(i int) (r bool, e error) {
+	e = self.get(i, func(v JsonValue) (e error) { r, e = AsBool(v); return })
+	return
+}
+
+func (self *JsonArray) GetObject(i int) (r *JsonObject, e error) {
+	e = self.get(i, func(v JsonValue) (e error) { r, e = AsObject(v); return })
+	return
+}
+
+func (self *JsonArray) GetArray(i int) (r *JsonArray, e error) {
+	e = self.get(i, func(v JsonValue) (e error) { r, e = AsArray(v); return })
+	return
+}
+
+func (self *JsonArray) GetIntOr(i int, def int) int {
+	if r, e := self.GetInt(i); e == nil {
+		return r
+	}
+	return def
+}
+
+func (self *JsonArray) GetFloatOr(i int, def float64) float64 {
+	if r, e := self.GetFloat(i); e == nil {
+		return r
+	}
+	return def
+}
+
+func (self *JsonArray) GetNumberOr(i int, def float64) float64 {
+	if r, e := self.GetNumber(i); e == nil {
+		return r
+	}
+	return def
+}
+
+func (self *JsonArray) GetStringOr(i int, def string) string {
+	if r, e := self.GetString(i); e == nil {
+		return r
+	}
+	return def
+}
+
+func (self *JsonArray) GetBoolOr(i int, def bool) bool {
+	if r, e := self.GetBool(i); e == nil {
+		return r
+	}
+	return def
+}
+
+/*----------------------------------------------------------------------------*/
+
+// finds the value by a dotted path like "disks.0.name": the words step into
+// object members, the numbers into array elements (negative from the end)
+func Lookup(v JsonValue, path string) (JsonValue, error) {
+	if path == "" {
+		return v, nil
+	}
+	var done []string
+	for _, w := range strings.Split(path, ".") {
+		var e error
+		switch c := v.(type) {
+		case *JsonObject:
+			v, e = c.Get(w)
+		case *JsonArray:
+			i, ae := strconv.Atoi(w)
+			if ae != nil {
+				e = fmt.Errorf("%w: %+q is not an index", ErrType, w)
+			} else {
+				v, e = c.Get(i)
+			}
+		default:
+			e = fmt.Errorf("%w: cannot step into %s", ErrType, jsonOf(v))
+		}
+		if e != nil {
+			return nil, fmt.Errorf("path %+q at %+q: %w", path, strings.Join(done, "."), e)
+		}
+		done = append(done, w)
+	}
+	return v, nil
+}
+
+func (self *JsonObject) lookup(path string, as func(JsonValue) error) error {
+	v, e := Lookup(self, path)
+	if e == nil {
+		if e = as(v); e != nil {
+			e = fmt.Errorf("path %+q: %w", path, e)
+		}
+	}
+	return e
+}
+
+// the nested member by a dotted path, see Lookup()
+func (self *JsonObject) Lookup(path string) (JsonValue, error) { return Lookup(self, path) }
+
+func (self *JsonObject) LookupInt(path string) (r int, e error) {
+	e = self.lookup(path, func(v JsonValue) (e error) { r, e = AsInt(v); return })
+	return
+}
+
+func (self *JsonObject) LookupFloat(path string) (r float64, e error) {
+	e = self.lookup(path, func(v JsonValue) (e error) { r, e = AsFloat(v); return })
+	return
+}
+
+func (self *JsonObject) LookupNumber(path string) (r float64, e error) {
+	e = self.lookup(path, func(v JsonValue) (e error) { r, e = AsNumber(v); return })
+	return
+}
+
+func (self *JsonObject) LookupString(path string) (r string, e error) {
+	e = self.lookup(path, func(v JsonValue) (e error) { r, e = AsString(v); return })
+	return
+}
+
+func (self *JsonObject) LookupBool(path string) (r bool, e error) {
+	e = self.lookup(path, func(v JsonValue) (e error) { r, e = AsBool(v); return })
+	return
+}
+
+func (self *JsonObject) LookupObject(path string) (r *JsonObject, e error) {
+	e = self.lookup(path, func(v JsonValue) (e error) { r, e = AsObject(v); return })
+	return
+}
+
+func (self *JsonObject) LookupArray(path string) (r *JsonArray, e error) {
+	e = self.lookup(path, func(v JsonValue) (e error) { r, e = AsArray(v); return })
+	return
+}
@@ -0,0 +1,110 @@
+package json
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAccessors(t *testing.T) {
+	doc, _, err := ParseValue(`{ "uptime": 3295164.96, "time": 1576839878, "host": "alpha", "up": true, "none": null,
+		"disks": [ { "name": "sda", "used": 95 }, 12, 1.5, "s", false, null, [ 1 ] ], "cpu": { "load": { "1m": 0.42 } } }`)
+	if err != nil {
+		t.Fatalf("ParseValue(): %v", err)
+	}
+	o := doc.(*JsonObject)
+	(*o)["empty"] = NewJsonString("")
+
+	check := func(what string, got interface{}, e error, expected interface{}, reason error) {
+		if reason != nil {
+			if !errors.Is(e, reason) {
+				t.Errorf("%s: %v, not %v", what, e, reason)
+			}
+			return
+		}
+		if e != nil || got != expected {
+			t.Errorf("%s = %v (%v), not %v", what, got, e, expected)
+		}
+	}
+
+	i, e := o.GetInt("time")
+	check("GetInt(time)", i, e, 1576839878, nil)
+	_, e = o.GetInt("uptime")
+	check("GetInt(uptime)", nil, e, nil, ErrType)
+	_, e = o.GetInt("nope")
+	check("GetInt(nope)", nil, e, nil, ErrMissing)
+	_, e = o.GetInt("none")
+	check("GetInt(none)", nil, e, nil, ErrNull)
+	f, e := o.GetFloat("uptime")
+	check("GetFloat(uptime)", f, e, 3295164.96, nil)
+	_, e = o.GetFloat("time")
+	check("GetFloat(time)", nil, e, nil, ErrType)
+	f, e = o.GetNumber("time")
+	check("GetNumber(time)", f, e, 1576839878.0, nil)
+	s, e := o.GetString("host")
+	check("GetString(host)", s, e, "alpha", nil)
+	s, e = o.GetString("empty")
+	check("GetString(empty)", s, e, "", nil)
+	b, e := o.GetBool("up")
+	check("GetBool(up)", b, e, true, nil)
+	_, e = o.GetObject("up")
+	check("GetObject(up)", nil, e, nil, ErrType)
+	if a, e := o.GetArray("disks"); e != nil || len(*a) != 7 {
+		t.Errorf("GetArray(disks) = %v, %v", a, e)
+	}
+	if c, e := o.GetObject("cpu"); e != nil || c.GetNumberOr("nope", 1) != 1 {
+		t.Errorf("GetObject(cpu) = %v, %v", c, e)
+	}
+	if o.GetIntOr("nope", 7) != 7 || o.GetIntOr("time", 7) != 1576839878 ||
+		o.GetFloatOr("time", 0.5) != 0.5 || o.GetNumberOr("time", 0.5) != 1576839878 ||
+		o.GetStringOr("up", "x") != "x" || o.GetBoolOr("up", false) != true {
+		t.Errorf("Get*Or() failed")
+	}
+
+	a, _ := o.GetArray("disks")
+	i, e = a.GetInt(1)
+	check("GetInt(1)", i, e, 12, nil)
+	f, e = a.GetFloat(2)
+	check("GetFloat(2)", f, e, 1.5, nil)
+	f, e = a.GetNumber(1)
+	check("GetNumber(1)", f, e, 12.0, nil)
+	s, e = a.GetString(-4)
+	check("GetString(-4)", s, e, "s", nil)
+	b, e = a.GetBool(4)
+	check("GetBool(4)", b, e, false, nil)
+	_, e = a.GetBool(5)
+	check("GetBool(5)", nil, e, nil, ErrNull)
+	_, e = a.GetInt(7)
+	check("GetInt(7)", nil, e, nil, ErrMissing)
+	if x, e := a.GetObject(0); e != nil || x.GetStringOr("name", "") != "sda" {
+		t.Errorf("GetObject(0) = %v, %v", x, e)
+	}
+	if x, e := a.GetArray(-1); e != nil || x.GetIntOr(0, 0) != 1 {
+		t.Errorf("GetArray(-1) = %v, %v", x, e)
+	}
+	if a.GetIntOr(0, 3) != 3 || a.GetFloatOr(2, 0) != 1.5 || a.GetNumberOr(1, 0) != 12 ||
+		a.GetStringOr(3, "") != "s" || a.GetBoolOr(9, true) != true {
+		t.Errorf("Get*Or() failed")
+	}
+
+	f, e = o.LookupFloat("cpu.load.1m")
+	check("LookupFloat(cpu.load.1m)", f, e, 0.42, nil)
+	f, e = o.LookupNumber("disks.0.used")
+	check("LookupNumber(disks.0.used)", f, e, 95.0, nil)
+	i, e = o.LookupInt("disks.-1.0")
+	check("LookupInt(disks.-1.0)", i, e, 1, nil)
+	s, e = o.LookupString("disks.0.name")
+	check("LookupString(disks.0.name)", s, e, "sda", nil)
+	b, e = o.LookupBool("disks.4")
+	check("LookupBool(disks.4)", b, e, false, nil)
+	_, e = o.LookupObject("disks.x")
+	check("LookupObject(disks.x)", nil, e, nil, ErrType)
+	_, e = o.LookupArray("host.x")
+	check("LookupArray(host.x)", nil, e, nil, ErrType)
+	_, e = o.LookupInt("cpu.nope.x")
+	check("LookupInt(cpu.nope.x)", nil, e, nil, ErrMissing)
+	_, e = o.LookupInt("cpu.load")
+	check("LookupInt(cpu.load)", nil, e, nil, ErrType)
+	if v, e := o.Lookup(""); e != nil || v != doc {
+		t.Errorf("Lookup() = %v, %v", v, e)
+	}
+}
@@ -0,0 +1,200 @@
+// Editing operations on JsonArray
+package json
+
+import (
+	"fmt"
+	"sort"
+)
+
+// the number of elements, 0 for null
+func (self *JsonArray) Len() int {
+	if self.IsNull() {
+		return 0
+	}
+	return len(*self)
+}
+
+// resolves a negative index (counts from the end) and checks it against the limit
+func (self *JsonArray) index(i, limit int) (int, error) {
+	j := i
+	if j < 0 {
+		j += self.Len()
+	}
+	if j < 0 || j > limit {
+		return 0, elementError(i, ErrMissing)
+	}
+	return j, nil
+}
+
+// inserts the values before the i-th element; i may be Len() to append,
+// a negative one counts from the end
+func (self *JsonArray) InsertAt(i int, v ...JsonValue) error {
+	j, e := self.index(i, self.Len())
+	if e != nil {
+		return e
+	}
+	if e := self.acyclic("InsertAt", v); e != nil {
+		return e
+	}
+	r := make(JsonArray, 0, self.Len()+len(v))
+	r = append(r, (*self)[:j]...)
+	r = append(r, v...)
+	*self = append(r, (*self)[j:]...)
+	return nil
+}
+
+// a CycleError if one of the values is (or holds) this array
+func (self *JsonArray) acyclic(op string, v []JsonValue) error {
+	for _, x := range v {
+		if reaches(x, self) {
+			return CycleError(fmt.Errorf("cannot %T.%s(): a cycle", self, op))
+		}
+	}
+	return nil
+}
+
+// removes the i-th element and returns it; a negative i counts from the end
+func (self *JsonArray) RemoveAt(i int) (JsonValue, error) {
+	j, e := self.index(i, self.Len()-1)
+	if e != nil {
+		return nil, e
+	}
+	v := (*self)[j]
+	*self = append((*self)[:j], (*self)[j+1:]...)
+	return v, nil
+}
+
+// removes the elements fn says so about, returns how many were removed
+func (self *JsonArray) RemoveIf(fn func(i int, v JsonValue) bool) int {
+	if self.IsNull() {
+		return 0
+	}
+	r := (*self)[:0]
+	for i, v := range *self {
+		if !fn(i, v) {
+			r = append(r, v)
+		}
+	}
+	n := len(*self) - len(r)
+	for i := len(r); i < len(*self); i++ {
+		(*self)[i] = nil // let them go
+	}
+	*self = r
+	return n
+}
+
+// removes up to n elements starting at i and inserts the values there instead
+// (like the JavaScript one); returns the removed elements
+func (self *JsonArray) Splice(i, n int, v ...JsonValue) (*JsonArray, error) {
+	j, e := self.index(i, self.Len())
+	if e != nil {
+		return nil, e
+	}
+	if n < 0 {
+		return nil, elementError(n, ErrMissing)
+	}
+	if e := self.acyclic("Splice", v); e != nil {
+		return nil, e
+	}
+	if j+n > self.Len() {
+		n = self.Len() - j
+	}
+	removed := make(JsonArray, n)
+	copy(removed, (*self)[j:j+n])
+	r := make(JsonArray, 0, self.Len()-n+len(v))
+	r = append(r, (*self)[:j]...)
+	r = append(r, v...)
+	*self = append(r, (*self)[j+n:]...)
+	return &removed, nil
+}
+
+// the elements from i up to (not including) j as a view: it shares the
+// elements with this array (so the in-place edits of this one may show
+// through), but appending to it never touches this one; negative indices
+// count from the end
+func (self *JsonArray) Slice(i, j int) (*JsonArray, error) {
+	from, e := self.index(i, self.Len())
+	if e != nil {
+		return nil, e
+	}
+	to, e := self.index(j, self.Len())
+	if e != nil {
+		return nil, e
+	}
+	if to < from {
+		return nil, fmt.Errorf("%w: slice [%d:%d]", ErrMissing, i, j)
+	}
+	if self.IsNull() {
+		return new(JsonArray), nil
+	}
+	r := (*self)[from:to:to]
+	return &r, nil
+}
+
+// keeps only the first n elements
+func (self *JsonArray) Truncate(n int) error {
+	if n < 0 || n > self.Len() {
+		return elementError(n, ErrMissing)
+	}
+	if !self.IsNull() {
+		*self = (*self)[:n]
+	}
+	return nil
+}
+
+// reverses the order of the elements in place
+func (self *JsonArray) Reverse() {
+	for i, j := 0, self.Len()-1; i < j; i, j = i+1, j-1 {
+		(*self)[i], (*self)[j] = (*self)[j], (*self)[i]
+	}
+}
+
+// the index of the first element .Equal() to v, or -1
+func (self *JsonArray) IndexOf(v JsonValue) int {
+	for i := 0; i < self.Len(); i++ {
+		if equalValues((*self)[i], v) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sorts the elements in place keeping the equal ones in their order
+func (self *JsonArray) SortStable(less func(a, b JsonValue) bool) {
+	if self.IsNull() {
+		return
+	}
+	sort.SliceStable(*self, func(i, j int) bool { return less((*self)[i], (*self)[j]) })
+}
+
+// removes the elements .Equal() to some earlier one, returns how many were removed
+func (self *JsonArray) Dedupe() int {
+	var seen JsonArray
+	return self.RemoveIf(func(i int, v JsonValue) bool {
+		if seen.IndexOf(v) >= 0 {
+			return true
+		}
+		seen = append(seen, v)
+		return false
+	})
+}
+
+// a new array of the elements fn says so about (the elements are shared)
+func (self *JsonArray) Filter(fn func(i int, v JsonValue) bool) *JsonArray {
+	r := JsonArray{}
+	for i := 0; i < self.Len(); i++ {
+		if fn(i, (*self)[i]) {
+			r = append(r, (*self)[i])
+		}
+	}
+	return &r
+}
+
+// a new array of whatever fn makes out of the elements
+func (self *JsonArray) Map(fn func(i int, v JsonValue) JsonValue) *JsonArray {
+	r := make(JsonArray, self.Len())
+	for i := range r {
+		r[i] = fn(i, (*self)[i])
+	}
+	return &r
+}
@@ -0,0 +1,105 @@
+package json
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestArrayEditing(t *testing.T) {
+	parse := func(s string) *JsonArray { return mustParse(t, s).(*JsonArray) }
+	expect := func(what string, a *JsonArray, expected string) {
+		if a.Json() != expected {
+			t.Errorf("%s: %s, not %s", what, a.Json(), expected)
+		}
+	}
+
+	a := parse(`[ 1, 2, 3 ]`)
+	if a.Len() != 3 || new(JsonArray).Len() != 0 {
+		t.Errorf("Len(): %d", a.Len())
+	}
+	if e := a.InsertAt(1, NewJsonString("x"), nil); e != nil {
+		t.Errorf("InsertAt(): %v", e)
+	}
+	expect("InsertAt(1)", a, `[ 1, "x", null, 2, 3 ]`)
+	a.InsertAt(a.Len(), NewJsonInt(4))
+	a.InsertAt(-1, NewJsonInt(5))
+	expect("InsertAt(end)", a, `[ 1, "x", null, 2, 3, 5, 4 ]`)
+	if e := a.InsertAt(99, nil); !errors.Is(e, ErrMissing) {
+		t.Errorf("InsertAt(99): %v", e)
+	}
+
+	if v, e := a.RemoveAt(-1); e != nil || jsonOf(v) != "4" {
+		t.Errorf("RemoveAt(-1) = %s, %v", jsonOf(v), e)
+	}
+	if _, e := a.RemoveAt(6); e == nil {
+		t.Errorf("RemoveAt(6): no error")
+	}
+	if n := a.RemoveIf(func(i int, v JsonValue) bool { return v == nil || i == 1 }); n != 2 {
+		t.Errorf("RemoveIf() = %d", n)
+	}
+	expect("RemoveIf()", a, `[ 1, 2, 3, 5 ]`)
+
+	removed, e := a.Splice(1, 2, NewJsonInt(7))
+	if e != nil {
+		t.Errorf("Splice(): %v", e)
+	} else {
+		expect("Splice() removed", removed, `[ 2, 3 ]`)
+	}
+	expect("Splice()", a, `[ 1, 7, 5 ]`)
+	if removed, _ = a.Splice(2, 10); removed.Len() != 1 || a.Len() != 2 {
+		t.Errorf("Splice(2, 10) = %s, %s", removed.Json(), a.Json())
+	}
+
+	a = parse(`[ 0, 1, 2, 3, 4 ]`)
+	view, e := a.Slice(1, -1)
+	if e != nil {
+		t.Fatalf("Slice(): %v", e)
+	}
+	expect("Slice(1, -1)", view, `[ 1, 2, 3 ]`)
+	view.Append(NewJsonInt(9))
+	expect("Slice() appended", a, `[ 0, 1, 2, 3, 4 ]`)
+	(*view)[0].Set(8)
+	expect("Slice() shared", a, `[ 0, 8, 2, 3, 4 ]`)
+	if _, e := a.Slice(3, 1); e == nil {
+		t.Errorf("Slice(3, 1): no error")
+	}
+
+	a.Reverse()
+	expect("Reverse()", a, `[ 4, 3, 2, 8, 0 ]`)
+	a.Truncate(2)
+	expect("Truncate(2)", a, `[ 4, 3 ]`)
+	if e := a.Truncate(3); e == nil {
+		t.Errorf("Truncate(3): no error")
+	}
+
+	a = parse(`[ "b", 2, { "k": 1 }, "a", 2, { "k": 1 }, null, null ]`)
+	if i := a.IndexOf((*parse(`[ { "k": 1 } ]`))[0]); i != 2 {
+		t.Errorf("IndexOf() = %d", i)
+	}
+	if i := a.IndexOf(NewJsonBool(true)); i != -1 {
+		t.Errorf("IndexOf(true) = %d", i)
+	}
+	if n := a.Dedupe(); n != 3 {
+		t.Errorf("Dedupe() = %d", n)
+	}
+	expect("Dedupe()", a, `[ "b", 2, { "k": 1 }, "a", null ]`)
+
+	a = parse(`[ { "n": 2, "s": "x" }, { "n": 1, "s": "y" }, { "n": 2, "s": "z" }, { "n": 1, "s": "w" } ]`)
+	a.SortStable(func(x, y JsonValue) bool {
+		return x.(*JsonObject).GetIntOr("n", 0) < y.(*JsonObject).GetIntOr("n", 0)
+	})
+	var order string
+	for i := range *a {
+		order += (*a)[i].(*JsonObject).GetStringOr("s", "?")
+	}
+	if order != "ywxz" {
+		t.Errorf("SortStable(): %s", order)
+	}
+
+	a = parse(`[ 1, 2, 3, 4 ]`)
+	even := a.Filter(func(i int, v JsonValue) bool { return v.Value().(int)%2 == 0 })
+	expect("Filter()", even, `[ 2, 4 ]`)
+	squares := a.Map(func(i int, v JsonValue) JsonValue { return NewJsonInt(v.Value().(int) * v.Value().(int)) })
+	expect("Map()", squares, `[ 1, 4, 9, 16 ]`)
+	expect("Filter() and Map() source", a, `[ 1, 2, 3, 4 ]`)
+}
@@ -0,0 +1,238 @@
+// Building nested documents by dotted paths
+package json
+
+import (
+	stdjson "encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type BuildError error
+
+const (
+	maxInt = int(^uint(0) >> 1)
+	minInt = -maxInt - 1
+)
+
+// builds a *JsonObject out of values set by dotted paths like "system.cpu.load.1m";
+// the missing intermediate objects and arrays are created on the way; the first
+// error is kept (see .Err()) and makes all the following calls no-ops
+type Builder struct {
+	root *JsonObject
+	err  error
+}
+
+// starts a new document
+func NewBuilder() *Builder { return &Builder{root: &JsonObject{}} }
+
+// continues with an existing document (it is modified in place)
+func NewBuilderFor(o *JsonObject) *Builder {
+	if *o == nil {
+		*o = JsonObject{}
+	}
+	return &Builder{root: o}
+}
+
+// the first error happened, if any
+func (self *Builder) Err() error { return self.err }
+
+// the document built so far
+func (self *Builder) Object() *JsonObject { return self.root }
+
+// the document and the first error happened, if any
+func (self *Builder) Build() (*JsonObject, error) { return self.root, self.err }
+
+// sets the value (converted by ToJsonValue()) at the path; numeric path words
+// index existing arrays (the index may be one past the end to extend it)
+func (self *Builder) Set(path string, v interface{}) *Builder {
+	if self.err != nil {
+		return self
+	}
+	x, e := ToJsonValue(v)
+	if e != nil {
+		self.err = BuildError(fmt.Errorf("Set(%+q): %v", path, e))
+		return self
+	}
+	if e = self.put(path, x, false); e != nil {
+		self.err = BuildError(fmt.Errorf("Set(%+q): %v", path, e))
+	}
+	return self
+}
+
+// appends the value (converted by ToJsonValue()) to the array at the path, creating it if missing
+func (self *Builder) Append(path string, v interface{}) *Builder {
+	if self.err != nil {
+		return self
+	}
+	x, e := ToJsonValue(v)
+	if e != nil {
+		self.err = BuildError(fmt.Errorf("Append(%+q): %v", path, e))
+		return self
+	}
+	if e = self.put(path, x, true); e != nil {
+		self.err = BuildError(fmt.Errorf("Append(%+q): %v", path, e))
+	}
+	return self
+}
+
+func (self *Builder) put(path string, v JsonValue, appending bool) error {
+	if path == "" {
+		return fmt.Errorf("Empty path")
+	}
+	words := strings.Split(path, ".")
+	var current JsonValue = self.root
+	for n, w := range words {
+		last := n == len(words)-1
+		var next JsonValue
+		if last && !appending {
+			next = v
+		} else if last {
+			next = new(JsonArray)
+		} else {
+			next = &JsonObject{}
+		}
+		var e error
+		if current, e = step(current, w, next, last && !appending); e != nil {
+			return fmt.Errorf("at %+q: %v", strings.Join(words[:n+1], "."), e)
+		}
+	}
+	if !appending {
+		return nil
+	}
+	a, ok := current.(*JsonArray)
+	if !ok || a == nil {
+		return fmt.Errorf("%s is not an array", jsonOf(current))
+	}
+	return a.TryAppend(v)
+}
+
+// steps into the member w of the container, creating it as next if missing
+// (or replacing it if replace); returns the member
+func step(c JsonValue, w string, next JsonValue, replace bool) (JsonValue, error) {
+	if reaches(next, c) {
+		return nil, CycleError(fmt.Errorf("A cycle"))
+	}
+	switch x := c.(type) {
+	case *JsonObject:
+		if x != nil {
+			if o, found := (*x)[w]; found && !replace && o != nil {
+				return o, nil
+			}
+			if *x == nil { // a null object is stepped into as an empty one
+				*x = JsonObject{}
+			}
+			(*x)[w] = next
+			return next, nil
+		}
+	case *JsonArray:
+		if x != nil {
+			i, e := strconv.Atoi(w)
+			if e != nil || i < 0 || i > len(*x) {
+				return nil, fmt.Errorf("Bad index %+q", w)
+			}
+			if i == len(*x) {
+				*x = append(*x, next)
+				return next, nil
+			}
+			if (*x)[i] == nil || replace {
+				(*x)[i] = next
+				return next, nil
+			}
+			return (*x)[i], nil
+		}
+	}
+	return nil, fmt.Errorf("Cannot step into %s", jsonOf(c))
+}
+
+// an error rather than panic on NaN and infinities
+func toJsonFloat(f float64) (JsonValue, error) {
+	r := new(JsonFloat)
+	if e := r.TrySet(f); e != nil {
+		return nil, e
+	}
+	return r, nil
+}
+
+// converts Go native values: nil, bool, any int or uint (if it fits an int, see ToInt()),
+// any float, string, any slice or array, maps with string keys, pointers to
+// any of these, json.Number and json.RawMessage; JsonValues are taken as they are
+func ToJsonValue(v interface{}) (JsonValue, error) {
+	switch x := v.(type) {
+	case nil:
+		return nil, nil
+	case JsonValue:
+		return x, nil
+	case bool:
+		return NewJsonBool(x), nil
+	case string:
+		return NewJsonString(x), nil
+	case int:
+		return NewJsonInt(x), nil
+	case float64:
+		return toJsonFloat(x)
+	case []JsonValue:
+		a := JsonArray(x)
+		return &a, nil
+	case map[string]JsonValue:
+		o := JsonObject(x)
+		return &o, nil
+	case stdjson.Number:
+		return FromNumber(x)
+	case stdjson.RawMessage:
+		return FromRawMessage(x)
+	}
+
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, e := ToInt(v)
+		if e != nil {
+			return nil, e
+		}
+		return NewJsonInt(i), nil
+	case reflect.Float32, reflect.Float64:
+		return toJsonFloat(r.Float())
+	case reflect.Bool:
+		return NewJsonBool(r.Bool()), nil
+	case reflect.String:
+		return NewJsonString(r.String()), nil
+	case reflect.Ptr, reflect.Interface:
+		if r.IsNil() {
+			return nil, nil
+		}
+		return ToJsonValue(r.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		if r.Kind() == reflect.Slice && r.IsNil() {
+			return new(JsonArray), nil
+		}
+		a := make(JsonArray, r.Len())
+		for i := range a {
+			x, e := ToJsonValue(r.Index(i).Interface())
+			if e != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, e)
+			}
+			a[i] = x
+		}
+		return &a, nil
+	case reflect.Map:
+		if r.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("Map keys of %T are not strings", v)
+		}
+		if r.IsNil() {
+			return new(JsonObject), nil
+		}
+		o := make(JsonObject, r.Len())
+		for it := r.MapRange(); it.Next(); {
+			x, e := ToJsonValue(it.Value().Interface())
+			if e != nil {
+				return nil, fmt.Errorf("[%+q]: %v", it.Key().String(), e)
+			}
+			o[it.Key().String()] = x
+		}
+		return &o, nil
+	}
+	return nil, fmt.Errorf("Cannot convert %T", v)
+}
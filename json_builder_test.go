@@ -0,0 +1,73 @@
+package json
+
+import "testing"
+
+func TestBuilder(t *testing.T) {
+	type disk struct{ name string }
+	load := 0.42
+	b := NewBuilder().
+		Set("time", int64(1576839878)).
+		Set("uptime", 3295164.96).
+		Set("system.cpu.load.1m", load).
+		Set("system.cpu.load.5m", &load).
+		Set("system.cpu.count", uint8(4)).
+		Set("system.name", "alpha").
+		Set("system.up", true).
+		Set("system.none", nil).
+		Set("tags", []string{"a", "b"}).
+		Set("limits", map[string]interface{}{"cpu": 2, "mem": float32(0.5)}).
+		Append("disks", map[string]interface{}{"name": "sda", "used": 95}).
+		Append("disks", NewJsonObject(map[string]JsonValue{"name": NewJsonString("sdb")})).
+		Set("disks.1.used", 12).
+		Set("tags.2", "c").
+		Set("tags.0", "z").
+		Append("system.cpu.temps", 40.5)
+	o, err := b.Build()
+	if err != nil {
+		t.Fatalf("Build(): %v", err)
+	}
+	expected := `{ "disks": [ { "name": "sda", "used": 95 }, { "name": "sdb", "used": 12 } ], ` +
+		`"limits": { "cpu": 2, "mem": 0.500000 }, ` +
+		`"system": { "cpu": { "count": 4, "load": { "1m": 0.420000, "5m": 0.420000 }, "temps": [ 40.500000 ] }, ` +
+		`"name": "alpha", "none": null, "up": true }, ` +
+		`"tags": [ "z", "b", "c" ], "time": 1576839878, "uptime": 3295164.960000 }`
+	if o.Json() != expected {
+		t.Errorf("Build():\n%s\nnot\n%s", o.Json(), expected)
+	}
+	if b.Object() != o || b.Err() != nil {
+		t.Errorf("Object() or Err() differ")
+	}
+
+	for _, bad := range []func(*Builder) *Builder{
+		func(b *Builder) *Builder { return b.Set("", 1) },
+		func(b *Builder) *Builder { return b.Set("system.name.x", 1) },
+		func(b *Builder) *Builder { return b.Set("x", disk{"sda"}) },
+		func(b *Builder) *Builder { return b.Set("x", map[int]int{1: 1}) },
+		func(b *Builder) *Builder { return b.Set("x", uint64(1<<63)) },
+		func(b *Builder) *Builder { return b.Set("tags.5", 1) },
+		func(b *Builder) *Builder { return b.Set("tags.x", 1) },
+		func(b *Builder) *Builder { return b.Append("system", 1) },
+		func(b *Builder) *Builder { return b.Append("x", []interface{}{1, struct{}{}}) },
+	} {
+		o := o.Clone().(*JsonObject)
+		before := o.Json()
+		b := bad(NewBuilderFor(o))
+		if b.Err() == nil {
+			t.Errorf("no error: %s", o.Json())
+		}
+		if b.Set("more", 1).Err() == nil || o.Json() != before {
+			t.Errorf("Builder goes on after error: %s", o.Json())
+		}
+	}
+
+	// the null objects on the way are filled in
+	nulls := NewBuilder().Set("a", new(JsonObject)).Set("a.b", 1).Set("m", map[string]int(nil)).Set("m.x.y", 2)
+	if o, e := nulls.Build(); e != nil || o.Json() != `{ "a": { "b": 1 }, "m": { "x": { "y": 2 } } }` {
+		t.Errorf("Builder over nulls: %v, %s", e, o.Json())
+	}
+
+	empty := new(JsonObject)
+	if NewBuilderFor(empty).Set("a", []int(nil)).Err() != nil || empty.Json() != `{ "a": null }` {
+		t.Errorf("NewBuilderFor(): %s", empty.Json())
+	}
+}
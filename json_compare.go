@@ -0,0 +1,141 @@
+// A total order of JsonValues
+package json
+
+import (
+	"math"
+	"strings"
+)
+
+// the order of the types
+func compareRank(v JsonValue) int {
+	if isNullValue(v) {
+		return 0
+	}
+	switch v.(type) {
+	case *JsonBool:
+		return 1
+	case *JsonInt, *JsonFloat:
+		return 2
+	case *JsonString:
+		return 3
+	case *JsonArray:
+		return 4
+	case *JsonObject:
+		return 5
+	}
+	return 6 // someone else's JsonValue, by its .Json()
+}
+
+// -1, 0 or +1 as a is less than, equal to or greater than b in the total order:
+//
+//	null < false < true < numbers < strings < arrays < objects
+//
+// numbers compare by value, JsonInt and JsonFloat together (an int goes first
+// when they are equal, so that 0 means .Equal()); strings compare bytewise;
+// arrays compare element by element, a shorter prefix goes first; objects
+// compare the same way as the lists of their members sorted by key, the key
+// first, then the value; all the nulls are equal
+func Compare(a, b JsonValue) int {
+	ra, rb := compareRank(a), compareRank(b)
+	if ra != rb {
+		return compareInts(ra, rb)
+	}
+	if ra == 0 {
+		return 0
+	}
+	switch x := a.(type) {
+	case *JsonBool:
+		return compareInts(boolRank(bool(*x)), boolRank(bool(*b.(*JsonBool))))
+	case *JsonInt, *JsonFloat:
+		return compareNumbers(a, b)
+	case *JsonString:
+		return strings.Compare(string(*x), string(*b.(*JsonString)))
+	case *JsonArray:
+		y := b.(*JsonArray)
+		for i := 0; i < len(*x) && i < len(*y); i++ {
+			if r := Compare((*x)[i], (*y)[i]); r != 0 {
+				return r
+			}
+		}
+		return compareInts(len(*x), len(*y))
+	case *JsonObject:
+		y := b.(*JsonObject)
+		xk, yk := sortedKeys(*x), sortedKeys(*y)
+		for i := 0; i < len(xk) && i < len(yk); i++ {
+			if r := strings.Compare(xk[i], yk[i]); r != 0 {
+				return r
+			}
+			if r := Compare((*x)[xk[i]], (*y)[yk[i]]); r != 0 {
+				return r
+			}
+		}
+		return compareInts(len(xk), len(yk))
+	}
+	return strings.Compare(a.Json(), b.Json())
+}
+
+// sorts the elements in the Compare() order
+func (self *JsonArray) Sort() {
+	self.SortStable(func(a, b JsonValue) bool { return Compare(a, b) < 0 })
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func compareNumbers(a, b JsonValue) int {
+	ai, aInt := a.(*JsonInt)
+	bi, bInt := b.(*JsonInt)
+	if aInt && bInt {
+		return compareInts(int(*ai), int(*bi))
+	}
+	if aInt {
+		return -compareIntFloat(int(*ai), float64(*b.(*JsonFloat)))
+	}
+	if bInt {
+		return compareIntFloat(int(*bi), float64(*a.(*JsonFloat)))
+	}
+	x, y := float64(*a.(*JsonFloat)), float64(*b.(*JsonFloat))
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// compares the float f to the int i exactly (no rounding of the big ints);
+// when they are equal the float goes after
+func compareIntFloat(i int, f float64) int {
+	if f >= -float64(minInt) {
+		return 1
+	}
+	if f < float64(minInt) {
+		return -1
+	}
+	t := math.Trunc(f)
+	if r := compareInts(int(t), i); r != 0 {
+		return r
+	}
+	if f > t {
+		return 1
+	}
+	if f < t {
+		return -1
+	}
+	return 1
+}
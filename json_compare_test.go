@@ -0,0 +1,69 @@
+package json
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	// in the ascending order
+	ordered := []string{
+		`null`,
+		`false`,
+		`true`,
+		`-2.5`,
+		`-2`,
+		`-1.5`,
+		`1`,
+		`1.0`,
+		`1.000001`,
+		`2`,
+		`9223372036854775807`,
+		`"A"`,
+		`"a"`,
+		`"ab"`,
+		`"b"`,
+		`[ 1 ]`,
+		`[ 1, null ]`,
+		`[ 1, 2 ]`,
+		`[ 2 ]`,
+		`[ "a" ]`,
+		`{ "a": 1 }`,
+		`{ "a": 1, "b": 0 }`,
+		`{ "a": 2 }`,
+		`{ "b": 0 }`,
+	}
+	values := make([]JsonValue, len(ordered))
+	for i, s := range ordered {
+		values[i] = mustParse(t, s)
+	}
+	for i := range values {
+		for j := range values {
+			expected := compareInts(i, j)
+			if r := Compare(values[i], values[j]); r != expected {
+				t.Errorf("Compare(%s, %s) = %d, not %d", ordered[i], ordered[j], r, expected)
+			}
+		}
+	}
+
+	nulls := []JsonValue{nil, new(JsonArray), new(JsonObject), (*JsonInt)(nil), (*JsonBool)(nil), NewJsonString("")}
+	for _, a := range nulls {
+		for _, b := range nulls {
+			if Compare(a, b) != 0 {
+				t.Errorf("Compare(%s, %s) != 0", jsonOf(a), jsonOf(b))
+			}
+		}
+		if Compare(a, NewJsonBool(false)) != -1 {
+			t.Errorf("Compare(%s, false) != -1", jsonOf(a))
+		}
+	}
+
+	a := JsonArray(append([]JsonValue{}, values...))
+	rand.New(rand.NewSource(1)).Shuffle(len(a), func(i, j int) { a[i], a[j] = a[j], a[i] })
+	a.Sort()
+	for i := range a {
+		if !equalValues(a[i], values[i]) {
+			t.Errorf("Sort(): [%d] is %s, not %s", i, jsonOf(a[i]), ordered[i])
+		}
+	}
+}
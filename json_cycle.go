@@ -0,0 +1,150 @@
+// Guarding against cyclic JsonValue graphs
+package json
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// what one gets trying to make (or to use) a cyclic graph of values
+type CycleError error
+
+// the identity of a container: objects sharing the map (see .Set()) are the same one
+func nodeKey(v JsonValue) interface{} {
+	if o, ok := v.(*JsonObject); ok && !o.IsNull() {
+		return reflect.ValueOf(*o).Pointer()
+	}
+	return v
+}
+
+// the members (elements) of a container, nil for the others
+func members(v JsonValue) []JsonValue {
+	var r []JsonValue
+	switch x := v.(type) {
+	case *JsonArray:
+		if !x.IsNull() {
+			r = *x
+		}
+	case *JsonObject:
+		if !x.IsNull() {
+			for _, k := range sortedKeys(*x) {
+				r = append(r, (*x)[k])
+			}
+		}
+	}
+	return r
+}
+
+// whether the container target is v or somewhere inside of it
+func reaches(v, target JsonValue) bool {
+	key := nodeKey(target)
+	seen := map[interface{}]bool{}
+	var search func(v JsonValue) bool
+	search = func(v JsonValue) bool {
+		switch v.(type) {
+		case *JsonArray, *JsonObject:
+		default:
+			return false
+		}
+		k := nodeKey(v)
+		if k == key {
+			return true
+		}
+		if seen[k] {
+			return false
+		}
+		seen[k] = true
+		for _, c := range members(v) {
+			if search(c) {
+				return true
+			}
+		}
+		return false
+	}
+	return search(v)
+}
+
+// returns a CycleError telling where a container holds one of its ancestors, if any
+func FindCycle(v JsonValue) error {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[interface{}]int{}
+	var search func(path []string, v JsonValue) error
+	search = func(path []string, v JsonValue) error {
+		switch v.(type) {
+		case *JsonArray, *JsonObject:
+		default:
+			return nil
+		}
+		k := nodeKey(v)
+		switch state[k] {
+		case visiting:
+			return CycleError(fmt.Errorf("Cycle at %+q", joinPointer(path)))
+		case done:
+			return nil
+		}
+		state[k] = visiting
+		switch x := v.(type) {
+		case *JsonArray:
+			for i := 0; i < x.Len(); i++ {
+				if e := search(subPath(path, fmt.Sprint(i)), (*x)[i]); e != nil {
+					return e
+				}
+			}
+		case *JsonObject:
+			for _, n := range x.Keys() {
+				if e := search(subPath(path, n), (*x)[n]); e != nil {
+					return e
+				}
+			}
+		}
+		state[k] = done
+		return nil
+	}
+	return search(nil, v)
+}
+
+// like .Json(), but returns a CycleError instead of panic on a cyclic tree
+func Encode(v JsonValue) (string, error) {
+	if e := FindCycle(v); e != nil {
+		return "", e
+	}
+	return memberJson(v, map[interface{}]bool{}), nil
+}
+
+// .Json() of a member; path holds the containers being rendered
+func memberJson(v JsonValue, path map[interface{}]bool) string {
+	switch x := v.(type) {
+	case nil:
+		return "null"
+	case *JsonArray:
+		return x.json(path)
+	case *JsonObject:
+		return x.json(path)
+	}
+	return v.Json()
+}
+
+// puts the container on the path (panics with a CycleError if it is there
+// already) and returns the function taking it off
+func enter(path map[interface{}]bool, v JsonValue) func() {
+	k := nodeKey(v)
+	if path[k] {
+		panic(CycleError(fmt.Errorf("Cycle in %T.Json()", v)))
+	}
+	path[k] = true
+	return func() { delete(path, k) }
+}
+
+// .Equal() of the members, the containers keep track of the pairs seen
+func equalMember(a, b JsonValue, seen map[[2]interface{}]bool) bool {
+	switch x := a.(type) {
+	case *JsonArray:
+		return x.equal(b, seen)
+	case *JsonObject:
+		return x.equal(b, seen)
+	}
+	return a.Equal(b)
+}
@@ -0,0 +1,100 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCycles(t *testing.T) {
+	a, b := &JsonObject{}, &JsonObject{}
+	if e := a.TryInsert("b", b); e != nil {
+		t.Fatalf("TryInsert(): %v", e)
+	}
+	if e := b.TryInsert("a", a); e == nil {
+		t.Errorf("TryInsert(): no cycle found")
+	}
+	if e := b.TryInsert("a", NewJsonArray([]JsonValue{NewJsonInt(1), a})); e == nil {
+		t.Errorf("TryInsert(): no cycle found through an array")
+	}
+	if b.Len() != 0 {
+		t.Errorf("TryInsert() failed, yet inserted: %s", b.Json())
+	}
+
+	l := NewJsonArray([]JsonValue{NewJsonInt(1)})
+	if e := l.TryAppend(l); e == nil {
+		t.Errorf("TryAppend(): no cycle found")
+	}
+	m := NewJsonArray([]JsonValue{l})
+	if e := l.TryAppend(m); e == nil {
+		t.Errorf("TryAppend(): no cycle found in a nested array")
+	}
+	if e := l.TryAppend(NewJsonArray([]JsonValue{a, a})); e != nil {
+		t.Errorf("TryAppend(): a shared value is no cycle: %v", e)
+	}
+	if e := m.TrySet(NewJsonArray([]JsonValue{m})); e == nil {
+		t.Errorf("TrySet(): no cycle found")
+	}
+
+	// two objects sharing the map are the same one
+	c := new(JsonObject)
+	c.Set(a)
+	if e := a.TryInsert("c", c); e == nil {
+		t.Errorf("TryInsert(): no cycle found through a shared map")
+	}
+
+	// nor do the array edits and the builder let one in
+	if e := l.InsertAt(0, l); e == nil {
+		t.Errorf("InsertAt(): no cycle found")
+	}
+	if _, e := l.Splice(0, 1, NewJsonInt(0), m); e == nil || l.Len() != 2 {
+		t.Errorf("Splice(): no cycle found (%v, %s)", e, l.Json())
+	}
+	bld := NewBuilder().Set("x.y", 1)
+	x, _ := bld.Object().GetObject("x")
+	if e := bld.Set("x.self", x).Err(); e == nil || !strings.Contains(e.Error(), "cycle") {
+		t.Errorf("Builder.Set(): no cycle found: %v", e)
+	}
+	bld = NewBuilder().Append("list", 1)
+	if e := bld.Append("list", bld.Object()).Err(); e == nil {
+		t.Errorf("Builder.Append(): no cycle found")
+	}
+	if e := NewBuilder().Set("a.b", 1).Set("a.c", NewBuilder().Set("d", 2).Object()).Err(); e != nil {
+		t.Errorf("Builder.Set(): not a cycle: %v", e)
+	}
+	if s, e := Encode(bld.Object()); e != nil || s != `{ "list": [ 1 ] }` {
+		t.Errorf("Builder: %s, %v", s, e)
+	}
+
+	// a value met twice is no cycle
+	if s, e := Encode(NewJsonArray([]JsonValue{a, a})); e != nil || s != `[ { "b": {  } }, { "b": {  } } ]` {
+		t.Errorf("Encode() = %s, %v", s, e)
+	}
+
+	// the raw maps and slices bypass the checks, the guards catch it then
+	(*b)["a"] = a
+	if _, e := Encode(a); e == nil || !strings.Contains(e.Error(), `"/b/a"`) {
+		t.Errorf("Encode(): %v", e)
+	}
+	if e := FindCycle(b); e == nil {
+		t.Errorf("FindCycle(): nothing found")
+	}
+	defer func() {
+		if r := recover(); r == nil {
+			t.Errorf("Json() does not panic on a cycle")
+		}
+	}()
+	if !a.Equal(a) || a.Equal(b) {
+		t.Errorf("Equal() of cyclic objects is wrong")
+	}
+	a2 := &JsonObject{}
+	b2 := &JsonObject{"a": a2}
+	(*a2)["b"] = b2
+	if !a.Equal(a2) || !a2.Equal(a) {
+		t.Errorf("Equal() of the same cyclic shapes is false")
+	}
+	(*b2)["x"] = nil
+	if a.Equal(a2) {
+		t.Errorf("Equal() of different cyclic shapes is true")
+	}
+	a.Json()
+}
@@ -0,0 +1,291 @@
+// Structural diff between two JsonValue trees
+package json
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+type DiffKind int
+
+const (
+	DiffAdded       DiffKind = iota // a member or an element is new
+	DiffRemoved                     // a member or an element has gone
+	DiffChanged                     // the value is of the same type but differs
+	DiffTypeChanged                 // the value is of a different type (null is a type too)
+	DiffMoved                       // an array element changed its place (keyed arrays only)
+)
+
+var diffKindNames = map[DiffKind]string{
+	DiffAdded:       "added",
+	DiffRemoved:     "removed",
+	DiffChanged:     "changed",
+	DiffTypeChanged: "type-changed",
+	DiffMoved:       "moved",
+}
+
+func (self DiffKind) String() string {
+	if s, ok := diffKindNames[self]; ok {
+		return s
+	}
+	return fmt.Sprintf("DiffKind(%d)", int(self))
+}
+
+// a single difference; the paths are JSON Pointers valid at the moment
+// the change is applied, so the changes can be replayed in order
+type Change struct {
+	Kind DiffKind
+	Path string    // where the change happens
+	From string    // where the element comes from (DiffMoved only)
+	Old  JsonValue // the value being removed, replaced or moved
+	New  JsonValue // the value being added or the replacement
+}
+
+func (self Change) String() string {
+	switch self.Kind {
+	case DiffAdded:
+		return fmt.Sprintf("%v %+q: %s", self.Kind, self.Path, jsonOf(self.New))
+	case DiffRemoved:
+		return fmt.Sprintf("%v %+q: %s", self.Kind, self.Path, jsonOf(self.Old))
+	case DiffMoved:
+		return fmt.Sprintf("%v %+q to %+q", self.Kind, self.From, self.Path)
+	}
+	return fmt.Sprintf("%v %+q: %s -> %s", self.Kind, self.Path, jsonOf(self.Old), jsonOf(self.New))
+}
+
+type Changes []Change
+
+// renders the changes as a JSON Patch (RFC 6902) suitable for ApplyPatch()
+func (self Changes) Patch() *JsonArray {
+	patch := new(JsonArray)
+	for _, c := range self {
+		op := new(JsonObject)
+		switch c.Kind {
+		case DiffAdded:
+			op.Insert("op", NewJsonString("add"))
+			op.Insert("value", deepCopy(c.New))
+		case DiffRemoved:
+			op.Insert("op", NewJsonString("remove"))
+		case DiffMoved:
+			op.Insert("op", NewJsonString("move"))
+			op.Insert("from", new(JsonString).Set(c.From))
+		default:
+			op.Insert("op", NewJsonString("replace"))
+			op.Insert("value", deepCopy(c.New))
+		}
+		op.Insert("path", new(JsonString).Set(c.Path))
+		patch.Append(op)
+	}
+	return patch
+}
+
+// how the arrays are compared by DiffWith()
+type DiffOptions struct {
+	ArrayLCS bool   // find the longest common subsequence instead of going index by index
+	ArrayKey string // match object elements by the value of this member (takes precedence)
+}
+
+// compares two trees element by element, see DiffWith()
+func Diff(a, b JsonValue) Changes { return DiffWith(a, b, nil) }
+
+// compares two trees and returns what has to be done to turn a into b;
+// the object members are visited in the sorted key order
+func DiffWith(a, b JsonValue, options *DiffOptions) Changes {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+	d := &differ{options: options}
+	d.diff(nil, a, b)
+	return d.changes
+}
+
+type differ struct {
+	options *DiffOptions
+	changes Changes
+}
+
+func (self *differ) add(c Change) { self.changes = append(self.changes, c) }
+
+// appends a token to a copy of the path
+func subPath(path []string, t string) []string {
+	return append(path[:len(path):len(path)], t)
+}
+
+func (self *differ) diff(path []string, a, b JsonValue) {
+	if equalValues(a, b) {
+		return
+	}
+	if isNullValue(a) || isNullValue(b) || reflect.TypeOf(a) != reflect.TypeOf(b) {
+		self.add(Change{Kind: DiffTypeChanged, Path: joinPointer(path), Old: a, New: b})
+		return
+	}
+	switch x := a.(type) {
+	case *JsonObject:
+		self.diffObjects(path, *x, *b.(*JsonObject))
+	case *JsonArray:
+		switch {
+		case self.options.ArrayKey != "":
+			self.diffKeyed(path, *x, *b.(*JsonArray))
+		case self.options.ArrayLCS:
+			self.diffLCS(path, *x, *b.(*JsonArray))
+		default:
+			self.diffIndexed(path, *x, *b.(*JsonArray))
+		}
+	default:
+		self.add(Change{Kind: DiffChanged, Path: joinPointer(path), Old: a, New: b})
+	}
+}
+
+func (self *differ) diffObjects(path []string, a, b JsonObject) {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, found := a[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		o, inA := a[k]
+		n, inB := b[k]
+		switch {
+		case !inB:
+			self.add(Change{Kind: DiffRemoved, Path: joinPointer(subPath(path, k)), Old: o})
+		case !inA:
+			self.add(Change{Kind: DiffAdded, Path: joinPointer(subPath(path, k)), New: n})
+		default:
+			self.diff(subPath(path, k), o, n)
+		}
+	}
+}
+
+func (self *differ) diffIndexed(path []string, a, b JsonArray) {
+	i := 0
+	for ; i < len(a) && i < len(b); i++ {
+		self.diff(subPath(path, fmt.Sprint(i)), a[i], b[i])
+	}
+	for j := i; j < len(b); j++ {
+		self.add(Change{Kind: DiffAdded, Path: joinPointer(subPath(path, fmt.Sprint(j))), New: b[j]})
+	}
+	for j := len(a) - 1; j >= i; j-- {
+		self.add(Change{Kind: DiffRemoved, Path: joinPointer(subPath(path, fmt.Sprint(j))), Old: a[j]})
+	}
+}
+
+func (self *differ) diffLCS(path []string, a, b JsonArray) {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if equalValues(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	k := 0 // the position in the array being patched
+	var removed, added []JsonValue
+	flush := func() {
+		// removals paired with additions are just changes
+		for len(removed) > 0 && len(added) > 0 {
+			self.diff(subPath(path, fmt.Sprint(k)), removed[0], added[0])
+			removed, added = removed[1:], added[1:]
+			k++
+		}
+		for _, o := range removed {
+			self.add(Change{Kind: DiffRemoved, Path: joinPointer(subPath(path, fmt.Sprint(k))), Old: o})
+		}
+		for _, n := range added {
+			self.add(Change{Kind: DiffAdded, Path: joinPointer(subPath(path, fmt.Sprint(k))), New: n})
+			k++
+		}
+		removed, added = nil, nil
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && equalValues(a[i], b[j]):
+			flush()
+			i, j, k = i+1, j+1, k+1
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			removed = append(removed, a[i])
+			i++
+		default:
+			added = append(added, b[j])
+			j++
+		}
+	}
+	flush()
+}
+
+// the matching key of an array element, if any
+func (self *differ) elementKey(v JsonValue) (string, bool) {
+	if o, ok := v.(*JsonObject); ok && !o.IsNull() {
+		if k, found := (*o)[self.options.ArrayKey]; found {
+			return jsonOf(k), true
+		}
+	}
+	return "", false
+}
+
+func (self *differ) diffKeyed(path []string, a, b JsonArray) {
+	// match the elements of b to the (first unused) elements of a with the same key
+	byKey := make(map[string][]int)
+	for i, o := range a {
+		if k, ok := self.elementKey(o); ok {
+			byKey[k] = append(byKey[k], i)
+		}
+	}
+	match := make([]int, len(b)) // index in a or -1
+	used := make([]bool, len(a))
+	for j, n := range b {
+		match[j] = -1
+		if k, ok := self.elementKey(n); ok && len(byKey[k]) > 0 {
+			match[j] = byKey[k][0]
+			used[match[j]] = true
+			byKey[k] = byKey[k][1:]
+		}
+	}
+
+	// current keeps the original indices of what is in the array being patched
+	var current []int
+	for i := range a {
+		if used[i] {
+			current = append(current, i)
+		}
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		if !used[i] {
+			self.add(Change{Kind: DiffRemoved, Path: joinPointer(subPath(path, fmt.Sprint(i))), Old: a[i]})
+		}
+	}
+	for j, i := range match {
+		at := subPath(path, fmt.Sprint(j))
+		if i < 0 {
+			self.add(Change{Kind: DiffAdded, Path: joinPointer(at), New: b[j]})
+			current = append(current[:j], append([]int{-1}, current[j:]...)...)
+			continue
+		}
+		p := j
+		for current[p] != i {
+			p++
+		}
+		if p != j {
+			self.add(Change{Kind: DiffMoved, Path: joinPointer(at),
+				From: joinPointer(subPath(path, fmt.Sprint(p))), Old: a[i]})
+			copy(current[j+1:p+1], current[j:p])
+			current[j] = i
+		}
+		self.diff(at, a[i], b[j])
+	}
+}
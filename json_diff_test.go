@@ -0,0 +1,88 @@
+package json
+
+import "testing"
+
+func TestDiff(t *testing.T) {
+	// the diff must replay into the second value
+	test := func(a, b string, options *DiffOptions, expected ...string) {
+		va, vb := mustParse(t, a), mustParse(t, b)
+		changes := DiffWith(va, vb, options)
+		if len(changes) != len(expected) {
+			t.Errorf("DiffWith(%s, %s, %+v) = %v", a, b, options, changes)
+		} else {
+			for i, c := range changes {
+				if c.String() != expected[i] {
+					t.Errorf("DiffWith(%s, %s, %+v)[%d] = %q, not %q", a, b, options, i, c.String(), expected[i])
+				}
+			}
+		}
+		r, err := ApplyPatch(va, changes.Patch())
+		if err != nil {
+			t.Errorf("DiffWith(%s, %s, %+v).Patch() = %s: %v", a, b, options, changes.Patch().Json(), err)
+		} else if !equalValues(r, vb) {
+			t.Errorf("DiffWith(%s, %s, %+v).Patch() gives %s", a, b, options, jsonOf(r))
+		}
+	}
+
+	test(`{"a":1}`, `{"a":1}`, nil)
+	test(`{"a":1,"b":2,"c":{"d":"x"}}`, `{"a":1,"c":{"d":"y"},"e":true}`, nil,
+		`removed "/b": 2`,
+		`changed "/c/d": "x" -> "y"`,
+		`added "/e": true`)
+	test(`{"a":1,"b":null}`, `{"a":1.5,"b":"x"}`, nil,
+		`type-changed "/a": 1 -> 1.500000`,
+		`type-changed "/b": null -> "x"`)
+	test(`[1,2,3]`, `{"a/b":1}`, nil,
+		`type-changed "": [ 1, 2, 3 ] -> { "a/b": 1 }`)
+	test(`{"x":[1,2,3,4]}`, `{"x":[1,5]}`, nil,
+		`changed "/x/1": 2 -> 5`,
+		`removed "/x/3": 4`,
+		`removed "/x/2": 3`)
+	test(`[1,2]`, `[1,2,3,4]`, nil,
+		`added "/2": 3`,
+		`added "/3": 4`)
+
+	lcs := &DiffOptions{ArrayLCS: true}
+	test(`[1,2,3,4,5]`, `[0,1,3,4,6,5]`, lcs,
+		`added "/0": 0`,
+		`removed "/2": 2`,
+		`added "/4": 6`)
+	test(`[1,{"a":1},3]`, `[1,{"a":2},3]`, lcs,
+		`changed "/1/a": 1 -> 2`)
+	test(`["a","b","c"]`, `["c","b","a"]`, lcs,
+		`removed "/0": "a"`,
+		`removed "/0": "b"`,
+		`added "/1": "b"`,
+		`added "/2": "a"`)
+
+	keyed := &DiffOptions{ArrayKey: "id"}
+	test(`[{"id":1,"v":"a"},{"id":2,"v":"b"},{"id":3,"v":"c"}]`,
+		`[{"id":3,"v":"c"},{"id":1,"v":"x"},{"id":4,"v":"d"}]`, keyed,
+		`removed "/1": { "id": 2, "v": "b" }`,
+		`moved "/1" to "/0"`,
+		`changed "/1/v": "a" -> "x"`,
+		`added "/2": { "id": 4, "v": "d" }`)
+	test(`[{"id":1},{"id":2},5]`, `[7,{"id":2},{"id":1}]`, keyed,
+		`removed "/2": 5`,
+		`added "/0": 7`,
+		`moved "/2" to "/1"`)
+
+	if s := (Change{Kind: DiffKind(42)}).Kind.String(); s != "DiffKind(42)" {
+		t.Errorf("DiffKind(42).String() = %q", s)
+	}
+	if c := Diff(mustParse(t, `{"a":[1]}`), mustParse(t, `{"a":[2]}`)); len(c) != 1 || c[0].Path != "/a/0" {
+		t.Errorf("Diff() = %v", c)
+	}
+
+	// the nil members (untyped or typed) are nulls, not panics
+	nils := &JsonObject{"a": nil, "b": (*JsonInt)(nil)}
+	if !nils.Equal(&JsonObject{"a": (*JsonInt)(nil), "b": nil}) {
+		t.Errorf("nil members differ")
+	}
+	if nils.Equal(&JsonObject{"a": NewJsonInt(1), "b": nil}) || (&JsonObject{"a": NewJsonInt(1), "b": nil}).Equal(nils) {
+		t.Errorf("nil member equals 1")
+	}
+	if c := Diff(nils, &JsonObject{"a": nil, "b": NewJsonInt(1)}); len(c) != 1 || c[0].Path != "/b" {
+		t.Errorf("Diff() = %v", c)
+	}
+}
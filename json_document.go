@@ -0,0 +1,175 @@
+// A JsonObject shared by goroutines
+package json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type DocumentError error
+
+// an object document safe for concurrent use: writers to different top-level
+// members go in parallel, the ones to the same member take turns; snapshots
+// wait for the writers in progress and see all of them or none; the values go
+// in and out as copies, so nothing inside is ever shared with the callers
+type Document struct {
+	snap  sync.RWMutex // the writers share it, the snapshots take it exclusively
+	mu    sync.Mutex   // guards the root map and the locks
+	root  JsonObject
+	locks map[string]*sync.Mutex // one per top-level member
+}
+
+// an empty document
+func NewDocument() *Document {
+	return &Document{root: JsonObject{}, locks: map[string]*sync.Mutex{}}
+}
+
+// a document starting as a copy of the object
+func NewDocumentFrom(o *JsonObject) *Document {
+	r := NewDocument()
+	if !o.IsNull() {
+		r.root = *o.Clone().(*JsonObject)
+	}
+	return r
+}
+
+// the top-level member name of a dotted path
+func documentKey(path string) string {
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// runs fn over a one-member object holding the top-level member of the path,
+// with that member locked; the member fn leaves there is stored back
+func (self *Document) member(path string, write bool, fn func(o *JsonObject) error) error {
+	if path == "" {
+		return DocumentError(fmt.Errorf("Empty path"))
+	}
+	key := documentKey(path)
+	self.snap.RLock()
+	defer self.snap.RUnlock()
+
+	self.mu.Lock()
+	lock, found := self.locks[key]
+	if !found {
+		lock = new(sync.Mutex)
+		self.locks[key] = lock
+	}
+	self.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+	self.mu.Lock()
+	v, exists := self.root[key] // only now, the previous writer might have replaced it
+	self.mu.Unlock()
+	o := JsonObject{}
+	if exists {
+		o[key] = v
+	}
+	if e := fn(&o); e != nil || !write {
+		return e
+	}
+	self.mu.Lock()
+	if v, exists = o[key]; exists {
+		self.root[key] = v
+	} else {
+		delete(self.root, key)
+	}
+	self.mu.Unlock()
+	return nil
+}
+
+// sets a copy of the value (see ToJsonValue()) at the dotted path, see Builder.Set()
+func (self *Document) Set(path string, v interface{}) error {
+	x, e := ToJsonValue(v)
+	if e != nil {
+		return DocumentError(fmt.Errorf("Set(%+q): %v", path, e))
+	}
+	x = deepCopy(x)
+	return self.member(path, true, func(o *JsonObject) error {
+		return NewBuilderFor(o).Set(path, x).Err()
+	})
+}
+
+// appends a copy of the value to the array at the dotted path, see Builder.Append()
+func (self *Document) Append(path string, v interface{}) error {
+	x, e := ToJsonValue(v)
+	if e != nil {
+		return DocumentError(fmt.Errorf("Append(%+q): %v", path, e))
+	}
+	x = deepCopy(x)
+	return self.member(path, true, func(o *JsonObject) error {
+		return NewBuilderFor(o).Append(path, x).Err()
+	})
+}
+
+// removes the member or element at the dotted path
+func (self *Document) Delete(path string) error {
+	return self.member(path, true, func(o *JsonObject) error {
+		parent, last := "", path
+		if i := strings.LastIndexByte(path, '.'); i >= 0 {
+			parent, last = path[:i], path[i+1:]
+		}
+		c, e := Lookup(o, parent)
+		if e != nil {
+			return DocumentError(fmt.Errorf("Delete(%+q): %w", path, e))
+		}
+		switch x := c.(type) {
+		case *JsonObject:
+			if !x.Delete(last) {
+				return DocumentError(fmt.Errorf("Delete(%+q): %w", path, memberError(last, ErrMissing)))
+			}
+			return nil
+		case *JsonArray:
+			i, e := strconv.Atoi(last)
+			if e == nil {
+				_, e = x.RemoveAt(i)
+			}
+			if e != nil {
+				return DocumentError(fmt.Errorf("Delete(%+q): %v", path, e))
+			}
+			return nil
+		}
+		return DocumentError(fmt.Errorf("Delete(%+q): cannot step into %s", path, jsonOf(c)))
+	})
+}
+
+// a copy of the value at the dotted path, see Lookup()
+func (self *Document) Get(path string) (r JsonValue, e error) {
+	e = self.member(path, false, func(o *JsonObject) error {
+		v, e := Lookup(o, path)
+		r = deepCopy(v)
+		return e
+	})
+	return
+}
+
+// runs fn over a copy of the whole document with all the writers waiting;
+// the copy replaces the document unless fn fails
+func (self *Document) Update(fn func(o *JsonObject) error) error {
+	self.snap.Lock()
+	defer self.snap.Unlock()
+	o := self.root.Clone().(*JsonObject)
+	if e := fn(o); e != nil {
+		return e
+	}
+	if o.IsNull() {
+		*o = JsonObject{}
+	}
+	self.root = *o
+	return nil
+}
+
+// a consistent copy of the whole document
+func (self *Document) Snapshot() *JsonObject {
+	self.snap.Lock()
+	defer self.snap.Unlock()
+	return self.root.Clone().(*JsonObject)
+}
+
+// the JSON of a consistent snapshot
+func (self *Document) Json() string { return self.Snapshot().Json() }
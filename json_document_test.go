@@ -0,0 +1,137 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestDocument(t *testing.T) {
+	d := NewDocument()
+	if e := d.Set("host.name", "box"); e != nil {
+		t.Errorf("Set(): %v", e)
+	}
+	d.Append("host.disks", map[string]interface{}{"name": "sda", "used": 42})
+	d.Append("host.disks", map[string]interface{}{"name": "sdb", "used": 7})
+	d.Set("load", 0.5)
+	if d.Json() != `{ "host": { "disks": [ { "name": "sda", "used": 42 }, { "name": "sdb", "used": 7 } ], "name": "box" }, "load": 0.500000 }` {
+		t.Errorf("Json(): %s", d.Json())
+	}
+	if v, e := d.Get("host.disks.1.name"); e != nil || jsonOf(v) != `"sdb"` {
+		t.Errorf("Get() = %s, %v", jsonOf(v), e)
+	}
+	if _, e := d.Get("host.nothing"); !errors.Is(e, ErrMissing) {
+		t.Errorf("Get(missing): %v", e)
+	}
+	if e := d.Delete("host.disks.0"); e != nil {
+		t.Errorf("Delete(): %v", e)
+	}
+	if e := d.Delete("load"); e != nil {
+		t.Errorf("Delete(): %v", e)
+	}
+	if e := d.Delete("load"); e == nil {
+		t.Errorf("Delete(missing): no error")
+	}
+	if e := d.Delete("host.disks.x"); e == nil {
+		t.Errorf("Delete(bad index): no error")
+	}
+	if d.Json() != `{ "host": { "disks": [ { "name": "sdb", "used": 7 } ], "name": "box" } }` {
+		t.Errorf("Delete(): %s", d.Json())
+	}
+	if e := d.Set("", 1); e == nil {
+		t.Errorf("Set(empty): no error")
+	}
+
+	// nothing is shared with the callers
+	v := NewJsonArray([]JsonValue{NewJsonInt(1)})
+	d.Set("v", v)
+	v.Append(NewJsonInt(2))
+	got, _ := d.Get("v")
+	got.Append(NewJsonInt(3))
+	snap := d.Snapshot()
+	snap.Insert("x", nil)
+	if d.Json() != `{ "host": { "disks": [ { "name": "sdb", "used": 7 } ], "name": "box" }, "v": [ 1 ] }` {
+		t.Errorf("shared: %s", d.Json())
+	}
+
+	oops := errors.New("oops")
+	if e := d.Update(func(o *JsonObject) error { o.Delete("host"); return oops }); e != oops || !d.Snapshot().Has("host") {
+		t.Errorf("Update() failed, yet changed: %s", d.Json())
+	}
+	d.Update(func(o *JsonObject) error { o.Rename("v", "w"); return nil })
+	if !d.Snapshot().Has("w") {
+		t.Errorf("Update(): %s", d.Json())
+	}
+	if e := NewDocumentFrom(snap).Set("x.y", true); e != nil || !snap.Has("x") || snap.Json() != `{ "host": { "disks": [ { "name": "sdb", "used": 7 } ], "name": "box" }, "v": [ 1 ], "x": null }` {
+		t.Errorf("NewDocumentFrom() shares the object: %v", e)
+	}
+}
+
+// run it with -race
+func TestDocumentStress(t *testing.T) {
+	const (
+		writers = 8
+		rounds  = 200
+	)
+	d := NewDocument()
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			own := fmt.Sprintf("w%d", w)
+			for i := 0; i < rounds; i++ {
+				if e := d.Set(own+".count", i+1); e != nil {
+					t.Errorf("Set(): %v", e)
+					return
+				}
+				if e := d.Append(own+".seen", i); e != nil {
+					t.Errorf("Append(): %v", e)
+					return
+				}
+				d.Append("shared", w) // all of them into the same member
+				if i%10 == 0 {
+					d.Delete(own + ".seen.0")
+				}
+			}
+		}(w)
+	}
+	// the readers see consistent states while the writers go on
+	for r := 0; r < 2; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				s := d.Snapshot()
+				total := 0
+				for w := 0; w < writers; w++ {
+					n, _ := s.LookupInt(fmt.Sprintf("w%d.count", w))
+					total += n
+				}
+				// a writer appends to "shared" only after setting its count
+				if shared, _ := s.GetArray("shared"); shared.Len() > total {
+					t.Errorf("Snapshot(): %d shared, %d counted", shared.Len(), total)
+					return
+				}
+				d.Get("w0.seen")
+				_ = d.Json()
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := d.Snapshot()
+	if shared, _ := s.GetArray("shared"); shared.Len() != writers*rounds {
+		t.Errorf("shared: %d", shared.Len())
+	}
+	for w := 0; w < writers; w++ {
+		own := fmt.Sprintf("w%d", w)
+		if n, _ := s.LookupInt(own + ".count"); n != rounds {
+			t.Errorf("%s.count = %d", own, n)
+		}
+		if seen, _ := s.LookupArray(own + ".seen"); seen.Len() != rounds-rounds/10 {
+			t.Errorf("%s.seen: %d", own, seen.Len())
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Configurable equality of JsonValue trees
+package json
+
+import (
+	"fmt"
+	"math"
+)
+
+// what EqualWith() tolerates; the zero value makes it work like .Equal()
+type EqualOptions struct {
+	NumericEquivalence bool     // JsonInt(1) equals JsonFloat(1.0)
+	AbsTolerance       float64  // the floats may differ by that much...
+	RelTolerance       float64  // ...or by that fraction of the larger one
+	UnorderedArrays    bool     // arrays are compared as multisets
+	IgnorePaths        []string // JSON Pointers of the values not to compare at all
+	MissingAsNull      bool     // a missing member equals a null one
+}
+
+// compares the values the way the options say (nil options make it .Equal());
+// the tolerances apply when at least one of two numbers is a float
+func EqualWith(a, b JsonValue, options *EqualOptions) bool {
+	if options == nil {
+		return equalValues(a, b)
+	}
+	q := &equality{options, map[string]bool{}}
+	for _, p := range options.IgnorePaths {
+		q.ignored[p] = true
+	}
+	return q.equal(nil, a, b)
+}
+
+type equality struct {
+	*EqualOptions
+	ignored map[string]bool
+}
+
+func (self *equality) equal(path []string, a, b JsonValue) bool {
+	if self.ignored[joinPointer(path)] {
+		return true
+	}
+	if isNullValue(a) || isNullValue(b) {
+		return isNullValue(a) && isNullValue(b)
+	}
+	switch x := a.(type) {
+	case *JsonInt, *JsonFloat:
+		return self.numbers(a, b)
+	case *JsonArray:
+		y, ok := b.(*JsonArray)
+		if !ok || len(*x) != len(*y) {
+			return false
+		}
+		if self.UnorderedArrays {
+			return self.multiset(path, *x, *y)
+		}
+		for i := range *x {
+			if !self.equal(subPath(path, fmt.Sprint(i)), (*x)[i], (*y)[i]) {
+				return false
+			}
+		}
+		return true
+	case *JsonObject:
+		y, ok := b.(*JsonObject)
+		if !ok {
+			return false
+		}
+		keys := x.Keys()
+		for _, k := range y.Keys() {
+			if !x.Has(k) {
+				keys = append(keys, k)
+			}
+		}
+		for _, k := range keys {
+			p := subPath(path, k)
+			if (!x.Has(k) || !y.Has(k)) && !self.MissingAsNull && !self.ignored[joinPointer(p)] {
+				return false
+			}
+			if !self.equal(p, (*x)[k], (*y)[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a.Equal(b)
+}
+
+func (self *equality) numbers(a, b JsonValue) bool {
+	_, aInt := a.(*JsonInt)
+	_, bInt := b.(*JsonInt)
+	x, aok := pathNumber(a)
+	y, bok := pathNumber(b)
+	if !aok || !bok || (aInt != bInt && !self.NumericEquivalence) {
+		return false
+	}
+	if aInt && bInt {
+		return a.Equal(b) // no rounding for the big ones
+	}
+	d := math.Abs(x - y)
+	return d == 0 || d <= self.AbsTolerance || d <= self.RelTolerance*math.Max(math.Abs(x), math.Abs(y))
+}
+
+// whether every element of a has its own equal one in b (a maximum
+// bipartite matching, for the tolerances make equality non-transitive)
+func (self *equality) multiset(path []string, a, b JsonArray) bool {
+	eq := make([][]bool, len(a))
+	for i := range a {
+		eq[i] = make([]bool, len(b))
+		for j := range b {
+			eq[i][j] = self.equal(subPath(path, fmt.Sprint(i)), a[i], b[j])
+		}
+	}
+	match := make([]int, len(b)) // the element of a matched by the one of b
+	for j := range match {
+		match[j] = -1
+	}
+	var try func(i int, seen []bool) bool
+	try = func(i int, seen []bool) bool {
+		for j := range b {
+			if eq[i][j] && !seen[j] {
+				seen[j] = true
+				if match[j] < 0 || try(match[j], seen) {
+					match[j] = i
+					return true
+				}
+			}
+		}
+		return false
+	}
+	for i := range a {
+		if !try(i, make([]bool, len(b))) {
+			return false
+		}
+	}
+	return true
+}
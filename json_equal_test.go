@@ -0,0 +1,49 @@
+package json
+
+import "testing"
+
+func TestEqualWith(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		options  *EqualOptions
+		expected bool
+	}{
+		{`1`, `1.0`, nil, false},
+		{`1`, `1.0`, &EqualOptions{}, false},
+		{`1`, `1.0`, &EqualOptions{NumericEquivalence: true}, true},
+		{`[ 1, 2.5 ]`, `[ 1.0, 2.5 ]`, &EqualOptions{NumericEquivalence: true}, true},
+		{`1`, `"1"`, &EqualOptions{NumericEquivalence: true}, false},
+		{`0.1`, `0.1001`, &EqualOptions{}, false},
+		{`0.1`, `0.1001`, &EqualOptions{AbsTolerance: 0.001}, true},
+		{`1000.0`, `1000.5`, &EqualOptions{RelTolerance: 0.001}, true},
+		{`1000.0`, `1002.0`, &EqualOptions{RelTolerance: 0.001}, false},
+		{`1000`, `1000.5`, &EqualOptions{RelTolerance: 0.001}, false},
+		{`1000`, `1000.5`, &EqualOptions{RelTolerance: 0.001, NumericEquivalence: true}, true},
+		{`1000`, `1001`, &EqualOptions{AbsTolerance: 5}, false},
+		{`[ 1, 2, 2 ]`, `[ 2, 1, 2 ]`, &EqualOptions{}, false},
+		{`[ 1, 2, 2 ]`, `[ 2, 1, 2 ]`, &EqualOptions{UnorderedArrays: true}, true},
+		{`[ 1, 2, 2 ]`, `[ 2, 1, 1 ]`, &EqualOptions{UnorderedArrays: true}, false},
+		{`[ { "a": [ 1, 2 ] }, 3 ]`, `[ 3, { "a": [ 2, 1 ] } ]`, &EqualOptions{UnorderedArrays: true}, true},
+		// greedy matching would pair 1.0 with 1.05 and fail
+		{`[ 1.0, 1.1 ]`, `[ 1.05, 0.95 ]`, &EqualOptions{UnorderedArrays: true, AbsTolerance: 0.06}, true},
+		{`{ "a": 1, "t": "now" }`, `{ "a": 1, "t": "then" }`, &EqualOptions{IgnorePaths: []string{"/t"}}, true},
+		{`{ "a": 1, "t": "now" }`, `{ "a": 1 }`, &EqualOptions{IgnorePaths: []string{"/t"}}, true},
+		{`{ "a": [ { "t": 1 } ] }`, `{ "a": [ { "t": 2 } ] }`, &EqualOptions{IgnorePaths: []string{"/a/0/t"}}, true},
+		{`{ "a": [ { "t": 1 } ] }`, `{ "a": [ { "t": 2 } ] }`, &EqualOptions{IgnorePaths: []string{"/t"}}, false},
+		{`{ "a": 1, "b": null }`, `{ "a": 1 }`, &EqualOptions{}, false},
+		{`{ "a": 1, "b": null }`, `{ "a": 1 }`, &EqualOptions{MissingAsNull: true}, true},
+		{`{ "a": 1 }`, `{ "a": 1, "b": 0 }`, &EqualOptions{MissingAsNull: true}, false},
+		{`{ "a": true }`, `[ true ]`, &EqualOptions{}, false},
+		{`null`, `{ "a": 1 }`, &EqualOptions{}, false},
+		{`null`, `""`, &EqualOptions{}, true},
+	}
+	for _, x := range tests {
+		a, b := mustParse(t, x.a), mustParse(t, x.b)
+		if r := EqualWith(a, b, x.options); r != x.expected {
+			t.Errorf("EqualWith(%s, %s, %+v) = %v", x.a, x.b, x.options, r)
+		}
+		if r := EqualWith(b, a, x.options); r != x.expected {
+			t.Errorf("EqualWith(%s, %s, %+v) = %v", x.b, x.a, x.options, r)
+		}
+	}
+}
@@ -0,0 +1,2458 @@
+// A jq-like filter language over JsonValue trees
+package json
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type FilterError error
+
+// a compiled filter: a jq program (the subset without definitions, paths and
+// assignments) that turns a value into any number of values:
+//
+//	.  ..  .a  ."a"  .[e]  .[e:e]  .[]  ?  $name  literals  "\(e)"  [e]  {a, b: e, (e): e}
+//	e | e   e, e   e // e   or  and  == != < <= > >=  + - * / %   -e
+//	if e then e elif e then e else e end   try e catch e   e as $name | e
+//	reduce e as $name (e; e)   @text @json @csv @tsv @base64   and the functions
+//
+// the object members go in the order of their keys; the numbers stay ints
+// when the result is an exact int
+type Filter struct {
+	source string
+	body   filterNode
+}
+
+// compiles the filter program; an empty one is "."
+func Compile(s string) (*Filter, error) {
+	p := &filterParser{pathParser: pathParser{s: s}}
+	p.ws()
+	if p.pos == len(p.s) {
+		return &Filter{source: s, body: identityNode{}}, nil
+	}
+	body, e := p.pipe()
+	if e != nil {
+		return nil, e
+	}
+	p.ws()
+	if p.pos < len(p.s) {
+		return nil, p.error("Unexpected %+q", p.s[p.pos:])
+	}
+	return &Filter{source: s, body: body}, nil
+}
+
+// like Compile() but panics on a bad program; for the programs known in advance
+func MustCompile(s string) *Filter {
+	f, e := Compile(s)
+	if e != nil {
+		panic(e)
+	}
+	return f
+}
+
+func (self *Filter) String() string { return self.source }
+
+// runs the filter over the value and returns all its outputs; on an error
+// the outputs made before it are returned along with it; the outputs may
+// share parts with the input
+func (self *Filter) Run(v JsonValue) ([]JsonValue, error) {
+	if e := FindCycle(v); e != nil {
+		return nil, e
+	}
+	var r []JsonValue
+	e := self.body.eval(v, nil, func(x JsonValue) error {
+		r = append(r, x)
+		return nil
+	})
+	return r, e
+}
+
+/*----------------------------------------------------------------------------*/
+
+// takes the outputs one by one; an error stops the filter
+type filterEmit func(JsonValue) error
+
+// the variables bound by "as", innermost first
+type filterEnv struct {
+	name  string
+	value JsonValue
+	next  *filterEnv
+}
+
+func (self *filterEnv) lookup(name string) JsonValue {
+	for ; self != nil; self = self.next {
+		if self.name == name {
+			return self.value
+		}
+	}
+	return nil
+}
+
+type filterNode interface {
+	eval(in JsonValue, env *filterEnv, out filterEmit) error
+}
+
+// all the outputs of the node
+func filterAll(n filterNode, in JsonValue, env *filterEnv) ([]JsonValue, error) {
+	var r []JsonValue
+	e := n.eval(in, env, func(v JsonValue) error {
+		r = append(r, v)
+		return nil
+	})
+	return r, e
+}
+
+// runs fn over every combination of the outputs of the nodes
+func filterEach(nodes []filterNode, in JsonValue, env *filterEnv, vals []JsonValue, fn func([]JsonValue) error) error {
+	if len(vals) == len(nodes) {
+		return fn(vals)
+	}
+	return nodes[len(vals)].eval(in, env, func(v JsonValue) error {
+		return filterEach(nodes, in, env, append(vals, v), fn)
+	})
+}
+
+// an error raised by error(v), the value is what "catch" gets
+type filterRaised struct{ value JsonValue }
+
+func (self *filterRaised) Error() string {
+	if s, ok := filterString(self.value); ok {
+		return s
+	}
+	return filterJson(self.value) + " (not a string)"
+}
+
+// what "catch" gets for the error
+func filterCaught(e error) JsonValue {
+	var r *filterRaised
+	if errors.As(e, &r) {
+		return r.value
+	}
+	return NewJsonString(e.Error())
+}
+
+type identityNode struct{}
+
+func (identityNode) eval(in JsonValue, _ *filterEnv, out filterEmit) error { return out(in) }
+
+// .. is recurse(.[]?)
+type recurseNode struct{}
+
+func (recurseNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	if e := out(in); e != nil {
+		return e
+	}
+	for _, x := range filterChildren(in) {
+		if e := (recurseNode{}).eval(x, env, out); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+type literalNode struct{ value JsonValue }
+
+func (self literalNode) eval(_ JsonValue, _ *filterEnv, out filterEmit) error { return out(self.value) }
+
+type varNode struct{ name string }
+
+func (self varNode) eval(_ JsonValue, env *filterEnv, out filterEmit) error {
+	return out(env.lookup(self.name))
+}
+
+// target[index], the index is evaluated against the input, not the target
+type indexNode struct{ target, index filterNode }
+
+func (self indexNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.target.eval(in, env, func(t JsonValue) error {
+		return self.index.eval(in, env, func(k JsonValue) error {
+			v, e := filterIndex(t, k)
+			if e != nil {
+				return e
+			}
+			return out(v)
+		})
+	})
+}
+
+// target[from:to], either may be nil
+type sliceNode struct{ target, from, to filterNode }
+
+func (self sliceNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	bound := func(n filterNode, fn func(JsonValue) error) error {
+		if n == nil {
+			return fn(nil)
+		}
+		return n.eval(in, env, fn)
+	}
+	return self.target.eval(in, env, func(t JsonValue) error {
+		return bound(self.to, func(to JsonValue) error {
+			return bound(self.from, func(from JsonValue) error {
+				v, e := filterSlice(t, from, to)
+				if e != nil {
+					return e
+				}
+				return out(v)
+			})
+		})
+	})
+}
+
+// target[]
+type iterateNode struct{ target filterNode }
+
+func (self iterateNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.target.eval(in, env, func(t JsonValue) error {
+		if !filterIterable(t) {
+			return FilterError(fmt.Errorf("Cannot iterate over %s", filterDescribe(t)))
+		}
+		for _, x := range filterChildren(t) {
+			if e := out(x); e != nil {
+				return e
+			}
+		}
+		return nil
+	})
+}
+
+// try body catch handler, body? is the one without the handler; only the
+// errors of the body are caught, not the ones of what takes its outputs
+type tryNode struct{ body, handler filterNode }
+
+func (self tryNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	var down error
+	e := self.body.eval(in, env, func(v JsonValue) error {
+		down = out(v)
+		return down
+	})
+	if down != nil || e == nil {
+		return down
+	}
+	if self.handler == nil {
+		return nil
+	}
+	return self.handler.eval(filterCaught(e), env, out)
+}
+
+type pipeNode struct{ left, right filterNode }
+
+func (self pipeNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.left.eval(in, env, func(v JsonValue) error {
+		return self.right.eval(v, env, out)
+	})
+}
+
+type commaNode struct{ left, right filterNode }
+
+func (self commaNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	if e := self.left.eval(in, env, out); e != nil {
+		return e
+	}
+	return self.right.eval(in, env, out)
+}
+
+// left // right: the true outputs of the left one, or (if none, errors
+// ignored) the outputs of the right one
+type altNode struct{ left, right filterNode }
+
+func (self altNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	found := false
+	var down error
+	self.left.eval(in, env, func(v JsonValue) error {
+		if !filterTruthy(v) {
+			return nil
+		}
+		found = true
+		down = out(v)
+		return down
+	})
+	if down != nil || found {
+		return down
+	}
+	return self.right.eval(in, env, out)
+}
+
+type andNode struct{ left, right filterNode }
+
+func (self andNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.left.eval(in, env, func(a JsonValue) error {
+		if !filterTruthy(a) {
+			return out(NewJsonBool(false))
+		}
+		return self.right.eval(in, env, func(b JsonValue) error {
+			return out(NewJsonBool(filterTruthy(b)))
+		})
+	})
+}
+
+type orNode struct{ left, right filterNode }
+
+func (self orNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.left.eval(in, env, func(a JsonValue) error {
+		if filterTruthy(a) {
+			return out(NewJsonBool(true))
+		}
+		return self.right.eval(in, env, func(b JsonValue) error {
+			return out(NewJsonBool(filterTruthy(b)))
+		})
+	})
+}
+
+// arithmetic and comparisons; like jq, the right outputs go in the outer loop
+type binaryNode struct {
+	op          string
+	left, right filterNode
+}
+
+func (self binaryNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.right.eval(in, env, func(b JsonValue) error {
+		return self.left.eval(in, env, func(a JsonValue) error {
+			v, e := filterBinary(self.op, a, b)
+			if e != nil {
+				return e
+			}
+			return out(v)
+		})
+	})
+}
+
+type negNode struct{ operand filterNode }
+
+func (self negNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.operand.eval(in, env, func(v JsonValue) error {
+		switch x := v.(type) {
+		case *JsonInt:
+			if x != nil && int(*x) != minInt {
+				return out(NewJsonInt(-int(*x)))
+			}
+		case *JsonFloat:
+			if x != nil {
+				return out(NewJsonFloat(-float64(*x)))
+			}
+		}
+		if f, ok := pathNumber(v); ok {
+			return out(NewJsonFloat(-f))
+		}
+		return FilterError(fmt.Errorf("%s cannot be negated", filterDescribe(v)))
+	})
+}
+
+// [body], [] when body is nil
+type arrayNode struct{ body filterNode }
+
+func (self arrayNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	r := JsonArray{}
+	if self.body != nil {
+		v, e := filterAll(self.body, in, env)
+		if e != nil {
+			return e
+		}
+		r = append(r, v...)
+	}
+	return out(&r)
+}
+
+type objectEntry struct{ key, value filterNode }
+
+type objectMember struct {
+	key   string
+	value JsonValue
+}
+
+// {...}, one output for every combination of the outputs of the entries
+type objectNode []objectEntry
+
+func (self objectNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.build(nil, in, env, out)
+}
+
+func (self objectNode) build(members []objectMember, in JsonValue, env *filterEnv, out filterEmit) error {
+	if len(members) == len(self) {
+		r := JsonObject{}
+		for _, m := range members {
+			r[m.key] = m.value
+		}
+		return out(&r)
+	}
+	entry := self[len(members)]
+	return entry.key.eval(in, env, func(k JsonValue) error {
+		key, ok := filterString(k)
+		if !ok {
+			return FilterError(fmt.Errorf("Object keys must be strings, not %s", filterDescribe(k)))
+		}
+		return entry.value.eval(in, env, func(v JsonValue) error {
+			return self.build(append(members, objectMember{key, v}), in, env, out)
+		})
+	})
+}
+
+// a string with \(...) in it: the values go as tostring does
+type stringNode []filterNode
+
+func (self stringNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return filterEach(self, in, env, make([]JsonValue, 0, len(self)), func(parts []JsonValue) error {
+		var b strings.Builder
+		for _, v := range parts {
+			s, e := filterText(v)
+			if e != nil {
+				return e
+			}
+			b.WriteString(s)
+		}
+		return out(NewJsonString(b.String()))
+	})
+}
+
+// source as $name | body
+type asNode struct {
+	source filterNode
+	name   string
+	body   filterNode
+}
+
+func (self asNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.source.eval(in, env, func(v JsonValue) error {
+		return self.body.eval(in, &filterEnv{self.name, v, env}, out)
+	})
+}
+
+// reduce source as $name (init; update); the last output of update is the
+// next value, null if none
+type reduceNode struct {
+	source       filterNode
+	name         string
+	init, update filterNode
+}
+
+func (self reduceNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.init.eval(in, env, func(acc JsonValue) error {
+		e := self.source.eval(in, env, func(v JsonValue) error {
+			var last JsonValue
+			e := self.update.eval(acc, &filterEnv{self.name, v, env}, func(x JsonValue) error {
+				last = x
+				return nil
+			})
+			acc = last
+			return e
+		})
+		if e != nil {
+			return e
+		}
+		return out(acc)
+	})
+}
+
+type ifNode struct{ cond, then, otherwise filterNode }
+
+func (self ifNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.cond.eval(in, env, func(c JsonValue) error {
+		if filterTruthy(c) {
+			return self.then.eval(in, env, out)
+		}
+		return self.otherwise.eval(in, env, out)
+	})
+}
+
+type callNode struct {
+	fn   filterBuiltin
+	args []filterNode
+}
+
+func (self callNode) eval(in JsonValue, env *filterEnv, out filterEmit) error {
+	return self.fn(in, self.args, env, out)
+}
+
+// @name: the input formatted as a string
+type formatNode struct {
+	fn func(JsonValue) (string, error)
+}
+
+func (self formatNode) eval(in JsonValue, _ *filterEnv, out filterEmit) error {
+	s, e := self.fn(in)
+	if e != nil {
+		return e
+	}
+	return out(NewJsonString(s))
+}
+
+/*----------------------------------------------------------------------------*/
+
+// the jq type name; "" is null here as everywhere else in the package
+func filterType(v JsonValue) string {
+	if isNullValue(v) {
+		return "null"
+	}
+	switch v.(type) {
+	case *JsonBool:
+		return "boolean"
+	case *JsonInt, *JsonFloat:
+		return "number"
+	case *JsonString:
+		return "string"
+	case *JsonArray:
+		return "array"
+	case *JsonObject:
+		return "object"
+	}
+	return "null"
+}
+
+// false and null are false, all the rest is true
+func filterTruthy(v JsonValue) bool {
+	if b, ok := v.(*JsonBool); ok && b != nil {
+		return bool(*b)
+	}
+	return !isNullValue(v)
+}
+
+// the string of a *JsonString, including "" (which is null otherwise)
+func filterString(v JsonValue) (string, bool) {
+	if s, ok := v.(*JsonString); ok && s != nil {
+		return string(*s), true
+	}
+	return "", false
+}
+
+func filterJson(v JsonValue) string {
+	s, e := Format(v, "")
+	if e != nil {
+		return jsonOf(v)
+	}
+	return s
+}
+
+// the value for the error messages: its type and (the start of) its JSON
+func filterDescribe(v JsonValue) string {
+	s := filterJson(v)
+	if len(s) > 16 {
+		s = s[:13] + "..."
+	}
+	return fmt.Sprintf("%s (%s)", filterType(v), s)
+}
+
+// tostring: the strings as they are, the rest as compact JSON
+func filterText(v JsonValue) (string, error) {
+	if s, ok := filterString(v); ok {
+		return s, nil
+	}
+	return Format(v, "")
+}
+
+func filterIterable(v JsonValue) bool {
+	switch v.(type) {
+	case *JsonArray, *JsonObject:
+		return !isNullValue(v)
+	}
+	return false
+}
+
+// the elements, or the member values by the sorted keys
+func filterChildren(v JsonValue) []JsonValue {
+	if isNullValue(v) {
+		return nil
+	}
+	switch x := v.(type) {
+	case *JsonArray:
+		return *x
+	case *JsonObject:
+		r := make([]JsonValue, 0, len(*x))
+		for _, k := range sortedKeys(*x) {
+			r = append(r, (*x)[k])
+		}
+		return r
+	}
+	return nil
+}
+
+// the index as an int, the floats truncated
+func filterInt(v JsonValue) (int, bool) {
+	switch x := v.(type) {
+	case *JsonInt:
+		if x != nil {
+			return int(*x), true
+		}
+	case *JsonFloat:
+		if x != nil {
+			i, e := floatToInt(math.Trunc(float64(*x)))
+			return i, e == nil
+		}
+	}
+	return 0, false
+}
+
+func filterIndex(t, k JsonValue) (JsonValue, error) {
+	switch x := t.(type) {
+	case *JsonObject:
+		if s, ok := filterString(k); ok {
+			if isNullValue(t) {
+				return nil, nil
+			}
+			return (*x)[s], nil
+		}
+	case *JsonArray:
+		if i, ok := filterInt(k); ok {
+			if i < 0 {
+				i += len(*x)
+			}
+			if i < 0 || i >= len(*x) {
+				return nil, nil
+			}
+			return (*x)[i], nil
+		}
+	}
+	if isNullValue(t) {
+		if _, ok := filterString(k); ok {
+			return nil, nil
+		}
+		if _, ok := filterInt(k); ok {
+			return nil, nil
+		}
+	}
+	return nil, FilterError(fmt.Errorf("Cannot index %s with %s", filterType(t), filterDescribe(k)))
+}
+
+// the bounds clamped to [0, n], negative ones counted from the end
+func filterBounds(from, to JsonValue, n int) (int, int, error) {
+	bound := func(v JsonValue, dflt int) (int, error) {
+		if isNullValue(v) {
+			return dflt, nil
+		}
+		f, ok := pathNumber(v)
+		if !ok {
+			return 0, FilterError(fmt.Errorf("Slice bounds must be numbers, not %s", filterDescribe(v)))
+		}
+		f = math.Floor(f)
+		if f < 0 {
+			f += float64(n)
+		}
+		return int(math.Max(0, math.Min(f, float64(n)))), nil
+	}
+	i, e := bound(from, 0)
+	if e != nil {
+		return 0, 0, e
+	}
+	j, e := bound(to, n)
+	if e != nil {
+		return 0, 0, e
+	}
+	if j < i {
+		j = i
+	}
+	return i, j, nil
+}
+
+func filterSlice(t, from, to JsonValue) (JsonValue, error) {
+	if s, ok := filterString(t); ok {
+		runes := []rune(s)
+		i, j, e := filterBounds(from, to, len(runes))
+		if e != nil {
+			return nil, e
+		}
+		return NewJsonString(string(runes[i:j])), nil
+	}
+	if isNullValue(t) {
+		return nil, nil
+	}
+	if x, ok := t.(*JsonArray); ok {
+		i, j, e := filterBounds(from, to, len(*x))
+		if e != nil {
+			return nil, e
+		}
+		r := append(JsonArray{}, (*x)[i:j]...)
+		return &r, nil
+	}
+	return nil, FilterError(fmt.Errorf("Cannot slice %s", filterDescribe(t)))
+}
+
+// the order of jq (that of Compare()), but the numbers go by their values only
+func filterCompare(a, b JsonValue) int {
+	ra, rb := compareRank(a), compareRank(b)
+	if ra != rb || ra != 2 && ra != 4 && ra != 5 {
+		return Compare(a, b)
+	}
+	switch x := a.(type) {
+	case *JsonArray:
+		y := b.(*JsonArray)
+		for i := 0; i < len(*x) && i < len(*y); i++ {
+			if r := filterCompare((*x)[i], (*y)[i]); r != 0 {
+				return r
+			}
+		}
+		return compareInts(len(*x), len(*y))
+	case *JsonObject:
+		y := b.(*JsonObject)
+		xk, yk := sortedKeys(*x), sortedKeys(*y)
+		for i := 0; i < len(xk) && i < len(yk); i++ {
+			if r := strings.Compare(xk[i], yk[i]); r != 0 {
+				return r
+			}
+		}
+		if r := compareInts(len(xk), len(yk)); r != 0 {
+			return r
+		}
+		for _, k := range xk {
+			if r := filterCompare((*x)[k], (*y)[k]); r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+	ai, aInt := a.(*JsonInt)
+	bi, bInt := b.(*JsonInt)
+	if aInt && bInt {
+		return compareInts(int(*ai), int(*bi))
+	}
+	f, _ := pathNumber(a)
+	g, _ := pathNumber(b)
+	switch {
+	case f < g:
+		return -1
+	case f > g:
+		return 1
+	}
+	return 0
+}
+
+func filterBinary(op string, a, b JsonValue) (JsonValue, error) {
+	switch op {
+	case "==":
+		return NewJsonBool(filterCompare(a, b) == 0), nil
+	case "!=":
+		return NewJsonBool(filterCompare(a, b) != 0), nil
+	case "<":
+		return NewJsonBool(filterCompare(a, b) < 0), nil
+	case "<=":
+		return NewJsonBool(filterCompare(a, b) <= 0), nil
+	case ">":
+		return NewJsonBool(filterCompare(a, b) > 0), nil
+	case ">=":
+		return NewJsonBool(filterCompare(a, b) >= 0), nil
+	}
+	_, aNum := pathNumber(a)
+	_, bNum := pathNumber(b)
+	if aNum && bNum {
+		return filterArithmetic(op, a, b)
+	}
+	as, aStr := filterString(a)
+	bs, bStr := filterString(b)
+	switch op {
+	case "+":
+		switch {
+		case isNullValue(a) && !aStr:
+			return b, nil
+		case isNullValue(b) && !bStr:
+			return a, nil
+		case aStr && bStr:
+			return NewJsonString(as + bs), nil
+		}
+		switch x := a.(type) {
+		case *JsonArray:
+			if y, ok := b.(*JsonArray); ok {
+				r := append(append(JsonArray{}, *x...), *y...)
+				return &r, nil
+			}
+		case *JsonObject:
+			if y, ok := b.(*JsonObject); ok {
+				r := JsonObject{}
+				for k, v := range *x {
+					r[k] = v
+				}
+				for k, v := range *y {
+					r[k] = v
+				}
+				return &r, nil
+			}
+		}
+	case "-":
+		x, xok := a.(*JsonArray)
+		y, yok := b.(*JsonArray)
+		if xok && yok && !isNullValue(a) && !isNullValue(b) {
+			r := JsonArray{}
+			for _, v := range *x {
+				keep := true
+				for _, w := range *y {
+					if filterCompare(v, w) == 0 {
+						keep = false
+						break
+					}
+				}
+				if keep {
+					r = append(r, v)
+				}
+			}
+			return &r, nil
+		}
+	case "*":
+		x, xok := a.(*JsonObject)
+		y, yok := b.(*JsonObject)
+		if xok && yok && !isNullValue(a) && !isNullValue(b) {
+			return filterDeepMerge(x, y), nil
+		}
+		if n, ok := filterInt(b); ok && aStr {
+			if n <= 0 {
+				return nil, nil
+			}
+			return NewJsonString(strings.Repeat(as, n)), nil
+		}
+	case "/":
+		if aStr && bStr {
+			return filterSplit(as, bs), nil
+		}
+	}
+	verbs := map[string]string{"+": "added to", "-": "subtracted from", "*": "multiplied by", "/": "divided by", "%": "divided by"}
+	return nil, FilterError(fmt.Errorf("%s cannot be %s %s", filterDescribe(a), verbs[op], filterDescribe(b)))
+}
+
+// the int result when both are ints and it fits, a float one otherwise
+func filterArithmetic(op string, a, b JsonValue) (JsonValue, error) {
+	ai, aInt := a.(*JsonInt)
+	bi, bInt := b.(*JsonInt)
+	if aInt && bInt {
+		x, y := int(*ai), int(*bi)
+		switch op {
+		case "+":
+			if r := x + y; (r > x) == (y > 0) {
+				return NewJsonInt(r), nil
+			}
+		case "-":
+			if r := x - y; (r < x) == (y > 0) {
+				return NewJsonInt(r), nil
+			}
+		case "*":
+			if x == 0 || y == 0 {
+				return NewJsonInt(0), nil
+			}
+			if r := x * y; r/y == x && !(x == -1 && y == minInt) && !(y == -1 && x == minInt) {
+				return NewJsonInt(r), nil
+			}
+		case "/":
+			if y != 0 && x%y == 0 && !(x == minInt && y == -1) {
+				return NewJsonInt(x / y), nil
+			}
+		}
+	}
+	f, _ := pathNumber(a)
+	g, _ := pathNumber(b)
+	var r float64
+	switch op {
+	case "+":
+		r = f + g
+	case "-":
+		r = f - g
+	case "*":
+		r = f * g
+	case "/":
+		if g == 0 {
+			return nil, FilterError(fmt.Errorf("%s cannot be divided by zero", filterDescribe(a)))
+		}
+		r = f / g
+	case "%":
+		x, xok := filterInt(a)
+		y, yok := filterInt(b)
+		if !xok || !yok {
+			return nil, FilterError(fmt.Errorf("%s %% %s overflows", filterDescribe(a), filterDescribe(b)))
+		}
+		if y == 0 {
+			return nil, FilterError(fmt.Errorf("%s cannot be divided by zero", filterDescribe(a)))
+		}
+		if y == -1 {
+			return NewJsonInt(0), nil
+		}
+		return NewJsonInt(x % y), nil
+	}
+	if math.IsInf(r, 0) || math.IsNaN(r) {
+		return nil, FilterError(fmt.Errorf("%s %s %s overflows", filterDescribe(a), op, filterDescribe(b)))
+	}
+	return NewJsonFloat(r), nil
+}
+
+// the objects merged recursively, b wins
+func filterDeepMerge(a, b *JsonObject) *JsonObject {
+	r := JsonObject{}
+	for k, v := range *a {
+		r[k] = v
+	}
+	for k, v := range *b {
+		x, xok := r[k].(*JsonObject)
+		y, yok := v.(*JsonObject)
+		if xok && yok && !isNullValue(x) && !isNullValue(y) {
+			r[k] = filterDeepMerge(x, y)
+		} else {
+			r[k] = v
+		}
+	}
+	return &r
+}
+
+func filterSplit(s, sep string) *JsonArray {
+	r := JsonArray{}
+	if s == "" {
+		return &r
+	}
+	for _, x := range strings.Split(s, sep) {
+		r = append(r, NewJsonString(x))
+	}
+	return &r
+}
+
+// whether b is in a the way jq's contains() means it
+func filterContains(a, b JsonValue) bool {
+	if as, ok := filterString(a); ok {
+		bs, ok := filterString(b)
+		return ok && strings.Contains(as, bs)
+	}
+	if filterType(a) != filterType(b) {
+		return false
+	}
+	switch x := a.(type) {
+	case *JsonArray:
+		for _, w := range *b.(*JsonArray) {
+			found := false
+			for _, v := range *x {
+				if filterContains(v, w) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	case *JsonObject:
+		for k, w := range *b.(*JsonObject) {
+			v, exists := (*x)[k]
+			if !exists || !filterContains(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return filterCompare(a, b) == 0
+}
+
+/*----------------------------------------------------------------------------*/
+
+type filterBuiltin func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error
+
+// a function of the input only
+func filterFunc(fn func(in JsonValue) (JsonValue, error)) filterBuiltin {
+	return func(in JsonValue, _ []filterNode, _ *filterEnv, out filterEmit) error {
+		v, e := fn(in)
+		if e != nil {
+			return e
+		}
+		return out(v)
+	}
+}
+
+// a function of the input and an argument, once for every argument output
+func filterFunc1(fn func(in, arg JsonValue) (JsonValue, error)) filterBuiltin {
+	return func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		return args[0].eval(in, env, func(a JsonValue) error {
+			v, e := fn(in, a)
+			if e != nil {
+				return e
+			}
+			return out(v)
+		})
+	}
+}
+
+// a function of a string input and a string argument
+func filterStrings(name string, fn func(s, arg string) JsonValue) filterBuiltin {
+	return filterFunc1(func(in, arg JsonValue) (JsonValue, error) {
+		s, ok := filterString(in)
+		a, aok := filterString(arg)
+		if !ok || !aok {
+			return nil, FilterError(fmt.Errorf("%s() needs strings, not %s and %s", name, filterDescribe(in), filterDescribe(arg)))
+		}
+		return fn(s, a), nil
+	})
+}
+
+// a function of a number input
+func filterMath(name string, fn func(float64) float64) filterBuiltin {
+	return filterFunc(func(in JsonValue) (JsonValue, error) {
+		f, ok := pathNumber(in)
+		if !ok {
+			return nil, FilterError(fmt.Errorf("%s() needs a number, not %s", name, filterDescribe(in)))
+		}
+		r := fn(f)
+		if math.IsNaN(r) || math.IsInf(r, 0) {
+			return nil, FilterError(fmt.Errorf("%s(%v) is not a number", name, f))
+		}
+		if i, e := floatToInt(r); e == nil && name != "sqrt" {
+			return NewJsonInt(i), nil
+		}
+		return NewJsonFloat(r), nil
+	})
+}
+
+// the input must be an array (or an object, if objects is set)
+func filterElements(name string, in JsonValue, objects bool) ([]JsonValue, error) {
+	switch in.(type) {
+	case *JsonArray:
+		return filterChildren(in), nil
+	case *JsonObject:
+		if objects {
+			return filterChildren(in), nil
+		}
+	}
+	if isNullValue(in) && objects {
+		return nil, nil
+	}
+	return nil, FilterError(fmt.Errorf("%s() needs an array, not %s", name, filterDescribe(in)))
+}
+
+// the elements with the arrays of their f outputs, stably sorted by those
+func filterSortBy(name string, in JsonValue, f filterNode, env *filterEnv) ([]JsonValue, []JsonValue, error) {
+	elements, e := filterElements(name, in, false)
+	if e != nil {
+		return nil, nil, e
+	}
+	keys := make([]JsonValue, len(elements))
+	for i, x := range elements {
+		k, e := filterAll(f, x, env)
+		if e != nil {
+			return nil, nil, e
+		}
+		a := JsonArray(k)
+		keys[i] = &a
+	}
+	order := make([]int, len(elements))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return filterCompare(keys[order[i]], keys[order[j]]) < 0 })
+	sorted, sortedKeys := make([]JsonValue, len(order)), make([]JsonValue, len(order))
+	for i, o := range order {
+		sorted[i], sortedKeys[i] = elements[o], keys[o]
+	}
+	return sorted, sortedKeys, nil
+}
+
+func filterArray(v []JsonValue) *JsonArray {
+	r := append(JsonArray{}, v...)
+	return &r
+}
+
+// the sorted groups of the elements with equal f outputs; the first one of
+// every group if first
+func filterGroups(name string, first bool) filterBuiltin {
+	return func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		sorted, keys, e := filterSortBy(name, in, args[0], env)
+		if e != nil {
+			return e
+		}
+		r := JsonArray{}
+		for i := 0; i < len(sorted); {
+			j := i + 1
+			for j < len(sorted) && filterCompare(keys[i], keys[j]) == 0 {
+				j++
+			}
+			if first {
+				r = append(r, sorted[i])
+			} else {
+				r = append(r, filterArray(sorted[i:j]))
+			}
+			i = j
+		}
+		return out(&r)
+	}
+}
+
+// the element with the least (or the greatest, if max) f output, null if none
+func filterExtreme(name string, max bool) filterBuiltin {
+	return func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		sorted, _, e := filterSortBy(name, in, args[0], env)
+		switch {
+		case e != nil:
+			return e
+		case len(sorted) == 0:
+			return out(nil)
+		case max:
+			return out(sorted[len(sorted)-1])
+		}
+		return out(sorted[0])
+	}
+}
+
+// whether f has an output that is true (or false, if not want); for any() and all()
+func filterFind(in JsonValue, f filterNode, env *filterEnv, want bool) (bool, error) {
+	stop := errors.New("stop")
+	found := false
+	e := f.eval(in, env, func(v JsonValue) error {
+		if filterTruthy(v) == want {
+			found = true
+			return stop
+		}
+		return nil
+	})
+	if e != nil && e != stop {
+		return false, e
+	}
+	return found, nil
+}
+
+// the first n outputs of f
+func filterLimit(n int, in JsonValue, f filterNode, env *filterEnv, out filterEmit) error {
+	if n <= 0 {
+		return nil
+	}
+	stop := errors.New("limit")
+	count := 0
+	e := f.eval(in, env, func(v JsonValue) error {
+		if e := out(v); e != nil {
+			return e
+		}
+		if count++; count >= n {
+			return stop
+		}
+		return nil
+	})
+	if e == stop {
+		return nil
+	}
+	return e
+}
+
+func filterRecurse(in JsonValue, f filterNode, env *filterEnv, out filterEmit) error {
+	if e := out(in); e != nil {
+		return e
+	}
+	return f.eval(in, env, func(v JsonValue) error { return filterRecurse(v, f, env, out) })
+}
+
+func filterRange(from, to JsonValue, out filterEmit) error {
+	i, iok := from.(*JsonInt)
+	j, jok := to.(*JsonInt)
+	if iok && jok && i != nil && j != nil {
+		for k := int(*i); k < int(*j); k++ {
+			if e := out(NewJsonInt(k)); e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+	f, fok := pathNumber(from)
+	g, gok := pathNumber(to)
+	if !fok || !gok {
+		return FilterError(fmt.Errorf("range() needs numbers, not %s and %s", filterDescribe(from), filterDescribe(to)))
+	}
+	for ; f < g; f++ {
+		if e := out(NewJsonFloat(f)); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+func filterFlatten(in JsonValue, depth float64) (JsonValue, error) {
+	if depth < 0 {
+		return nil, FilterError(fmt.Errorf("flatten() depth must not be negative"))
+	}
+	elements, e := filterElements("flatten", in, false)
+	if e != nil {
+		return nil, e
+	}
+	r := JsonArray{}
+	for _, x := range elements {
+		if a, ok := x.(*JsonArray); ok && depth > 0 {
+			f, _ := filterFlatten(a, depth-1)
+			r = append(r, *f.(*JsonArray)...)
+		} else {
+			r = append(r, x)
+		}
+	}
+	return &r, nil
+}
+
+func filterLength(in JsonValue) (JsonValue, error) {
+	if s, ok := filterString(in); ok {
+		return NewJsonInt(utf8.RuneCountInString(s)), nil
+	}
+	if isNullValue(in) {
+		return NewJsonInt(0), nil
+	}
+	switch x := in.(type) {
+	case *JsonInt:
+		if *x < 0 && int(*x) != minInt {
+			return NewJsonInt(-int(*x)), nil
+		}
+		return x, nil
+	case *JsonFloat:
+		return NewJsonFloat(math.Abs(float64(*x))), nil
+	case *JsonArray:
+		return NewJsonInt(len(*x)), nil
+	case *JsonObject:
+		return NewJsonInt(len(*x)), nil
+	}
+	return nil, FilterError(fmt.Errorf("%s has no length", filterDescribe(in)))
+}
+
+func filterKeys(in JsonValue) (JsonValue, error) {
+	r := JsonArray{}
+	switch x := in.(type) {
+	case *JsonObject:
+		if !isNullValue(in) {
+			for _, k := range sortedKeys(*x) {
+				r = append(r, NewJsonString(k))
+			}
+			return &r, nil
+		}
+	case *JsonArray:
+		if !isNullValue(in) {
+			for i := range *x {
+				r = append(r, NewJsonInt(i))
+			}
+			return &r, nil
+		}
+	}
+	return nil, FilterError(fmt.Errorf("%s has no keys", filterDescribe(in)))
+}
+
+func filterHas(in, k JsonValue) (JsonValue, error) {
+	switch x := in.(type) {
+	case *JsonObject:
+		if s, ok := filterString(k); ok && !isNullValue(in) {
+			_, exists := (*x)[s]
+			return NewJsonBool(exists), nil
+		}
+	case *JsonArray:
+		if i, ok := filterInt(k); ok && !isNullValue(in) {
+			return NewJsonBool(i >= 0 && i < len(*x)), nil
+		}
+	}
+	return nil, FilterError(fmt.Errorf("Cannot check whether %s has %s", filterType(in), filterDescribe(k)))
+}
+
+func filterAdd(in JsonValue) (JsonValue, error) {
+	elements, e := filterElements("add", in, true)
+	if e != nil {
+		return nil, e
+	}
+	var r JsonValue
+	for _, x := range elements {
+		if r, e = filterBinary("+", r, x); e != nil {
+			return nil, e
+		}
+	}
+	return r, nil
+}
+
+func filterToNumber(in JsonValue) (JsonValue, error) {
+	if _, ok := pathNumber(in); ok {
+		return in, nil
+	}
+	if s, ok := filterString(in); ok {
+		if i, e := strconv.ParseInt(s, 10, 0); e == nil {
+			return NewJsonInt(int(i)), nil
+		}
+		if f, e := parseFloat(s); e == nil {
+			return NewJsonFloat(f), nil
+		}
+	}
+	return nil, FilterError(fmt.Errorf("Cannot parse %s as a number", filterDescribe(in)))
+}
+
+func filterToEntries(in JsonValue) (JsonValue, error) {
+	o, ok := in.(*JsonObject)
+	if !ok || isNullValue(in) {
+		return nil, FilterError(fmt.Errorf("to_entries() needs an object, not %s", filterDescribe(in)))
+	}
+	r := JsonArray{}
+	for _, k := range sortedKeys(*o) {
+		r = append(r, &JsonObject{"key": NewJsonString(k), "value": (*o)[k]})
+	}
+	return &r, nil
+}
+
+func filterFromEntries(in JsonValue) (JsonValue, error) {
+	elements, e := filterElements("from_entries", in, false)
+	if e != nil {
+		return nil, e
+	}
+	r := JsonObject{}
+	for _, x := range elements {
+		entry, ok := x.(*JsonObject)
+		if !ok || isNullValue(x) {
+			return nil, FilterError(fmt.Errorf("from_entries() needs objects, not %s", filterDescribe(x)))
+		}
+		var key, value JsonValue
+		for _, name := range []string{"key", "k", "name", "Name", "Key", "K"} {
+			if v := (*entry)[name]; filterTruthy(v) {
+				key = v
+				break
+			}
+		}
+		for _, name := range []string{"value", "v", "Value", "V"} {
+			if v, exists := (*entry)[name]; exists {
+				value = v
+				break
+			}
+		}
+		s, ok := filterString(key)
+		switch key.(type) {
+		case *JsonInt, *JsonFloat, *JsonBool:
+			s, e = filterText(key)
+			ok = e == nil
+		}
+		if !ok {
+			return nil, FilterError(fmt.Errorf("Cannot use %s as a key", filterDescribe(key)))
+		}
+		r[s] = value
+	}
+	return &r, nil
+}
+
+func filterJoin(in, sep JsonValue) (JsonValue, error) {
+	elements, e := filterElements("join", in, false)
+	if e != nil {
+		return nil, e
+	}
+	s, ok := filterString(sep)
+	if !ok {
+		return nil, FilterError(fmt.Errorf("join() needs a string, not %s", filterDescribe(sep)))
+	}
+	parts := make([]string, len(elements))
+	for i, x := range elements {
+		if parts[i], ok = filterString(x); ok || isNullValue(x) {
+			continue
+		}
+		switch x.(type) {
+		case *JsonInt, *JsonFloat, *JsonBool:
+			parts[i], _ = filterText(x)
+		default:
+			return nil, FilterError(fmt.Errorf("Cannot join %s", filterDescribe(x)))
+		}
+	}
+	return NewJsonString(strings.Join(parts, s)), nil
+}
+
+func filterTest(in, re JsonValue) (JsonValue, error) {
+	s, ok := filterString(in)
+	p, pok := filterString(re)
+	if !ok || !pok {
+		return nil, FilterError(fmt.Errorf("test() needs strings, not %s and %s", filterDescribe(in), filterDescribe(re)))
+	}
+	r, e := regexp.Compile(p)
+	if e != nil {
+		return nil, FilterError(fmt.Errorf("test(%+q): %v", p, e))
+	}
+	return NewJsonBool(r.MatchString(s)), nil
+}
+
+func filterRaise(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+	if len(args) == 0 {
+		return FilterError(&filterRaised{in})
+	}
+	return args[0].eval(in, env, func(v JsonValue) error { return FilterError(&filterRaised{v}) })
+}
+
+func filterAsciiCase(upper bool) filterBuiltin {
+	return filterFunc(func(in JsonValue) (JsonValue, error) {
+		s, ok := filterString(in)
+		if !ok {
+			return nil, FilterError(fmt.Errorf("Cannot change the case of %s", filterDescribe(in)))
+		}
+		return NewJsonString(strings.Map(func(c rune) rune {
+			switch {
+			case upper && c >= 'a' && c <= 'z':
+				return c - 'a' + 'A'
+			case !upper && c >= 'A' && c <= 'Z':
+				return c - 'A' + 'a'
+			}
+			return c
+		}, s)), nil
+	})
+}
+
+// select() by the type
+func filterTypes(types ...string) filterBuiltin {
+	return func(in JsonValue, _ []filterNode, _ *filterEnv, out filterEmit) error {
+		t := filterType(in)
+		for _, x := range types {
+			if x == t {
+				return out(in)
+			}
+		}
+		return nil
+	}
+}
+
+// the functions by name/arity
+var filterBuiltins = map[string]filterBuiltin{
+	"empty/0": func(JsonValue, []filterNode, *filterEnv, filterEmit) error { return nil },
+	"not/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		return NewJsonBool(!filterTruthy(in)), nil
+	}),
+	"length/0":        filterFunc(filterLength),
+	"keys/0":          filterFunc(filterKeys),
+	"keys_unsorted/0": filterFunc(filterKeys),
+	"has/1":           filterFunc1(filterHas),
+	"add/0":           filterFunc(filterAdd),
+	"type/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		return NewJsonString(filterType(in)), nil
+	}),
+	"values/0": func(in JsonValue, _ []filterNode, _ *filterEnv, out filterEmit) error {
+		if isNullValue(in) {
+			return nil
+		}
+		return out(in)
+	},
+	"nulls/0":     filterTypes("null"),
+	"booleans/0":  filterTypes("boolean"),
+	"numbers/0":   filterTypes("number"),
+	"strings/0":   filterTypes("string"),
+	"arrays/0":    filterTypes("array"),
+	"objects/0":   filterTypes("object"),
+	"iterables/0": filterTypes("array", "object"),
+	"scalars/0":   filterTypes("null", "boolean", "number", "string"),
+	"select/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		return args[0].eval(in, env, func(v JsonValue) error {
+			if filterTruthy(v) {
+				return out(in)
+			}
+			return nil
+		})
+	},
+	"map/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		elements, e := filterElements("map", in, true)
+		if e != nil {
+			return e
+		}
+		r := JsonArray{}
+		for _, x := range elements {
+			v, e := filterAll(args[0], x, env)
+			if e != nil {
+				return e
+			}
+			r = append(r, v...)
+		}
+		return out(&r)
+	},
+	"map_values/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		first := func(x JsonValue) (JsonValue, bool, error) {
+			var r JsonValue
+			found := false
+			e := filterLimit(1, x, args[0], env, func(v JsonValue) error {
+				r, found = v, true
+				return nil
+			})
+			return r, found, e
+		}
+		switch x := in.(type) {
+		case *JsonObject:
+			r := JsonObject{}
+			for k, v := range *x {
+				v, found, e := first(v)
+				if e != nil {
+					return e
+				}
+				if found {
+					r[k] = v
+				}
+			}
+			return out(&r)
+		case *JsonArray:
+			r := JsonArray{}
+			for _, v := range *x {
+				v, found, e := first(v)
+				if e != nil {
+					return e
+				}
+				if found {
+					r = append(r, v)
+				}
+			}
+			return out(&r)
+		}
+		return FilterError(fmt.Errorf("map_values() needs an array or an object, not %s", filterDescribe(in)))
+	},
+	"to_entries/0":   filterFunc(filterToEntries),
+	"from_entries/0": filterFunc(filterFromEntries),
+	"with_entries/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		entries, e := filterToEntries(in)
+		if e != nil {
+			return e
+		}
+		r := JsonArray{}
+		for _, x := range *entries.(*JsonArray) {
+			v, e := filterAll(args[0], x, env)
+			if e != nil {
+				return e
+			}
+			r = append(r, v...)
+		}
+		o, e := filterFromEntries(&r)
+		if e != nil {
+			return e
+		}
+		return out(o)
+	},
+	"sort/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		elements, e := filterElements("sort", in, false)
+		if e != nil {
+			return nil, e
+		}
+		r := filterArray(elements)
+		sort.SliceStable(*r, func(i, j int) bool { return filterCompare((*r)[i], (*r)[j]) < 0 })
+		return r, nil
+	}),
+	"sort_by/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		sorted, _, e := filterSortBy("sort_by", in, args[0], env)
+		if e != nil {
+			return e
+		}
+		return out(filterArray(sorted))
+	},
+	"group_by/1":  filterGroups("group_by", false),
+	"unique_by/1": filterGroups("unique_by", true),
+	"unique/0": func(in JsonValue, _ []filterNode, env *filterEnv, out filterEmit) error {
+		return filterGroups("unique", true)(in, []filterNode{identityNode{}}, env, out)
+	},
+	"min_by/1": filterExtreme("min_by", false),
+	"max_by/1": filterExtreme("max_by", true),
+	"min/0": func(in JsonValue, _ []filterNode, env *filterEnv, out filterEmit) error {
+		return filterExtreme("min", false)(in, []filterNode{identityNode{}}, env, out)
+	},
+	"max/0": func(in JsonValue, _ []filterNode, env *filterEnv, out filterEmit) error {
+		return filterExtreme("max", true)(in, []filterNode{identityNode{}}, env, out)
+	},
+	"reverse/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		if s, ok := filterString(in); ok {
+			runes := []rune(s)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return NewJsonString(string(runes)), nil
+		}
+		if isNullValue(in) {
+			return &JsonArray{}, nil
+		}
+		elements, e := filterElements("reverse", in, false)
+		if e != nil {
+			return nil, e
+		}
+		r := make(JsonArray, len(elements))
+		for i, x := range elements {
+			r[len(r)-1-i] = x
+		}
+		return &r, nil
+	}),
+	"first/0": filterFunc(func(in JsonValue) (JsonValue, error) { return filterIndex(in, NewJsonInt(0)) }),
+	"last/0":  filterFunc(func(in JsonValue) (JsonValue, error) { return filterIndex(in, NewJsonInt(-1)) }),
+	"first/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		return filterLimit(1, in, args[0], env, out)
+	},
+	"last/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		v, e := filterAll(args[0], in, env)
+		if e != nil || len(v) == 0 {
+			return e
+		}
+		return out(v[len(v)-1])
+	},
+	"limit/2": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		return args[0].eval(in, env, func(n JsonValue) error {
+			i, ok := filterInt(n)
+			if !ok {
+				return FilterError(fmt.Errorf("limit() needs a number, not %s", filterDescribe(n)))
+			}
+			return filterLimit(i, in, args[1], env, out)
+		})
+	},
+	"range/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		return args[0].eval(in, env, func(n JsonValue) error { return filterRange(NewJsonInt(0), n, out) })
+	},
+	"range/2": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		return filterEach(args, in, env, make([]JsonValue, 0, 2), func(v []JsonValue) error {
+			return filterRange(v[0], v[1], out)
+		})
+	},
+	"any/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		elements, e := filterElements("any", in, false)
+		if e != nil {
+			return nil, e
+		}
+		for _, x := range elements {
+			if filterTruthy(x) {
+				return NewJsonBool(true), nil
+			}
+		}
+		return NewJsonBool(false), nil
+	}),
+	"all/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		elements, e := filterElements("all", in, false)
+		if e != nil {
+			return nil, e
+		}
+		for _, x := range elements {
+			if !filterTruthy(x) {
+				return NewJsonBool(false), nil
+			}
+		}
+		return NewJsonBool(true), nil
+	}),
+	"any/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		found, e := filterFind(in, pipeNode{iterateNode{identityNode{}}, args[0]}, env, true)
+		if e != nil {
+			return e
+		}
+		return out(NewJsonBool(found))
+	},
+	"all/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		found, e := filterFind(in, pipeNode{iterateNode{identityNode{}}, args[0]}, env, false)
+		if e != nil {
+			return e
+		}
+		return out(NewJsonBool(!found))
+	},
+	"flatten/0": filterFunc(func(in JsonValue) (JsonValue, error) { return filterFlatten(in, math.Inf(1)) }),
+	"flatten/1": filterFunc1(func(in, depth JsonValue) (JsonValue, error) {
+		d, ok := pathNumber(depth)
+		if !ok {
+			return nil, FilterError(fmt.Errorf("flatten() needs a number, not %s", filterDescribe(depth)))
+		}
+		return filterFlatten(in, d)
+	}),
+	"recurse/0": func(in JsonValue, _ []filterNode, env *filterEnv, out filterEmit) error {
+		return recurseNode{}.eval(in, env, out)
+	},
+	"recurse/1": func(in JsonValue, args []filterNode, env *filterEnv, out filterEmit) error {
+		return filterRecurse(in, args[0], env, out)
+	},
+	"contains/1": filterFunc1(func(in, x JsonValue) (JsonValue, error) {
+		if filterType(in) != filterType(x) {
+			return nil, FilterError(fmt.Errorf("%s cannot contain %s", filterDescribe(in), filterDescribe(x)))
+		}
+		return NewJsonBool(filterContains(in, x)), nil
+	}),
+	"tostring/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		s, e := filterText(in)
+		if e != nil {
+			return nil, e
+		}
+		return NewJsonString(s), nil
+	}),
+	"tonumber/0": filterFunc(filterToNumber),
+	"tojson/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		s, e := Format(in, "")
+		if e != nil {
+			return nil, e
+		}
+		return NewJsonString(s), nil
+	}),
+	"fromjson/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		s, ok := filterString(in)
+		if !ok {
+			return nil, FilterError(fmt.Errorf("fromjson() needs a string, not %s", filterDescribe(in)))
+		}
+		return Parse(s)
+	}),
+	"ascii_downcase/0": filterAsciiCase(false),
+	"ascii_upcase/0":   filterAsciiCase(true),
+	"ltrimstr/1": filterFunc1(func(in, x JsonValue) (JsonValue, error) {
+		s, ok := filterString(in)
+		p, pok := filterString(x)
+		if ok && pok && strings.HasPrefix(s, p) {
+			return NewJsonString(s[len(p):]), nil
+		}
+		return in, nil
+	}),
+	"rtrimstr/1": filterFunc1(func(in, x JsonValue) (JsonValue, error) {
+		s, ok := filterString(in)
+		p, pok := filterString(x)
+		if ok && pok && strings.HasSuffix(s, p) {
+			return NewJsonString(s[:len(s)-len(p)]), nil
+		}
+		return in, nil
+	}),
+	"trim/0": filterFunc(func(in JsonValue) (JsonValue, error) {
+		s, ok := filterString(in)
+		if !ok {
+			return nil, FilterError(fmt.Errorf("trim() needs a string, not %s", filterDescribe(in)))
+		}
+		return NewJsonString(strings.TrimSpace(s)), nil
+	}),
+	"startswith/1": filterStrings("startswith", func(s, p string) JsonValue { return NewJsonBool(strings.HasPrefix(s, p)) }),
+	"endswith/1":   filterStrings("endswith", func(s, p string) JsonValue { return NewJsonBool(strings.HasSuffix(s, p)) }),
+	"split/1":      filterStrings("split", func(s, sep string) JsonValue { return filterSplit(s, sep) }),
+	"join/1":       filterFunc1(filterJoin),
+	"test/1":       filterFunc1(filterTest),
+	"floor/0":      filterMath("floor", math.Floor),
+	"ceil/0":       filterMath("ceil", math.Ceil),
+	"round/0":      filterMath("round", math.Round),
+	"sqrt/0":       filterMath("sqrt", math.Sqrt),
+	"error/0":      filterRaise,
+	"error/1":      filterRaise,
+}
+
+// the @formats
+var filterFormats = map[string]func(JsonValue) (string, error){
+	"text": filterText,
+	"json": func(v JsonValue) (string, error) { return Format(v, "") },
+	"csv": func(v JsonValue) (string, error) {
+		return filterRow(v, ",", func(s string) string { return `"` + strings.ReplaceAll(s, `"`, `""`) + `"` })
+	},
+	"tsv": func(v JsonValue) (string, error) {
+		return filterRow(v, "\t", strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`).Replace)
+	},
+	"base64": func(v JsonValue) (string, error) {
+		s, e := filterText(v)
+		return base64.StdEncoding.EncodeToString([]byte(s)), e
+	},
+}
+
+// an array of scalars as a line of @csv or @tsv
+func filterRow(v JsonValue, sep string, quote func(string) string) (string, error) {
+	elements, e := filterElements("@csv/@tsv", v, false)
+	if e != nil {
+		return "", e
+	}
+	r := make([]string, len(elements))
+	for i, x := range elements {
+		if s, ok := filterString(x); ok {
+			r[i] = quote(s)
+			continue
+		}
+		switch x.(type) {
+		case *JsonInt, *JsonFloat, *JsonBool:
+			r[i], _ = filterText(x)
+		default:
+			if !isNullValue(x) {
+				return "", FilterError(fmt.Errorf("%s is not valid in a row", filterDescribe(x)))
+			}
+		}
+	}
+	return strings.Join(r, sep), nil
+}
+
+/*----------------------------------------------------------------------------*/
+
+type filterParser struct {
+	pathParser
+	scope []string // the variables bound, innermost last
+}
+
+var filterKeywords = map[string]bool{
+	"and": true, "or": true, "if": true, "then": true, "elif": true, "else": true, "end": true,
+	"as": true, "reduce": true, "foreach": true, "try": true, "catch": true, "def": true,
+	"label": true, "import": true, "include": true, "__loc__": true,
+}
+
+func (self *filterParser) error(format string, args ...interface{}) error {
+	return FilterError(fmt.Errorf("Filter %+q at %d: %s", self.s, self.pos, fmt.Sprintf(format, args...)))
+}
+
+// skips the spaces and the # comments
+func (self *filterParser) ws() {
+	for {
+		self.pathParser.ws()
+		if self.peek() != '#' {
+			return
+		}
+		for self.pos < len(self.s) && self.s[self.pos] != '\n' {
+			self.pos++
+		}
+	}
+}
+
+func isNameNext(c byte) bool { return isNameFirst(c) || isDigit(c) }
+
+// the identifier at the position, not consumed
+func (self *filterParser) word() string {
+	if !isNameFirst(self.peek()) {
+		return ""
+	}
+	i := self.pos + 1
+	for i < len(self.s) && isNameNext(self.s[i]) {
+		i++
+	}
+	return self.s[self.pos:i]
+}
+
+func (self *filterParser) eatKeyword(k string) bool {
+	self.ws()
+	if self.word() == k {
+		self.pos += len(k)
+		return true
+	}
+	return false
+}
+
+func (self *filterParser) expectKeyword(k string) error {
+	if !self.eatKeyword(k) {
+		return self.error("Expected %+q", k)
+	}
+	return nil
+}
+
+func (self *filterParser) expect(c byte) error {
+	self.ws()
+	if !self.eat(c) {
+		return self.error("Expected '%c'", c)
+	}
+	return nil
+}
+
+// $name, the '$' at the position
+func (self *filterParser) variable() (string, error) {
+	self.pos++
+	name := self.word()
+	if name == "" {
+		return "", self.error("Expected a variable name")
+	}
+	self.pos += len(name)
+	return name, nil
+}
+
+// runs fn with the variable bound
+func (self *filterParser) bound(name string, fn func() (filterNode, error)) (filterNode, error) {
+	self.scope = append(self.scope, name)
+	defer func() { self.scope = self.scope[:len(self.scope)-1] }()
+	return fn()
+}
+
+func (self *filterParser) pipe() (filterNode, error) {
+	left, e := self.comma()
+	if e != nil {
+		return nil, e
+	}
+	self.ws()
+	if !self.eat('|') {
+		return left, nil
+	}
+	self.ws()
+	right, e := self.pipe()
+	if e != nil {
+		return nil, e
+	}
+	return pipeNode{left, right}, nil
+}
+
+func (self *filterParser) comma() (filterNode, error) {
+	left, e := self.alternative()
+	for e == nil {
+		self.ws()
+		if !self.eat(',') {
+			return left, nil
+		}
+		self.ws()
+		var right filterNode
+		if right, e = self.alternative(); e == nil {
+			left = commaNode{left, right}
+		}
+	}
+	return nil, e
+}
+
+func (self *filterParser) alternative() (filterNode, error) {
+	left, e := self.or()
+	if e != nil {
+		return nil, e
+	}
+	self.ws()
+	if !self.eatString("//") {
+		return left, nil
+	}
+	self.ws()
+	right, e := self.alternative()
+	if e != nil {
+		return nil, e
+	}
+	return altNode{left, right}, nil
+}
+
+func (self *filterParser) or() (filterNode, error) {
+	left, e := self.and()
+	for e == nil {
+		if !self.eatKeyword("or") {
+			return left, nil
+		}
+		self.ws()
+		var right filterNode
+		if right, e = self.and(); e == nil {
+			left = orNode{left, right}
+		}
+	}
+	return nil, e
+}
+
+func (self *filterParser) and() (filterNode, error) {
+	left, e := self.comparison()
+	for e == nil {
+		if !self.eatKeyword("and") {
+			return left, nil
+		}
+		self.ws()
+		var right filterNode
+		if right, e = self.comparison(); e == nil {
+			left = andNode{left, right}
+		}
+	}
+	return nil, e
+}
+
+func (self *filterParser) comparison() (filterNode, error) {
+	left, e := self.additive()
+	if e != nil {
+		return nil, e
+	}
+	self.ws()
+	for _, op := range compareOps {
+		if self.eatString(op) {
+			self.ws()
+			right, e := self.additive()
+			if e != nil {
+				return nil, e
+			}
+			return binaryNode{op, left, right}, nil
+		}
+	}
+	return left, nil
+}
+
+func (self *filterParser) additive() (filterNode, error) {
+	left, e := self.multiplicative()
+	for e == nil {
+		self.ws()
+		op := self.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		self.pos++
+		self.ws()
+		var right filterNode
+		if right, e = self.multiplicative(); e == nil {
+			left = binaryNode{string(op), left, right}
+		}
+	}
+	return nil, e
+}
+
+func (self *filterParser) multiplicative() (filterNode, error) {
+	left, e := self.unary()
+	for e == nil {
+		self.ws()
+		op := self.peek()
+		if op != '*' && op != '%' && (op != '/' || strings.HasPrefix(self.s[self.pos:], "//")) {
+			return left, nil
+		}
+		self.pos++
+		self.ws()
+		var right filterNode
+		if right, e = self.unary(); e == nil {
+			left = binaryNode{string(op), left, right}
+		}
+	}
+	return nil, e
+}
+
+func (self *filterParser) unary() (filterNode, error) {
+	if self.eat('-') {
+		self.ws()
+		x, e := self.unary()
+		if e != nil {
+			return nil, e
+		}
+		return negNode{x}, nil
+	}
+	return self.postfix(true)
+}
+
+// a term with its suffixes; "as" is taken unless it is the reduce source
+func (self *filterParser) postfix(as bool) (filterNode, error) {
+	x, e := self.primary()
+	for e == nil {
+		switch c := self.peek(); {
+		case c == '.' && self.pos+1 < len(self.s) && (isNameFirst(self.s[self.pos+1]) || self.s[self.pos+1] == '"'):
+			self.pos++
+			var key filterNode
+			if key, e = self.field(); e == nil {
+				x = indexNode{x, key}
+			}
+		case c == '.' && self.pos+1 < len(self.s) && self.s[self.pos+1] == '[':
+			self.pos++
+		case c == '[':
+			x, e = self.bracket(x)
+		case c == '?':
+			self.pos++
+			x = tryNode{x, nil}
+		default:
+			if as && self.eatKeyword("as") {
+				return self.binding(x)
+			}
+			return x, nil
+		}
+	}
+	return nil, e
+}
+
+// source as $name | body
+func (self *filterParser) binding(source filterNode) (filterNode, error) {
+	self.ws()
+	if self.peek() != '$' {
+		return nil, self.error("Expected a variable")
+	}
+	name, e := self.variable()
+	if e != nil {
+		return nil, e
+	}
+	if e := self.expect('|'); e != nil {
+		return nil, e
+	}
+	self.ws()
+	body, e := self.bound(name, self.pipe)
+	if e != nil {
+		return nil, e
+	}
+	return asNode{source, name, body}, nil
+}
+
+// the name or the string after a '.'
+func (self *filterParser) field() (filterNode, error) {
+	if self.peek() == '"' {
+		return self.str()
+	}
+	name := self.word()
+	self.pos += len(name)
+	return literalNode{NewJsonString(name)}, nil
+}
+
+// [], [e], [e:e], [:e], [e:]
+func (self *filterParser) bracket(target filterNode) (filterNode, error) {
+	self.pos++
+	self.ws()
+	if self.eat(']') {
+		return iterateNode{target}, nil
+	}
+	var from, to filterNode
+	var e error
+	if self.peek() != ':' {
+		if from, e = self.pipe(); e != nil {
+			return nil, e
+		}
+		self.ws()
+		if self.eat(']') {
+			return indexNode{target, from}, nil
+		}
+	}
+	if e := self.expect(':'); e != nil {
+		return nil, e
+	}
+	self.ws()
+	if self.peek() != ']' {
+		if to, e = self.pipe(); e != nil {
+			return nil, e
+		}
+	}
+	if from == nil && to == nil {
+		return nil, self.error("Empty slice")
+	}
+	if e := self.expect(']'); e != nil {
+		return nil, e
+	}
+	return sliceNode{target, from, to}, nil
+}
+
+func (self *filterParser) primary() (filterNode, error) {
+	self.ws()
+	c := self.peek()
+	switch {
+	case c == '.':
+		self.pos++
+		switch n := self.peek(); {
+		case n == '.':
+			self.pos++
+			return recurseNode{}, nil
+		case isNameFirst(n) || n == '"':
+			key, e := self.field()
+			if e != nil {
+				return nil, e
+			}
+			return indexNode{identityNode{}, key}, nil
+		}
+		return identityNode{}, nil
+	case c == '$':
+		start := self.pos
+		name, e := self.variable()
+		if e != nil {
+			return nil, e
+		}
+		for _, x := range self.scope {
+			if x == name {
+				return varNode{name}, nil
+			}
+		}
+		self.pos = start
+		return nil, self.error("Undefined variable $%s", name)
+	case isDigit(c):
+		return self.number()
+	case c == '"':
+		return self.str()
+	case c == '(':
+		self.pos++
+		self.ws()
+		x, e := self.pipe()
+		if e != nil {
+			return nil, e
+		}
+		if e := self.expect(')'); e != nil {
+			return nil, e
+		}
+		return x, nil
+	case c == '[':
+		self.pos++
+		self.ws()
+		if self.eat(']') {
+			return arrayNode{}, nil
+		}
+		x, e := self.pipe()
+		if e != nil {
+			return nil, e
+		}
+		if e := self.expect(']'); e != nil {
+			return nil, e
+		}
+		return arrayNode{x}, nil
+	case c == '{':
+		return self.object()
+	case c == '@':
+		self.pos++
+		name := self.word()
+		fn, found := filterFormats[name]
+		if !found {
+			return nil, self.error("Unknown format @%s", name)
+		}
+		self.pos += len(name)
+		return formatNode{fn}, nil
+	case isNameFirst(c):
+		return self.keywordOrCall()
+	case c == 0:
+		return nil, self.error("Unexpected end")
+	}
+	return nil, self.error("Unexpected %+q", self.s[self.pos:])
+}
+
+func (self *filterParser) keywordOrCall() (filterNode, error) {
+	name := self.word()
+	switch name {
+	case "true", "false":
+		self.pos += len(name)
+		return literalNode{NewJsonBool(name == "true")}, nil
+	case "null":
+		self.pos += len(name)
+		return literalNode{nil}, nil
+	case "if":
+		self.pos += len(name)
+		return self.conditional()
+	case "try":
+		self.pos += len(name)
+		body, e := self.postfix(false)
+		if e != nil {
+			return nil, e
+		}
+		if !self.eatKeyword("catch") {
+			return tryNode{body, nil}, nil
+		}
+		handler, e := self.postfix(false)
+		if e != nil {
+			return nil, e
+		}
+		return tryNode{body, handler}, nil
+	case "reduce":
+		self.pos += len(name)
+		return self.reduce()
+	}
+	if filterKeywords[name] {
+		return nil, self.error("Unsupported %+q", name)
+	}
+	start := self.pos
+	self.pos += len(name)
+	var args []filterNode
+	if self.eat('(') {
+		for {
+			self.ws()
+			x, e := self.pipe()
+			if e != nil {
+				return nil, e
+			}
+			args = append(args, x)
+			self.ws()
+			if self.eat(')') {
+				break
+			}
+			if !self.eat(';') {
+				return nil, self.error("Expected ';' or ')'")
+			}
+		}
+	}
+	fn, found := filterBuiltins[fmt.Sprintf("%s/%d", name, len(args))]
+	if !found {
+		self.pos = start
+		return nil, self.error("Unknown function %s/%d", name, len(args))
+	}
+	return callNode{fn, args}, nil
+}
+
+// after "if" (or "elif"): the condition up to "end"
+func (self *filterParser) conditional() (filterNode, error) {
+	cond, e := self.pipe()
+	if e != nil {
+		return nil, e
+	}
+	if e := self.expectKeyword("then"); e != nil {
+		return nil, e
+	}
+	then, e := self.pipe()
+	if e != nil {
+		return nil, e
+	}
+	r := ifNode{cond, then, identityNode{}}
+	if self.eatKeyword("elif") {
+		r.otherwise, e = self.conditional() // that one takes the "end"
+		return r, e
+	}
+	if self.eatKeyword("else") {
+		if r.otherwise, e = self.pipe(); e != nil {
+			return nil, e
+		}
+	}
+	return r, self.expectKeyword("end")
+}
+
+// after "reduce": source as $name (init; update)
+func (self *filterParser) reduce() (filterNode, error) {
+	source, e := self.postfix(false)
+	if e != nil {
+		return nil, e
+	}
+	if e := self.expectKeyword("as"); e != nil {
+		return nil, e
+	}
+	self.ws()
+	if self.peek() != '$' {
+		return nil, self.error("Expected a variable")
+	}
+	name, e := self.variable()
+	if e != nil {
+		return nil, e
+	}
+	if e := self.expect('('); e != nil {
+		return nil, e
+	}
+	init, e := self.pipe()
+	if e != nil {
+		return nil, e
+	}
+	if e := self.expect(';'); e != nil {
+		return nil, e
+	}
+	update, e := self.bound(name, self.pipe)
+	if e != nil {
+		return nil, e
+	}
+	if e := self.expect(')'); e != nil {
+		return nil, e
+	}
+	return reduceNode{source, name, init, update}, nil
+}
+
+func (self *filterParser) number() (filterNode, error) {
+	start := self.pos
+	digits := func() {
+		for isDigit(self.peek()) {
+			self.pos++
+		}
+	}
+	digits()
+	float := false
+	if self.peek() == '.' && self.pos+1 < len(self.s) && isDigit(self.s[self.pos+1]) {
+		self.pos++
+		digits()
+		float = true
+	}
+	if c := self.peek(); c == 'e' || c == 'E' {
+		self.pos++
+		if c := self.peek(); c == '+' || c == '-' {
+			self.pos++
+		}
+		digits()
+		float = true
+	}
+	t := self.s[start:self.pos]
+	if !float {
+		if i, e := strconv.ParseInt(t, 10, 0); e == nil {
+			return literalNode{NewJsonInt(int(i))}, nil
+		}
+	}
+	f, e := strconv.ParseFloat(t, 64)
+	if e != nil {
+		self.pos = start
+		return nil, self.error("Bad number %+q", t)
+	}
+	return literalNode{NewJsonFloat(f)}, nil
+}
+
+// a string literal, a stringNode if there is \(...) in it
+func (self *filterParser) str() (filterNode, error) {
+	self.pos++
+	var parts stringNode
+	var b strings.Builder
+	interpolated := false
+	flush := func() {
+		if b.Len() > 0 {
+			parts = append(parts, literalNode{NewJsonString(b.String())})
+			b.Reset()
+		}
+	}
+	for self.pos < len(self.s) {
+		c := self.s[self.pos]
+		self.pos++
+		switch {
+		case c == '"':
+			if !interpolated {
+				return literalNode{NewJsonString(b.String())}, nil
+			}
+			flush()
+			return parts, nil
+		case c == '\\' && self.peek() == '(':
+			self.pos++
+			flush()
+			self.ws()
+			x, e := self.pipe()
+			if e != nil {
+				return nil, e
+			}
+			if e := self.expect(')'); e != nil {
+				return nil, e
+			}
+			parts = append(parts, x)
+			interpolated = true
+		case c == '\\':
+			c = self.peek()
+			self.pos++
+			switch c {
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '/', '\\', '"':
+				b.WriteByte(c)
+			case 'u':
+				if self.pos+4 > len(self.s) {
+					return nil, self.error("Bad unicode escape")
+				}
+				v, e := strconv.ParseUint(self.s[self.pos:self.pos+4], 16, 32)
+				if e != nil {
+					return nil, self.error("Bad unicode escape")
+				}
+				self.pos += 4
+				b.WriteRune(rune(v))
+			default:
+				return nil, self.error("Bad escape '\\%c'", c)
+			}
+		case c < 0x20:
+			return nil, self.error("Control character in string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return nil, self.error("Unterminated string")
+}
+
+// a member value: pipes are fine (jq's ObjVal), commas need parentheses
+func (self *filterParser) objectValue() (filterNode, error) {
+	left, e := self.alternative()
+	if e != nil {
+		return nil, e
+	}
+	self.ws()
+	if !self.eat('|') {
+		return left, nil
+	}
+	self.ws()
+	right, e := self.objectValue()
+	if e != nil {
+		return nil, e
+	}
+	return pipeNode{left, right}, nil
+}
+
+// {a, "b", $c, d: e, "\(e)": e, (e): e}
+func (self *filterParser) object() (filterNode, error) {
+	self.pos++
+	r := objectNode{}
+	self.ws()
+	if self.eat('}') {
+		return r, nil
+	}
+	for {
+		self.ws()
+		var key, value filterNode
+		var e error
+		shorthand := true
+		switch c := self.peek(); {
+		case c == '$':
+			start := self.pos
+			var name string
+			if name, e = self.variable(); e == nil {
+				self.pos = start
+				key = literalNode{NewJsonString(name)}
+				value, e = self.primary()
+			}
+		case c == '"':
+			key, e = self.str()
+		case c == '(':
+			self.pos++
+			if key, e = self.pipe(); e == nil {
+				e = self.expect(')')
+			}
+			shorthand = false
+		case isNameFirst(c):
+			name := self.word()
+			self.pos += len(name)
+			key = literalNode{NewJsonString(name)}
+		default:
+			return nil, self.error("Expected an object key")
+		}
+		if e != nil {
+			return nil, e
+		}
+		self.ws()
+		switch {
+		case value != nil:
+		case self.eat(':'):
+			self.ws()
+			if value, e = self.objectValue(); e != nil {
+				return nil, e
+			}
+		case shorthand:
+			value = indexNode{identityNode{}, key}
+		default:
+			return nil, self.error("Expected ':'")
+		}
+		r = append(r, objectEntry{key, value})
+		self.ws()
+		if self.eat('}') {
+			return r, nil
+		}
+		if !self.eat(',') {
+			return nil, self.error("Expected ',' or '}'")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	report := `{"name": "Report", "items": [
+		{"id": 1, "tags": ["a", "b"], "price": 2.5, "qty": 4},
+		{"id": 2, "tags": [], "price": 10, "qty": 1},
+		{"id": 3, "tags": ["b"], "price": 0.5, "qty": 3, "note": "cheap"}
+	]}`
+
+	cases := []struct {
+		program, input, output string // the outputs compact, separated by spaces
+	}{
+		{``, `{"a": 1}`, `{"a":1}`},
+		{`.`, `[1, 2]`, `[1,2]`},
+		{`.name`, report, `"Report"`},
+		{`.items[0].id, .items[-1]["id"]`, report, `1 3`},
+		{`.items[].id`, report, `1 2 3`},
+		{`.items[5], .missing, .missing.deeper`, report, `null null null`},
+		{`.items[1:].[].id`, report, `2 3`},
+		{`.name[:3], .name[-3:]`, report, `"Rep" "ort"`},
+		{`[.items[] | select(.price > 1) | .id]`, report, `[1,2]`},
+		{`.items | map({id, total: (.price * .qty)})`, report, `[{"id":1,"total":10.0},{"id":2,"total":10},{"id":3,"total":1.5}]`},
+		{`.items | map(.qty) | add`, report, `8`},
+		{`[.items[].tags[]] | unique`, report, `["a","b"]`},
+		{`.items | map(.tags | length)`, report, `[2,0,1]`},
+		{`.items | sort_by(-.price) | map(.id)`, report, `[2,1,3]`},
+		{`.items | group_by(.tags | length > 0) | map(map(.id))`, report, `[[2],[1,3]]`},
+		{`.items | max_by(.qty) | .id`, report, `1`},
+		{`.items | map(.note // "n/a")`, report, `["n/a","n/a","cheap"]`},
+		{`.items[] | "\(.id): \(.tags | join(","))"`, report, `"1: a,b" "2: " "3: b"`},
+		{`{(.name): (.items | length)}`, report, `{"Report":3}`},
+		{`{n: .items | length, first: .items[0] | .id | -., name}`, report, `{"first":-1,"n":3,"name":"Report"}`},
+		{`.items[0] | keys`, report, `["id","price","qty","tags"]`},
+		{`.items[0] | to_entries | map(.key) | join("-")`, report, `"id-price-qty-tags"`},
+		{`.items[0] | with_entries(select(.value | type == "number"))`, report, `{"id":1,"price":2.5,"qty":4}`},
+		{`.items | map(has("note"))`, report, `[false,false,true]`},
+		{`reduce .items[] as $x (0; . + $x.qty)`, report, `8`},
+		{`.name as $n | .items | map($n + "#" + (.id | tostring))`, report, `["Report#1","Report#2","Report#3"]`},
+		{`.items[] | if .qty > 3 then "many" elif .qty > 1 then "some" else "one" end`, report, `"many" "one" "some"`},
+		{`1 + 2 * 3 - 4 / 2, 7 / 2, 7 % 3, -(1 + 1), 1 + 1.5`, `null`, `5 3.5 1 -2 2.5`},
+		{`9223372036854775807 + 1`, `null`, `9.223372036854776e+18`},
+		{`1 == 1.0, [1] == [1.0], 1 < 1.5, "a" < "b", null < false, [] < {}`, `null`, `true true true true true true`},
+		{`[true and (false, true)], (false or false), ((1, null) | not)`, `null`, `[false,true] false false true`},
+		{`(1, 2) + (10, 20)`, `null`, `11 12 21 22`},
+		{`{a: (1, 2), b: (3, 4)} | [.a, .b]`, `null`, `[1,3] [1,4] [2,3] [2,4]`},
+		{`{"a": {"x": 1}} * {"a": {"y": 2}}, {"a": 1} + {"b": 2}, [1, 2, 3, 1] - [1]`, `null`, `{"a":{"x":1,"y":2}} {"a":1,"b":2} [2,3]`},
+		{`"a,b,c" | split(","), ("abc" | length, ascii_upcase), ("x" * 3)`, `null`, `["a","b","c"] 3 "ABC" "xxx"`},
+		{`"  hi " | trim, ("prefix.rest" | ltrimstr("prefix."), startswith("pre"), test("^p.*t$"))`, `null`, `"hi" "rest" true true`},
+		{`"12" | tonumber, ("1.5" | tonumber), (12 | tostring), ({"a": [1]} | tojson), ("[1]" | fromjson)`, `null`, `12 1.5 "12" "{\"a\":[1]}" [1]`},
+		{`[.[] | floor], (.[1] | sqrt)`, `[1.5, 4]`, `[1,4] 2.0`},
+		{`[limit(2; .[])], first(.[]), [range(3)], [range(1; 3)]`, `[5, 6, 7]`, `[5,6] 5 [0,1,2] [1,2]`},
+		{`([..] | length), ([.. | numbers] | length)`, `{"a": [1, {"b": 2}]}`, `5 2`},
+		{`[.[] | .a?], [.[] | try error("x") catch .]`, `[1, {"a": 2}]`, `[2] ["x","x"]`},
+		{`try error({"code": 1}) catch .code`, `null`, `1`},
+		{`any, all, (map(. > 1) | any), flatten`, `[1, [2, [3]]]`, `true true true [1,2,3]`},
+		{`[.[] | values], map(type)`, `[1, null, "a"]`, `[1,"a"] ["number","null","string"]`},
+		{`@csv, @tsv, @json, (.[0] | @base64)`, `["a\"b", 1, null, true]`, `"\"a\"\"b\",1,,true" "a\"b\t1\t\ttrue" "[\"a\\\"b\",1,null,true]" "YSJi"`},
+		{`contains({"a": [1]}), ("foobar" | contains("bar"))`, `{"a": [1, 2], "b": 3}`, `true true`},
+		{`.[] as $x | $x * 2 # the doubles`, `[1, 2]`, `2 4`},
+		{`map_values(. + 1), min, max, reverse, (sort | first, last)`, `[3, 1, 2]`, `[4,2,3] 1 3 [2,1,3] 1 3`},
+		{`empty, 1`, `null`, `1`},
+	}
+	for _, x := range cases {
+		f, e := Compile(x.program)
+		if e != nil {
+			t.Errorf("Compile(%+q): %v", x.program, e)
+			continue
+		}
+		r, e := f.Run(mustParse(t, x.input))
+		if e != nil {
+			t.Errorf("%+q: %v", x.program, e)
+			continue
+		}
+		got := make([]string, len(r))
+		for i, v := range r {
+			got[i], _ = Format(v, "")
+		}
+		if s := strings.Join(got, " "); s != x.output {
+			t.Errorf("%+q: %s, not %s", x.program, s, x.output)
+		}
+	}
+
+	// ints stay ints, floats stay floats
+	r, _ := MustCompile(`.[0] + .[1], .[0] + .[2]`).Run(mustParse(t, `[1, 2, 2.0]`))
+	if _, ok := r[0].(*JsonInt); !ok {
+		t.Errorf("1 + 2 is %T", r[0])
+	}
+	if _, ok := r[1].(*JsonFloat); !ok {
+		t.Errorf("1 + 2.0 is %T", r[1])
+	}
+
+	for _, x := range []string{`.a |`, `.[`, `{a: }`, `$undefined`, `nosuchfunction`, `map()`, `"open`, `if . then 1`, `@nope`, `1 as x | .`, `def f: .; f`, `. ]`} {
+		if _, e := Compile(x); e == nil {
+			t.Errorf("Compile(%+q) did not fail", x)
+		}
+	}
+	for _, x := range []struct{ program, input string }{
+		{`.a`, `[1]`},
+		{`.[0]`, `{"a": 1}`},
+		{`.[]`, `1`},
+		{`1 / 0`, `null`},
+		{`"a" - 1`, `null`},
+		{`{(1): 2}`, `null`},
+		{`error("boom")`, `null`},
+		{`length`, `true`},
+		{`keys`, `1`},
+	} {
+		if _, e := MustCompile(x.program).Run(mustParse(t, x.input)); e == nil {
+			t.Errorf("%+q over %s did not fail", x.program, x.input)
+		}
+	}
+
+	// the outputs made before an error are there
+	r, e := MustCompile(`.[] | 10 / .`).Run(mustParse(t, `[1, 2, 0, 5]`))
+	if e == nil || len(r) != 2 {
+		t.Errorf("outputs before the error: %v, %v", r, e)
+	} else if e.Error() != "number (10) cannot be divided by zero" {
+		t.Errorf("error message: %v", e)
+	}
+}
@@ -0,0 +1,169 @@
+// Parsing whole texts with positioned errors, formatting with indentation
+package json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// a parse error with its position in the text (all counted from 1, the
+// column in runes); Line is 0 when the position is unknown
+type PositionError struct {
+	Line, Column, Offset int
+	Err                  error
+}
+
+func (self *PositionError) Error() string {
+	if self.Line == 0 {
+		return self.Err.Error()
+	}
+	return fmt.Sprintf("%d:%d: %v", self.Line, self.Column, self.Err)
+}
+
+func (self *PositionError) Unwrap() error { return self.Err }
+
+// parses the whole text (nothing but spaces may follow the value); the
+// errors are *PositionError
+func Parse(s string) (v JsonValue, e error) {
+	defer func() {
+		if r := recover(); r != nil { // getString() panics on bad \uXXXX
+			v, e = nil, positionError(s, badEscape(s), SyntaxError(fmt.Errorf("Bad \\u escape: %v", r)))
+		}
+	}()
+	v, t, e := ParseValue(s)
+	if e == nil && strings.TrimSpace(t) != "" {
+		e = BadTail(fmt.Errorf("%+q bad tail", t))
+	}
+	if e != nil {
+		return nil, positionError(s, tailOffset(s, t), e)
+	}
+	return v, nil
+}
+
+// where the tail (its first non-space) starts in the text: the parsers slice
+// the text (trimming the spaces on both ends), so the tail ends where the text does
+func tailOffset(s, t string) int {
+	t = strings.TrimLeftFunc(t, unicode.IsSpace)
+	if t == "" {
+		return len(strings.TrimRightFunc(s, unicode.IsSpace))
+	}
+	return len(strings.TrimRightFunc(s, unicode.IsSpace)) - len(strings.TrimRightFunc(t, unicode.IsSpace))
+}
+
+// the first \u not followed by four hex digits, -1 if none
+func badEscape(s string) int {
+	for i := 0; i+1 < len(s); i++ {
+		if s[i] != '\\' {
+			continue
+		}
+		if s[i+1] == 'u' {
+			for j := i + 2; j < i+6; j++ {
+				if j >= len(s) || strings.IndexByte("0123456789abcdefABCDEF", s[j]) < 0 {
+					return i
+				}
+			}
+		}
+		i++ // the escaped one
+	}
+	return -1
+}
+
+func positionError(s string, offset int, e error) error {
+	if offset < 0 || offset > len(s) {
+		return &PositionError{Err: e}
+	}
+	line, column := 1, 1
+	for _, c := range s[:offset] {
+		if c == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return &PositionError{Line: line, Column: column, Offset: offset, Err: e}
+}
+
+// renders the value with the members (sorted by key) and the elements on
+// their own lines indented by indent, or compact if indent is ""; unlike
+// .Json() the floats keep all their digits (yet remain floats, "1.0")
+func Format(v JsonValue, indent string) (string, error) {
+	if e := FindCycle(v); e != nil {
+		return "", e
+	}
+	var b strings.Builder
+	format(&b, v, indent, "\n")
+	return b.String(), nil
+}
+
+// all the digits, yet still a float: "1.0", not "1"
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
+
+func format(b *strings.Builder, v JsonValue, indent, nl string) {
+	sep, colon := ",", ":"
+	if indent != "" {
+		colon = ": "
+	}
+	open := func(c byte) {
+		b.WriteByte(c)
+		if indent != "" {
+			b.WriteString(nl + indent)
+		}
+	}
+	next := func() {
+		b.WriteString(sep)
+		if indent != "" {
+			b.WriteString(nl + indent)
+		}
+	}
+	close := func(c byte) {
+		if indent != "" {
+			b.WriteString(nl)
+		}
+		b.WriteByte(c)
+	}
+	if isNullValue(v) {
+		b.WriteString("null")
+		return
+	}
+	switch x := v.(type) {
+	case *JsonFloat:
+		b.WriteString(formatFloat(float64(*x)))
+	case *JsonArray:
+		if len(*x) == 0 {
+			b.WriteString("[]")
+			return
+		}
+		open('[')
+		for i, o := range *x {
+			if i > 0 {
+				next()
+			}
+			format(b, o, indent, nl+indent)
+		}
+		close(']')
+	case *JsonObject:
+		if len(*x) == 0 {
+			b.WriteString("{}")
+			return
+		}
+		open('{')
+		for i, k := range sortedKeys(*x) {
+			if i > 0 {
+				next()
+			}
+			b.WriteString(quote(k) + colon)
+			format(b, (*x)[k], indent, nl+indent)
+		}
+		close('}')
+	default:
+		b.WriteString(v.Json())
+	}
+}
@@ -0,0 +1,66 @@
+package json
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	errs := []struct {
+		text         string
+		line, column int
+	}{
+		{`{ "a": 1, "b": x }`, 1, 16},
+		{"[\n  1,\n  2 3\n]", 3, 5},
+		{"{\n\t\"a\" 1 }", 2, 6},
+		{`[ 1 ] tail`, 1, 7},
+		{"\"héllo\" \n  ?", 2, 3},
+		{`[ "ok", "bad \u12x4" ]`, 1, 14},
+		{`[ "ok", "fine \\u12x4", 1,, 2 ]`, 1, 27},
+		{`"never closed`, 1, 14},
+		{"  \n ", 1, 1},
+	}
+	for _, x := range errs {
+		_, e := Parse(x.text)
+		var pe *PositionError
+		if !errors.As(e, &pe) {
+			t.Errorf("Parse(%+q): %v", x.text, e)
+			continue
+		}
+		if pe.Line != x.line || pe.Column != x.column {
+			t.Errorf("Parse(%+q): at %d:%d, not %d:%d (%v)", x.text, pe.Line, pe.Column, x.line, x.column, e)
+		}
+	}
+	v, e := Parse(" \n{ \"b\": [ 1, 2.5, 1e-9, 3.0, {}, [] ], \"a\": { \"x\": null, \"q\\\"\": \"\\u0001\" } }\n")
+	if e != nil {
+		t.Fatalf("Parse(): %v", e)
+	}
+
+	const compact = `{"a":{"q\"":"\u0001","x":null},"b":[1,2.5,1e-09,3.0,{},[]]}`
+	if s, e := Format(v, ""); e != nil || s != compact {
+		t.Errorf("Format(compact) = %s, %v", s, e)
+	}
+	const indented = `{
+  "a": {
+    "q\"": "\u0001",
+    "x": null
+  },
+  "b": [
+    1,
+    2.5,
+    1e-09,
+    3.0,
+    {},
+    []
+  ]
+}`
+	if s, e := Format(v, "  "); e != nil || s != indented {
+		t.Errorf("Format(indented) = %s, %v", s, e)
+	}
+	if w, e := Parse(compact); e != nil || !w.Equal(v) {
+		t.Errorf("Parse(Format()) = %s, %v", jsonOf(w), e)
+	}
+	if s, _ := Format(nil, "\t"); s != "null" {
+		t.Errorf("Format(nil) = %s", s)
+	}
+}
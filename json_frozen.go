@@ -0,0 +1,431 @@
+// Immutable (persistent) values sharing the unchanged parts between versions
+package json
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type FrozenError error
+
+// an immutable value: nil (null), *FrozenScalar, *FrozenArray or *FrozenObject;
+// the updates make new values sharing all the untouched subtrees with the old
+// ones (only the containers along the path are copied), so keeping many
+// versions around is cheap
+type FrozenValue interface {
+	Json() string
+	Thaw() JsonValue // a mutable deep copy
+	Equal(FrozenValue) bool
+}
+
+// a number, a bool or a string
+type FrozenScalar struct{ v JsonValue }
+
+type FrozenArray struct{ items []FrozenValue }
+
+type FrozenObject struct{ members map[string]FrozenValue }
+
+// an immutable copy of the value; the null containers become nil
+func Freeze(v JsonValue) FrozenValue {
+	if isNilValue(v) || v.IsNull() && !isScalar(v) {
+		return nil
+	}
+	switch x := v.(type) {
+	case *JsonArray:
+		r := &FrozenArray{make([]FrozenValue, len(*x))}
+		for i, o := range *x {
+			r.items[i] = Freeze(o)
+		}
+		return r
+	case *JsonObject:
+		r := &FrozenObject{make(map[string]FrozenValue, len(*x))}
+		for k, o := range *x {
+			r.members[k] = Freeze(o)
+		}
+		return r
+	}
+	return &FrozenScalar{v.Clone()}
+}
+
+func isScalar(v JsonValue) bool {
+	switch v.(type) {
+	case *JsonArray, *JsonObject:
+		return false
+	}
+	return true
+}
+
+// like Freeze() for Go values (see ToJsonValue()); a FrozenValue is taken as it is
+func freezeAny(v interface{}) (FrozenValue, error) {
+	if f, ok := v.(FrozenValue); ok {
+		return f, nil
+	}
+	x, e := ToJsonValue(v)
+	if e != nil {
+		return nil, e
+	}
+	return Freeze(x), nil
+}
+
+// .Thaw() that tolerates nil
+func thaw(v FrozenValue) JsonValue {
+	if v == nil {
+		return nil
+	}
+	return v.Thaw()
+}
+
+// .Json() that tolerates nil
+func frozenJson(v FrozenValue) string {
+	if v == nil {
+		return "null"
+	}
+	return v.Json()
+}
+
+// .Equal() that tolerates nil; the shared subtrees are equal right away
+func frozenEqual(a, b FrozenValue) bool {
+	if a == b {
+		return true
+	}
+	if a == nil {
+		return frozenNull(b)
+	}
+	return a.Equal(b)
+}
+
+func frozenNull(v FrozenValue) bool {
+	s, ok := v.(*FrozenScalar)
+	return v == nil || ok && s.v.IsNull()
+}
+
+/*----------------------------------------------------------------------------*/
+
+func (self *FrozenScalar) Json() string       { return self.v.Json() }
+func (self *FrozenScalar) Thaw() JsonValue    { return self.v.Clone() }
+func (self *FrozenScalar) Value() interface{} { return self.v.Value() }
+func (self *FrozenScalar) Equal(v FrozenValue) bool {
+	if x, ok := v.(*FrozenScalar); ok {
+		return equalValues(self.v, x.v)
+	}
+	return v == nil && self.v.IsNull()
+}
+
+/*----------------------------------------------------------------------------*/
+
+func (self *FrozenArray) Len() int { return len(self.items) }
+
+// the element by its index; negative ones count from the end
+func (self *FrozenArray) Get(i int) (FrozenValue, error) {
+	j := i
+	if j < 0 {
+		j += len(self.items)
+	}
+	if j < 0 || j >= len(self.items) {
+		return nil, elementError(i, ErrMissing)
+	}
+	return self.items[j], nil
+}
+
+func (self *FrozenArray) Json() string {
+	var r []string
+	for _, o := range self.items {
+		r = append(r, frozenJson(o))
+	}
+	return "[ " + strings.Join(r, ", ") + " ]"
+}
+
+func (self *FrozenArray) Thaw() JsonValue {
+	r := make(JsonArray, len(self.items))
+	for i, o := range self.items {
+		r[i] = thaw(o)
+	}
+	return &r
+}
+
+func (self *FrozenArray) Equal(v FrozenValue) bool {
+	x, ok := v.(*FrozenArray)
+	if !ok || len(self.items) != len(x.items) {
+		return false
+	}
+	for i, o := range self.items {
+		if !frozenEqual(o, x.items[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// the value at the dotted path, see Lookup()
+func (self *FrozenArray) Lookup(path string) (FrozenValue, error) { return frozenLookup(self, path) }
+
+// a new array with the value (see ToJsonValue(), a FrozenValue is shared) set at the dotted path
+func (self *FrozenArray) Set(path string, v interface{}) (*FrozenArray, error) {
+	r, e := frozenSet(self, path, v)
+	if e != nil {
+		return nil, e
+	}
+	return r.(*FrozenArray), nil
+}
+
+// a new array with the value appended to the array at the dotted path ("" for this one)
+func (self *FrozenArray) Append(path string, v interface{}) (*FrozenArray, error) {
+	r, e := frozenAppend(self, path, v)
+	if e != nil {
+		return nil, e
+	}
+	return r.(*FrozenArray), nil
+}
+
+// a new array without the member or element at the dotted path
+func (self *FrozenArray) Delete(path string) (*FrozenArray, error) {
+	r, e := frozenDelete(self, path)
+	if e != nil {
+		return nil, e
+	}
+	return r.(*FrozenArray), nil
+}
+
+/*----------------------------------------------------------------------------*/
+
+func (self *FrozenObject) Len() int { return len(self.members) }
+
+func (self *FrozenObject) Has(key string) bool {
+	_, found := self.members[key]
+	return found
+}
+
+// the member by its key
+func (self *FrozenObject) Get(key string) (FrozenValue, error) {
+	if v, found := self.members[key]; found {
+		return v, nil
+	}
+	return nil, memberError(key, ErrMissing)
+}
+
+// the member names, sorted
+func (self *FrozenObject) Keys() []string {
+	keys := make([]string, 0, len(self.members))
+	for k := range self.members {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (self *FrozenObject) Json() string {
+	var r []string
+	for _, k := range self.Keys() {
+		r = append(r, quote(k)+": "+frozenJson(self.members[k]))
+	}
+	return "{ " + strings.Join(r, ", ") + " }"
+}
+
+func (self *FrozenObject) Thaw() JsonValue {
+	r := make(JsonObject, len(self.members))
+	for k, o := range self.members {
+		r[k] = thaw(o)
+	}
+	return &r
+}
+
+func (self *FrozenObject) Equal(v FrozenValue) bool {
+	x, ok := v.(*FrozenObject)
+	if !ok || len(self.members) != len(x.members) {
+		return false
+	}
+	for k, o := range self.members {
+		p, found := x.members[k]
+		if !found || !frozenEqual(o, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// the value at the dotted path, see Lookup()
+func (self *FrozenObject) Lookup(path string) (FrozenValue, error) { return frozenLookup(self, path) }
+
+// a new object with the value (see ToJsonValue(), a FrozenValue is shared) set
+// at the dotted path; the missing objects on the way are created, see Builder.Set()
+func (self *FrozenObject) Set(path string, v interface{}) (*FrozenObject, error) {
+	r, e := frozenSet(self, path, v)
+	if e != nil {
+		return nil, e
+	}
+	return r.(*FrozenObject), nil
+}
+
+// a new object with the value appended to the array at the dotted path, created if missing
+func (self *FrozenObject) Append(path string, v interface{}) (*FrozenObject, error) {
+	r, e := frozenAppend(self, path, v)
+	if e != nil {
+		return nil, e
+	}
+	return r.(*FrozenObject), nil
+}
+
+// a new object without the member or element at the dotted path
+func (self *FrozenObject) Delete(path string) (*FrozenObject, error) {
+	r, e := frozenDelete(self, path)
+	if e != nil {
+		return nil, e
+	}
+	return r.(*FrozenObject), nil
+}
+
+/*----------------------------------------------------------------------------*/
+
+func splitPath(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+func frozenLookup(v FrozenValue, path string) (FrozenValue, error) {
+	var done []string
+	for _, w := range splitPath(path) {
+		var e error
+		switch c := v.(type) {
+		case *FrozenObject:
+			v, e = c.Get(w)
+		case *FrozenArray:
+			i, ae := strconv.Atoi(w)
+			if ae != nil {
+				e = fmt.Errorf("%w: %+q is not an index", ErrType, w)
+			} else {
+				v, e = c.Get(i)
+			}
+		default:
+			e = fmt.Errorf("%w: cannot step into %s", ErrType, frozenJson(v))
+		}
+		if e != nil {
+			return nil, fmt.Errorf("path %+q at %+q: %w", path, strings.Join(done, "."), e)
+		}
+		done = append(done, w)
+	}
+	return v, nil
+}
+
+// copies the containers along the path, the last one gets fn's result for the
+// value found there (nil if missing); the missing containers become objects
+func frozenUpdate(v FrozenValue, words []string, fn func(FrozenValue) (FrozenValue, error)) (FrozenValue, error) {
+	if len(words) == 0 {
+		return fn(v)
+	}
+	w := words[0]
+	if v == nil {
+		v = &FrozenObject{}
+	}
+	switch c := v.(type) {
+	case *FrozenObject:
+		x, e := frozenUpdate(c.members[w], words[1:], fn)
+		if e != nil {
+			return nil, e
+		}
+		r := &FrozenObject{make(map[string]FrozenValue, len(c.members)+1)}
+		for k, o := range c.members {
+			r.members[k] = o
+		}
+		r.members[w] = x
+		return r, nil
+	case *FrozenArray:
+		i, e := strconv.Atoi(w)
+		if e != nil || i < 0 || i > len(c.items) {
+			return nil, FrozenError(fmt.Errorf("Bad index %+q", w))
+		}
+		var old FrozenValue
+		if i < len(c.items) {
+			old = c.items[i]
+		}
+		x, e := frozenUpdate(old, words[1:], fn)
+		if e != nil {
+			return nil, e
+		}
+		r := &FrozenArray{make([]FrozenValue, len(c.items), len(c.items)+1)}
+		copy(r.items, c.items)
+		if i == len(c.items) {
+			r.items = append(r.items, x)
+		} else {
+			r.items[i] = x
+		}
+		return r, nil
+	}
+	return nil, FrozenError(fmt.Errorf("Cannot step into %s", frozenJson(v)))
+}
+
+func frozenSet(root FrozenValue, path string, v interface{}) (FrozenValue, error) {
+	x, e := freezeAny(v)
+	if e == nil && path == "" {
+		e = fmt.Errorf("Empty path")
+	}
+	if e == nil {
+		var r FrozenValue
+		if r, e = frozenUpdate(root, splitPath(path), func(FrozenValue) (FrozenValue, error) { return x, nil }); e == nil {
+			return r, nil
+		}
+	}
+	return nil, FrozenError(fmt.Errorf("Set(%+q): %v", path, e))
+}
+
+func frozenAppend(root FrozenValue, path string, v interface{}) (FrozenValue, error) {
+	x, e := freezeAny(v)
+	if e == nil {
+		var r FrozenValue
+		r, e = frozenUpdate(root, splitPath(path), func(old FrozenValue) (FrozenValue, error) {
+			if old == nil {
+				return &FrozenArray{[]FrozenValue{x}}, nil
+			}
+			a, ok := old.(*FrozenArray)
+			if !ok {
+				return nil, fmt.Errorf("%s is not an array", old.Json())
+			}
+			items := make([]FrozenValue, len(a.items), len(a.items)+1)
+			copy(items, a.items)
+			return &FrozenArray{append(items, x)}, nil
+		})
+		if e == nil {
+			return r, nil
+		}
+	}
+	return nil, FrozenError(fmt.Errorf("Append(%+q): %v", path, e))
+}
+
+func frozenDelete(root FrozenValue, path string) (FrozenValue, error) {
+	words := splitPath(path)
+	if len(words) == 0 {
+		return nil, FrozenError(fmt.Errorf("Delete(): Empty path"))
+	}
+	last := words[len(words)-1]
+	if _, e := frozenLookup(root, path); e != nil {
+		return nil, FrozenError(fmt.Errorf("Delete(%+q): %w", path, e))
+	}
+	r, e := frozenUpdate(root, words[:len(words)-1], func(parent FrozenValue) (FrozenValue, error) {
+		switch c := parent.(type) {
+		case *FrozenObject:
+			r := &FrozenObject{make(map[string]FrozenValue, len(c.members))}
+			for k, o := range c.members {
+				if k != last {
+					r.members[k] = o
+				}
+			}
+			return r, nil
+		case *FrozenArray:
+			i, _ := strconv.Atoi(last) // looked up already
+			if i < 0 {
+				i += len(c.items)
+			}
+			items := make([]FrozenValue, 0, len(c.items)-1)
+			items = append(items, c.items[:i]...)
+			return &FrozenArray{append(items, c.items[i+1:]...)}, nil
+		}
+		return nil, fmt.Errorf("Cannot step into %s", frozenJson(parent))
+	})
+	if e != nil {
+		return nil, FrozenError(fmt.Errorf("Delete(%+q): %v", path, e))
+	}
+	return r, nil
+}
@@ -0,0 +1,116 @@
+package json
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFrozen(t *testing.T) {
+	doc, _, err := ParseValue(`{ "host": { "name": "box", "disks": [ { "name": "sda" } ] }, "load": [ 0.5 ], "up": true }`)
+	if err != nil {
+		t.Fatalf("ParseValue(): %v", err)
+	}
+	v1, ok := Freeze(doc).(*FrozenObject)
+	if !ok {
+		t.Fatalf("Freeze(): %T", Freeze(doc))
+	}
+	if v1.Json() != doc.Json() || !v1.Thaw().Equal(doc) {
+		t.Errorf("Freeze(): %s", v1.Json())
+	}
+	// the frozen one is a copy
+	doc.(*JsonObject).Insert("up", NewJsonBool(false))
+	if up, _ := v1.Lookup("up"); up.(*FrozenScalar).Value() != true {
+		t.Errorf("Freeze() shares with the source")
+	}
+
+	v2, err := v1.Set("host.disks.0.used", 42)
+	if err != nil {
+		t.Fatalf("Set(): %v", err)
+	}
+	v3, err := v2.Append("load", 0.75)
+	if err != nil {
+		t.Fatalf("Append(): %v", err)
+	}
+	v4, err := v3.Delete("up")
+	if err != nil {
+		t.Fatalf("Delete(): %v", err)
+	}
+	v5, err := v4.Append("new.list", "x")
+	if err != nil {
+		t.Fatalf("Append(missing): %v", err)
+	}
+	versions := []struct {
+		v        *FrozenObject
+		expected string
+	}{
+		{v1, `{ "host": { "disks": [ { "name": "sda" } ], "name": "box" }, "load": [ 0.500000 ], "up": true }`},
+		{v2, `{ "host": { "disks": [ { "name": "sda", "used": 42 } ], "name": "box" }, "load": [ 0.500000 ], "up": true }`},
+		{v3, `{ "host": { "disks": [ { "name": "sda", "used": 42 } ], "name": "box" }, "load": [ 0.500000, 0.750000 ], "up": true }`},
+		{v4, `{ "host": { "disks": [ { "name": "sda", "used": 42 } ], "name": "box" }, "load": [ 0.500000, 0.750000 ] }`},
+		{v5, `{ "host": { "disks": [ { "name": "sda", "used": 42 } ], "name": "box" }, "load": [ 0.500000, 0.750000 ], "new": { "list": [ "x" ] } }`},
+	}
+	for i, x := range versions {
+		if x.v.Json() != x.expected {
+			t.Errorf("v%d: %s", i+1, x.v.Json())
+		}
+		if x.v.Thaw().Json() != x.expected {
+			t.Errorf("v%d.Thaw(): %s", i+1, x.v.Thaw().Json())
+		}
+	}
+
+	// the untouched subtrees are shared
+	same := func(a, b *FrozenObject, path string) bool {
+		x, _ := a.Lookup(path)
+		y, _ := b.Lookup(path)
+		return x == y
+	}
+	if !same(v1, v2, "load") || !same(v1, v2, "host.name") || same(v1, v2, "host.disks") {
+		t.Errorf("Set() sharing is wrong")
+	}
+	if !same(v2, v3, "host") || same(v2, v3, "load") || !same(v2, v3, "load.0") {
+		t.Errorf("Append() sharing is wrong")
+	}
+	if !same(v3, v4, "host") || !same(v3, v4, "load") {
+		t.Errorf("Delete() sharing is wrong")
+	}
+
+	// a frozen value set into another one is shared as well
+	v6, _ := v5.Set("copy", v1)
+	if x, _ := v6.Lookup("copy"); x != FrozenValue(v1) {
+		t.Errorf("Set(FrozenValue) does not share")
+	}
+
+	if !v4.Equal(Freeze(v4.Thaw())) || v4.Equal(v3) || !frozenEqual(nil, Freeze(NewJsonString(""))) {
+		t.Errorf("Equal() is wrong")
+	}
+
+	if _, err := v1.Set("up.x", 1); err == nil {
+		t.Errorf("Set(into scalar): no error")
+	}
+	if _, err := v1.Set("host.disks.5", 1); err == nil {
+		t.Errorf("Set(bad index): no error")
+	}
+	if _, err := v1.Delete("host.nothing"); !errors.Is(err, ErrMissing) {
+		t.Errorf("Delete(missing): %v", err)
+	}
+	if _, err := v1.Append("up", 1); err == nil {
+		t.Errorf("Append(scalar): no error")
+	}
+
+	a := Freeze(NewJsonArray([]JsonValue{NewJsonInt(1), nil, NewJsonInt(3)})).(*FrozenArray)
+	b, err := a.Delete("-1")
+	if err != nil || b.Json() != "[ 1, null ]" || a.Len() != 3 {
+		t.Errorf("FrozenArray.Delete() = %s, %v", frozenJson(b), err)
+	}
+	c, err := b.Append("", 4)
+	if err != nil || c.Json() != "[ 1, null, 4 ]" || b.Len() != 2 {
+		t.Errorf("FrozenArray.Append() = %s, %v", frozenJson(c), err)
+	}
+	c, err = c.Set("1", map[string]interface{}{"k": "v"})
+	if err != nil || c.Json() != `[ 1, { "k": "v" }, 4 ]` {
+		t.Errorf("FrozenArray.Set() = %s, %v", frozenJson(c), err)
+	}
+	if Freeze(new(JsonObject)) != nil || Freeze(nil) != nil {
+		t.Errorf("Freeze(null) is not nil")
+	}
+}
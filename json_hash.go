@@ -0,0 +1,185 @@
+// Hashing of JsonValue trees consistent with .Equal(), and a set of values
+package json
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// a 64-bit (FNV-1a) hash of the value: the .Equal() values hash the same
+// regardless of the member order (so do all the nulls)
+func Hash(v JsonValue) uint64 {
+	h := fnv.New64a()
+	hashValue(h, v)
+	return h.Sum64()
+}
+
+// like Hash(), but a SHA-256 one, for the content-keyed caches
+func CryptoHash(v JsonValue) [sha256.Size]byte {
+	var r [sha256.Size]byte
+	h := sha256.New()
+	hashValue(h, v)
+	copy(r[:], h.Sum(nil))
+	return r
+}
+
+// the type tags of the canonical form fed to the hash
+const (
+	hashNull byte = iota
+	hashFalse
+	hashTrue
+	hashInt
+	hashFloat
+	hashString
+	hashArray
+	hashObject
+)
+
+// feeds the canonical form of the value: a type tag, then the value itself;
+// strings and containers are prefixed with their lengths, object members go
+// by the sorted keys
+func hashValue(h hash.Hash, v JsonValue) {
+	var buf [8]byte
+	number := func(tag byte, u uint64) {
+		binary.BigEndian.PutUint64(buf[:], u)
+		h.Write([]byte{tag})
+		h.Write(buf[:])
+	}
+	text := func(tag byte, s string) {
+		number(tag, uint64(len(s)))
+		h.Write([]byte(s))
+	}
+	if isNullValue(v) {
+		h.Write([]byte{hashNull})
+		return
+	}
+	switch x := v.(type) {
+	case *JsonBool:
+		if *x {
+			h.Write([]byte{hashTrue})
+		} else {
+			h.Write([]byte{hashFalse})
+		}
+	case *JsonInt:
+		number(hashInt, uint64(*x))
+	case *JsonFloat:
+		f := float64(*x)
+		if f == 0 {
+			f = 0 // -0.0 == 0.0
+		}
+		number(hashFloat, math.Float64bits(f))
+	case *JsonString:
+		text(hashString, string(*x))
+	case *JsonArray:
+		number(hashArray, uint64(len(*x)))
+		for _, o := range *x {
+			hashValue(h, o)
+		}
+	case *JsonObject:
+		number(hashObject, uint64(len(*x)))
+		for _, k := range sortedKeys(*x) {
+			text(hashString, k)
+			hashValue(h, (*x)[k])
+		}
+	default:
+		text(hashString, v.Json()) // someone else's JsonValue
+	}
+}
+
+/*----------------------------------------------------------------------------*/
+
+// a set of unique (in the .Equal() sense) values; it keeps copies of the
+// values added and gives them back in the order they were added
+type JsonSet struct {
+	buckets map[uint64][]setEntry
+	size    int
+	seq     int
+}
+
+type setEntry struct {
+	value JsonValue
+	seq   int
+}
+
+// a set of the values given (the duplicates are dropped)
+func NewJsonSet(v ...JsonValue) *JsonSet {
+	r := &JsonSet{buckets: map[uint64][]setEntry{}}
+	for _, x := range v {
+		r.Add(x)
+	}
+	return r
+}
+
+func (self *JsonSet) find(v JsonValue) (uint64, int) {
+	h := Hash(v)
+	for i, x := range self.buckets[h] {
+		if equalValues(x.value, v) {
+			return h, i
+		}
+	}
+	return h, -1
+}
+
+// adds a copy of the value unless there is an equal one, tells if it was added
+func (self *JsonSet) Add(v JsonValue) bool {
+	if self.buckets == nil {
+		self.buckets = map[uint64][]setEntry{}
+	}
+	h, i := self.find(v)
+	if i >= 0 {
+		return false
+	}
+	self.buckets[h] = append(self.buckets[h], setEntry{deepCopy(v), self.seq})
+	self.seq++
+	self.size++
+	return true
+}
+
+// whether there is a value equal to v
+func (self *JsonSet) Has(v JsonValue) bool {
+	_, i := self.find(v)
+	return i >= 0
+}
+
+// removes the value equal to v, tells if there was one
+func (self *JsonSet) Remove(v JsonValue) bool {
+	h, i := self.find(v)
+	if i < 0 {
+		return false
+	}
+	b := self.buckets[h]
+	if len(b) == 1 {
+		delete(self.buckets, h)
+	} else {
+		self.buckets[h] = append(b[:i:i], b[i+1:]...)
+	}
+	self.size--
+	return true
+}
+
+// the number of values
+func (self *JsonSet) Len() int { return self.size }
+
+// the values in the order they were added
+func (self *JsonSet) Values() []JsonValue {
+	var entries []setEntry
+	for _, b := range self.buckets {
+		entries = append(entries, b...)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+	r := make([]JsonValue, len(entries))
+	for i, x := range entries {
+		r[i] = x.value
+	}
+	return r
+}
+
+// the values as a new array
+func (self *JsonSet) Array() *JsonArray {
+	r := JsonArray(self.Values())
+	return &r
+}
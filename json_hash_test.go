@@ -0,0 +1,79 @@
+package json
+
+import "testing"
+
+func TestHash(t *testing.T) {
+	same := [][2]string{
+		{`{ "a": 1, "b": [ true, "x" ] }`, `{ "b": [ true, "x" ], "a": 1 }`},
+		{`null`, `""`},
+		{`[ null, 1 ]`, `[ "", 1 ]`},
+		{`0.0`, `-0.0`},
+		{`{ "k": { "y": null, "x": 2.5 } }`, `{ "k": { "x": 2.5, "y": null } }`},
+	}
+	for _, x := range same {
+		a, b := mustParse(t, x[0]), mustParse(t, x[1])
+		if !equalValues(a, b) {
+			t.Errorf("%s != %s", x[0], x[1])
+		}
+		if Hash(a) != Hash(b) {
+			t.Errorf("Hash(%s) != Hash(%s)", x[0], x[1])
+		}
+		if CryptoHash(a) != CryptoHash(b) {
+			t.Errorf("CryptoHash(%s) != CryptoHash(%s)", x[0], x[1])
+		}
+	}
+	differ := [][2]string{
+		{`1`, `1.0`},
+		{`"1"`, `1`},
+		{`[ 1, 2 ]`, `[ 2, 1 ]`},
+		{`[ [ 1 ], 2 ]`, `[ [ 1, 2 ] ]`},
+		{`{ "a": "bc" }`, `{ "ab": "c" }`},
+		{`{ "a": null }`, `null`},
+		{`true`, `false`},
+	}
+	for _, x := range differ {
+		a, b := mustParse(t, x[0]), mustParse(t, x[1])
+		if Hash(a) == Hash(b) {
+			t.Errorf("Hash(%s) == Hash(%s)", x[0], x[1])
+		}
+		if CryptoHash(a) == CryptoHash(b) {
+			t.Errorf("CryptoHash(%s) == CryptoHash(%s)", x[0], x[1])
+		}
+	}
+	// map iteration order must not matter
+	o := mustParse(t, `{ "a": 1, "b": 2, "c": 3, "d": 4, "e": 5, "f": 6, "g": 7, "h": 8 }`)
+	h := Hash(o)
+	for i := 0; i < 20; i++ {
+		if Hash(o.Clone()) != h {
+			t.Fatalf("Hash() is unstable")
+		}
+	}
+
+	s := NewJsonSet(mustParse(t, `{ "a": 1, "b": 2 }`), mustParse(t, `3`), mustParse(t, `{ "b": 2, "a": 1 }`), nil, mustParse(t, `""`))
+	if s.Len() != 3 {
+		t.Errorf("NewJsonSet(): %d values: %s", s.Len(), s.Array().Json())
+	}
+	if s.Add(mustParse(t, `3`)) || !s.Add(mustParse(t, `3.0`)) {
+		t.Errorf("Add(): wrong")
+	}
+	if !s.Has(mustParse(t, `{ "b": 2, "a": 1 }`)) || s.Has(mustParse(t, `4`)) {
+		t.Errorf("Has(): wrong")
+	}
+	if s.Array().Json() != `[ { "a": 1, "b": 2 }, 3, null, 3.000000 ]` {
+		t.Errorf("Array(): %s", s.Array().Json())
+	}
+	if !s.Remove(mustParse(t, `3`)) || s.Remove(mustParse(t, `3`)) || s.Len() != 3 {
+		t.Errorf("Remove(): %s", s.Array().Json())
+	}
+	// the set keeps its own copies
+	v := mustParse(t, `[ 1 ]`)
+	s.Add(v)
+	v.Append(NewJsonInt(2))
+	if !s.Has(mustParse(t, `[ 1 ]`)) || s.Has(v) {
+		t.Errorf("Add() does not copy")
+	}
+	var zero JsonSet
+	if !zero.Add(mustParse(t, `1`)) || zero.Len() != 1 {
+		t.Errorf("zero JsonSet does not work")
+	}
+}
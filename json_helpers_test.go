@@ -0,0 +1,24 @@
+package json
+
+import "testing"
+
+// parses the whole of s or fails the test
+func mustParse(t *testing.T, s string) JsonValue {
+	t.Helper()
+	v, tail, e := ParseValue(s)
+	if e != nil || tail != "" {
+		t.Fatalf("ParseValue(%+q): %v (tail %+q)", s, e, tail)
+	}
+	return v
+}
+
+// fails the test unless f panics
+func mustPanic(t *testing.T, what string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s did not panic", what)
+		}
+	}()
+	f()
+}
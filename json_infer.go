@@ -0,0 +1,222 @@
+// JSON Schema inference from sample documents
+package json
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// how InferSchemaWith() generalizes the samples
+type InferOptions struct {
+	MaxEnum int // strings with at most this many distinct (repeated) values make an enum; 0 disables
+}
+
+var defaultInferOptions = InferOptions{MaxEnum: 8}
+
+// infers a schema from the samples with the default options, see InferSchemaWith()
+func InferSchema(samples ...JsonValue) *JsonObject {
+	return InferSchemaWith(samples, &defaultInferOptions)
+}
+
+// infers a (draft 2020-12) schema all the samples conform to: types are merged,
+// the members present in every sample are required, numbers get their ranges,
+// strings their lengths; JsonInt-only values are "integer", any JsonFloat makes it "number"
+func InferSchemaWith(samples []JsonValue, options *InferOptions) *JsonObject {
+	if options == nil {
+		options = &defaultInferOptions
+	}
+	root := newInferNode()
+	for _, s := range samples {
+		root.add(s, options)
+	}
+	schema := root.schema(options)
+	schema.Insert("$schema", NewJsonString("https://json-schema.org/draft/2020-12/schema"))
+	return schema
+}
+
+// what has been seen at one place of the samples
+type inferNode struct {
+	count int            // values seen here
+	types map[string]int // by schemaType()
+
+	min, max   float64 // numbers
+	imin, imax int64   // integers, exact where a float64 is not
+
+	strings      map[string]bool // distinct values, nil after there are too many
+	minLen       int
+	maxLen       int
+	stringsCount int
+
+	items              *inferNode // all the elements of all the arrays
+	minItems, maxItems int
+
+	objects    int // how many objects the properties come from
+	properties map[string]*inferNode
+}
+
+func newInferNode() *inferNode {
+	return &inferNode{types: make(map[string]int), strings: make(map[string]bool)}
+}
+
+func (self *inferNode) add(v JsonValue, options *InferOptions) {
+	t := schemaType(v)
+	first := self.types[t] == 0
+	self.count++
+	self.types[t]++
+
+	switch x := v.(type) {
+	case *JsonInt, *JsonFloat:
+		f, _ := pathNumber(x)
+		num := self.types["integer"] + self.types["number"]
+		if num == 1 || f < self.min {
+			self.min = f
+		}
+		if num == 1 || f > self.max {
+			self.max = f
+		}
+		if i, ok := x.(*JsonInt); ok {
+			n := int64(*i)
+			if self.types["integer"] == 1 || n < self.imin {
+				self.imin = n
+			}
+			if self.types["integer"] == 1 || n > self.imax {
+				self.imax = n
+			}
+		}
+	case *JsonString:
+		if t == "null" {
+			break
+		}
+		s := string(*x)
+		n := utf8.RuneCountInString(s)
+		if first || n < self.minLen {
+			self.minLen = n
+		}
+		if first || n > self.maxLen {
+			self.maxLen = n
+		}
+		self.stringsCount++
+		if self.strings != nil {
+			self.strings[s] = true
+			if len(self.strings) > options.MaxEnum {
+				self.strings = nil
+			}
+		}
+	case *JsonArray:
+		if t == "null" {
+			break
+		}
+		if first || len(*x) < self.minItems {
+			self.minItems = len(*x)
+		}
+		if first || len(*x) > self.maxItems {
+			self.maxItems = len(*x)
+		}
+		if self.items == nil {
+			self.items = newInferNode()
+		}
+		for _, o := range *x {
+			self.items.add(o, options)
+		}
+	case *JsonObject:
+		if t == "null" {
+			break
+		}
+		self.objects++
+		if self.properties == nil {
+			self.properties = make(map[string]*inferNode)
+		}
+		for k, o := range *x {
+			p, found := self.properties[k]
+			if !found {
+				p = newInferNode()
+				self.properties[k] = p
+			}
+			p.add(o, options)
+		}
+	}
+}
+
+func (self *inferNode) schema(options *InferOptions) *JsonObject {
+	schema := &JsonObject{}
+	var types []string
+	for t := range self.types {
+		if t == "integer" && self.types["number"] > 0 {
+			continue // integers are numbers as well
+		}
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	switch len(types) {
+	case 0:
+		return schema // nothing seen, anything goes
+	case 1:
+		schema.Insert("type", NewJsonString(types[0]))
+	default:
+		a := new(JsonArray)
+		for _, t := range types {
+			a.Append(NewJsonString(t))
+		}
+		schema.Insert("type", a)
+	}
+
+	if self.types["number"] > 0 {
+		schema.Insert("minimum", NewJsonFloat(self.min))
+		schema.Insert("maximum", NewJsonFloat(self.max))
+	} else if self.types["integer"] > 0 {
+		schema.Insert("minimum", NewJsonInt(self.imin))
+		schema.Insert("maximum", NewJsonInt(self.imax))
+	}
+
+	if self.stringsCount > 0 {
+		// few distinct values seen more than once make an enum, if there is nothing but strings
+		onlyStrings := len(types) == 1 || (len(types) == 2 && self.types["null"] > 0)
+		if onlyStrings && self.strings != nil && options.MaxEnum > 0 && len(self.strings) < self.stringsCount {
+			values := make([]string, 0, len(self.strings))
+			for s := range self.strings {
+				values = append(values, s)
+			}
+			sort.Strings(values)
+			enum := new(JsonArray)
+			for _, s := range values {
+				enum.Append(NewJsonString(s))
+			}
+			if self.types["null"] > 0 {
+				enum.Append(nil)
+			}
+			schema.Insert("enum", enum)
+		} else {
+			schema.Insert("minLength", NewJsonInt(self.minLen))
+			schema.Insert("maxLength", NewJsonInt(self.maxLen))
+		}
+	}
+
+	if self.items != nil {
+		schema.Insert("minItems", NewJsonInt(self.minItems))
+		schema.Insert("maxItems", NewJsonInt(self.maxItems))
+		if self.items.count > 0 {
+			schema.Insert("items", self.items.schema(options))
+		}
+	}
+
+	if self.properties != nil {
+		properties := &JsonObject{}
+		var required []string
+		for k, p := range self.properties {
+			properties.Insert(k, p.schema(options))
+			if p.count == self.objects {
+				required = append(required, k)
+			}
+		}
+		schema.Insert("properties", properties)
+		if len(required) > 0 {
+			sort.Strings(required)
+			a := new(JsonArray)
+			for _, k := range required {
+				a.Append(NewJsonString(k))
+			}
+			schema.Insert("required", a)
+		}
+	}
+	return schema
+}
@@ -0,0 +1,68 @@
+package json
+
+import "testing"
+
+func TestInferSchema(t *testing.T) {
+	var samples []JsonValue
+	for _, s := range []string{
+		`{ "host": "alpha", "kind": "agent", "uptime": 12, "load": [ 0.5, 1.25 ], "disks": [ { "name": "sda", "used": 95 } ] }`,
+		`{ "host": "beta", "kind": "probe", "uptime": 3, "load": [ 2.5 ], "disks": [ { "name": "nvme0", "used": 12 } ], "note": null }`,
+		`{ "host": "gamma", "kind": "agent", "uptime": 7.5, "load": [ 1, 2, 3 ], "note": "rebooted" }`,
+	} {
+		v, _, err := ParseValue(s)
+		if err != nil {
+			t.Fatalf("ParseValue(%+q): %v", s, err)
+		}
+		samples = append(samples, v)
+	}
+
+	schema := InferSchema(samples...)
+	expected := `{ "$schema": "https://json-schema.org/draft/2020-12/schema", ` +
+		`"properties": { ` +
+		`"disks": { "items": { "properties": { ` +
+		`"name": { "maxLength": 5, "minLength": 3, "type": "string" }, ` +
+		`"used": { "maximum": 95, "minimum": 12, "type": "integer" } }, ` +
+		`"required": [ "name", "used" ], "type": "object" }, "maxItems": 1, "minItems": 1, "type": "array" }, ` +
+		`"host": { "maxLength": 5, "minLength": 4, "type": "string" }, ` +
+		`"kind": { "enum": [ "agent", "probe" ], "type": "string" }, ` +
+		`"load": { "items": { "maximum": 3.000000, "minimum": 0.500000, "type": "number" }, "maxItems": 3, "minItems": 1, "type": "array" }, ` +
+		`"note": { "maxLength": 8, "minLength": 8, "type": [ "null", "string" ] }, ` +
+		`"uptime": { "maximum": 12.000000, "minimum": 3.000000, "type": "number" } }, ` +
+		`"required": [ "host", "kind", "load", "uptime" ], "type": "object" }`
+	if schema.Json() != expected {
+		t.Errorf("InferSchema():\n%s\nnot\n%s", schema.Json(), expected)
+	}
+
+	compiled, err := CompileSchema(schema)
+	if err != nil {
+		t.Fatalf("CompileSchema(%s): %v", schema.Json(), err)
+	}
+	for _, s := range samples {
+		if v := compiled.Validate(s); v != nil {
+			t.Errorf("Validate(%s): %v", s.Json(), v)
+		}
+	}
+
+	// no enums without repetitions or when disabled
+	a, b := NewJsonString("a"), NewJsonString("b")
+	if s := InferSchema(a, b).Json(); s != `{ "$schema": "https://json-schema.org/draft/2020-12/schema", "maxLength": 1, "minLength": 1, "type": "string" }` {
+		t.Errorf("InferSchema(a, b) = %s", s)
+	}
+	if s := InferSchemaWith([]JsonValue{a, a, nil}, &InferOptions{}).Json(); s != `{ "$schema": "https://json-schema.org/draft/2020-12/schema", "maxLength": 1, "minLength": 1, "type": [ "null", "string" ] }` {
+		t.Errorf("InferSchemaWith(a, a, null) = %s", s)
+	}
+	if s := InferSchemaWith([]JsonValue{a, a, nil}, nil).Json(); s != `{ "$schema": "https://json-schema.org/draft/2020-12/schema", "enum": [ "a", null ], "type": [ "null", "string" ] }` {
+		t.Errorf("InferSchemaWith(a, a, null) = %s", s)
+	}
+	if s := InferSchema().Json(); s != `{ "$schema": "https://json-schema.org/draft/2020-12/schema" }` {
+		t.Errorf("InferSchema() = %s", s)
+	}
+
+	// the integer bounds are exact past 2^53
+	big := NewJsonInt(int64(1<<53 + 1))
+	if s := InferSchema(big).Json(); s != `{ "$schema": "https://json-schema.org/draft/2020-12/schema", "maximum": 9007199254740993, "minimum": 9007199254740993, "type": "integer" }` {
+		t.Errorf("InferSchema(2^53+1) = %s", s)
+	} else if compiled, err := CompileSchema(InferSchema(big)); err != nil || !compiled.IsValid(big) {
+		t.Errorf("CompileSchema(%s): %v", s, err)
+	}
+}
@@ -0,0 +1,68 @@
+// JSON Merge Patch (RFC 7386) application and generation
+package json
+
+// the merge patch "null" is whatever this library considers null: an untyped
+// nil as well as any JsonValue that .IsNull() (note the empty string!)
+func isNullValue(v JsonValue) bool { return v == nil || v.IsNull() }
+
+// applies the merge patch to a copy of target: objects are merged recursively,
+// null members delete keys and anything else replaces the target
+func MergePatch(target, patch JsonValue) JsonValue {
+	return mergePatch(deepCopy(target), patch)
+}
+
+// target is owned by the caller and may be reused for the result
+func mergePatch(target, patch JsonValue) JsonValue {
+	p, ok := patch.(*JsonObject)
+	if !ok || p.IsNull() {
+		return deepCopy(patch)
+	}
+	t, ok := target.(*JsonObject)
+	if !ok || t.IsNull() {
+		t = &JsonObject{}
+	}
+	for k, v := range *p {
+		if isNullValue(v) {
+			delete(*t, k)
+			continue
+		}
+		t.Insert(k, mergePatch((*t)[k], v))
+	}
+	return t
+}
+
+// generates the minimal merge patch turning from into to, so that
+// MergePatch(from, CreateMergePatch(from, to)) is equal to the to;
+// the members of to that are null cannot survive a merge patch and get dropped
+func CreateMergePatch(from, to JsonValue) JsonValue {
+	f, fok := from.(*JsonObject)
+	t, tok := to.(*JsonObject)
+	if !fok || !tok || f.IsNull() || t.IsNull() {
+		if !tok || t.IsNull() {
+			return deepCopy(to)
+		}
+		f = &JsonObject{}
+	}
+	patch := &JsonObject{}
+	for k, v := range *f {
+		if o, found := (*t)[k]; (!found || isNullValue(o)) && !isNullValue(v) {
+			patch.Insert(k, nil)
+		}
+	}
+	for k, v := range *t {
+		if isNullValue(v) {
+			continue
+		}
+		o := (*f)[k]
+		if equalValues(o, v) {
+			continue
+		}
+		_, vok := v.(*JsonObject)
+		if _, ook := o.(*JsonObject); ook && vok && !isNullValue(o) {
+			patch.Insert(k, CreateMergePatch(o, v))
+		} else {
+			patch.Insert(k, CreateMergePatch(nil, v))
+		}
+	}
+	return patch
+}
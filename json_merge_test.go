@@ -0,0 +1,53 @@
+package json
+
+import "testing"
+
+func TestMergePatch(t *testing.T) {
+	// RFC 7386, Appendix A (but for the empty objects, which are nulls here)
+	test := func(target, patch, expected string) {
+		o, p := mustParse(t, target), mustParse(t, patch)
+		before := jsonOf(o)
+		r := MergePatch(o, p)
+		if !equalValues(r, mustParse(t, expected)) {
+			t.Errorf("MergePatch(%s, %s) = %s, not %s", target, patch, jsonOf(r), expected)
+		}
+		if jsonOf(o) != before {
+			t.Errorf("MergePatch(%s, %s) altered the target: %s", target, patch, jsonOf(o))
+		}
+		if d := CreateMergePatch(o, r); !equalValues(MergePatch(o, d), r) {
+			t.Errorf("CreateMergePatch(%s, %s) = %s: does not apply", target, jsonOf(r), jsonOf(d))
+		}
+	}
+
+	test(`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`)
+	test(`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`)
+	test(`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`)
+	test(`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`)
+	test(`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`)
+	test(`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`)
+	test(`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`)
+	test(`["a","b"]`, `["c","d"]`, `["c","d"]`)
+	test(`{"a":"b"}`, `["c"]`, `["c"]`)
+	test(`{"a":"foo"}`, `null`, `null`)
+	test(`{"a":"foo"}`, `"bar"`, `"bar"`)
+	test(`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`)
+	test(`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`)
+	test(`{"a":1}`, `{"a":{"bb":{"ccc":null,"d":2},"x":1}}`, `{"a":{"bb":{"d":2},"x":1}}`)
+	test(`{"title":"Hello!","author":{"givenName":"John","familyName":"Doe"},"tags":["example","sample"],"content":"This will be unchanged"}`,
+		`{"title":"Hello!","phoneNumber":"+01-123-456-7890","author":{"familyName":null},"tags":["example"]}`,
+		`{"title":"Hello!","author":{"givenName":"John"},"tags":["example"],"content":"This will be unchanged","phoneNumber":"+01-123-456-7890"}`)
+
+	if r := MergePatch(mustParse(t, `{"a":"b"}`), mustParse(t, `{"a":null}`)); r.Json() != "{  }" {
+		t.Errorf("MergePatch(%s, %s) = %s, not empty", `{"a":"b"}`, `{"a":null}`, r.Json())
+	}
+
+	// the minimal patch only mentions what differs
+	from := mustParse(t, `{"a":1,"b":{"c":2,"d":3},"e":4}`)
+	to := mustParse(t, `{"a":1,"b":{"c":2,"d":5},"f":6}`)
+	if d := CreateMergePatch(from, to); d.Json() != `{ "b": { "d": 5 }, "e": null, "f": 6 }` {
+		t.Errorf("CreateMergePatch(%s, %s) = %s", from.Json(), to.Json(), d.Json())
+	}
+	if d := CreateMergePatch(from, from); !d.(*JsonObject).IsNull() && len(*d.(*JsonObject)) != 0 {
+		t.Errorf("CreateMergePatch(%s, self) = %s", from.Json(), d.Json())
+	}
+}
@@ -0,0 +1,156 @@
+// Coercion of the Go numeric types (and numeric strings) into the JSON ones
+package json
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+type NumberError error
+
+// every integer up to this one in magnitude is exact in float64
+const maxExact = 1 << 53
+
+// converts any Go integer or float kind, or a numeric string (like json.Number),
+// to int; fails on overflow and on a fractional part rather than truncating
+func ToInt(v interface{}) (int, error) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if r.Int() < int64(minInt) || r.Int() > int64(maxInt) {
+			return 0, NumberError(fmt.Errorf("%v overflows int", v))
+		}
+		return int(r.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if r.Uint() > uint64(maxInt) {
+			return 0, NumberError(fmt.Errorf("%v overflows int", v))
+		}
+		return int(r.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return floatToInt(r.Float())
+	case reflect.String:
+		return parseInt(r.String())
+	}
+	return 0, NumberError(fmt.Errorf("Cannot convert %T to int", v))
+}
+
+// converts any Go integer or float kind, or a numeric string (like json.Number),
+// to float64; fails on integers float64 cannot hold exactly, on NaN and infinities
+func ToFloat(v interface{}) (float64, error) {
+	r := reflect.ValueOf(v)
+	switch r.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		u := uint64(r.Int())
+		if r.Int() < 0 {
+			u = -u
+		}
+		if !exactFloat(u) {
+			return 0, NumberError(fmt.Errorf("%v loses precision as float", v))
+		}
+		return float64(r.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if !exactFloat(r.Uint()) {
+			return 0, NumberError(fmt.Errorf("%v loses precision as float", v))
+		}
+		return float64(r.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		f := r.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, NumberError(fmt.Errorf("%v is not a JSON number", v))
+		}
+		return f, nil
+	case reflect.String:
+		return parseFloat(r.String())
+	}
+	return 0, NumberError(fmt.Errorf("Cannot convert %T to float", v))
+}
+
+// whether the (absolute) integer survives the float64 round trip
+func exactFloat(u uint64) bool {
+	for u > maxExact && u&1 == 0 {
+		u >>= 1
+	}
+	return u <= maxExact
+}
+
+func floatToInt(f float64) (int, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) || f != math.Trunc(f) {
+		return 0, NumberError(fmt.Errorf("%v is not an integer", f))
+	}
+	if f < float64(minInt) || f >= -float64(minInt) {
+		return 0, NumberError(fmt.Errorf("%v overflows int", f))
+	}
+	return int(f), nil
+}
+
+// an integer literal, or a number literal with an integral value ("1e3")
+func parseInt(s string) (int, error) {
+	if !isNumberLiteral(s) {
+		return 0, NumberError(fmt.Errorf("Bad number %+q", s))
+	}
+	i, e := strconv.ParseInt(s, 10, 0)
+	if e == nil {
+		return int(i), nil
+	}
+	if errors.Is(e, strconv.ErrRange) {
+		return 0, NumberError(fmt.Errorf("%s overflows int", s))
+	}
+	f, e := parseFloat(s)
+	if e != nil {
+		return 0, e
+	}
+	if math.Abs(f) > maxExact {
+		return 0, NumberError(fmt.Errorf("%s loses precision as int", s))
+	}
+	return floatToInt(f)
+}
+
+func parseFloat(s string) (float64, error) {
+	if !isNumberLiteral(s) {
+		return 0, NumberError(fmt.Errorf("Bad number %+q", s))
+	}
+	f, e := strconv.ParseFloat(s, 64)
+	if e != nil {
+		return 0, NumberError(fmt.Errorf("%s overflows float", s))
+	}
+	return f, nil
+}
+
+// the JSON number grammar: -?int(.digits)?([eE][+-]?digits)?
+func isNumberLiteral(s string) bool {
+	i := 0
+	digits := func() bool {
+		j := i
+		for i < len(s) && isDigit(s[i]) {
+			i++
+		}
+		return i > j
+	}
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	if i < len(s) && s[i] == '0' {
+		i++
+	} else if !digits() {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if !digits() {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if !digits() {
+			return false
+		}
+	}
+	return i == len(s)
+}
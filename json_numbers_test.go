@@ -0,0 +1,123 @@
+package json
+
+import (
+	"math"
+	"testing"
+)
+
+type number string // like json.Number
+
+func TestNumbers(t *testing.T) {
+	ints := []struct {
+		v        interface{}
+		expected int
+		fails    bool
+	}{
+		{int8(-8), -8, false},
+		{uint(7), 7, false},
+		{uint8(255), 255, false},
+		{uint32(1 << 31), 1 << 31, false},
+		{uint64(maxInt), maxInt, false},
+		{uint64(maxInt) + 1, 0, true},
+		{uintptr(12), 12, false},
+		{3.0, 3, false},
+		{float32(-2), -2, false},
+		{3.5, 0, true},
+		{1e300, 0, true},
+		{math.NaN(), 0, true},
+		{"42", 42, false},
+		{"-1e3", -1000, false},
+		{"1.25e2", 125, false},
+		{"1.5", 0, true},
+		{"99999999999999999999", 0, true},
+		{"1e30", 0, true},
+		{"0x10", 0, true},
+		{" 1", 0, true},
+		{number("17"), 17, false},
+		{true, 0, true},
+		{nil, 0, true},
+	}
+	for _, x := range ints {
+		r, e := ToInt(x.v)
+		if x.fails && e == nil {
+			t.Errorf("ToInt(%T(%v)) = %d, no error", x.v, x.v, r)
+		} else if !x.fails && (e != nil || r != x.expected) {
+			t.Errorf("ToInt(%T(%v)) = %d, %v", x.v, x.v, r, e)
+		}
+		if _, ok := e.(NumberError); e != nil && !ok {
+			t.Errorf("ToInt(%T(%v)): %T is not a NumberError", x.v, x.v, e)
+		}
+	}
+
+	floats := []struct {
+		v        interface{}
+		expected float64
+		fails    bool
+	}{
+		{float32(0.5), 0.5, false},
+		{7, 7, false},
+		{int64(-1 << 53), -1 << 53, false},
+		{int64(1<<53 + 1), 0, true},
+		{int64(1 << 62), 1 << 62, false},
+		{int64(math.MinInt64), math.MinInt64, false},
+		{uint64(math.MaxUint64), 0, true},
+		{uint64(1 << 63), 1 << 63, false},
+		{math.Inf(1), 0, true},
+		{"-2.5e-1", -0.25, false},
+		{"1e400", 0, true},
+		{"NaN", 0, true},
+		{"1.", 0, true},
+		{number("0.125"), 0.125, false},
+		{"x", 0, true},
+	}
+	for _, x := range floats {
+		r, e := ToFloat(x.v)
+		if x.fails && e == nil {
+			t.Errorf("ToFloat(%T(%v)) = %v, no error", x.v, x.v, r)
+		} else if !x.fails && (e != nil || r != x.expected) {
+			t.Errorf("ToFloat(%T(%v)) = %v, %v", x.v, x.v, r, e)
+		}
+	}
+
+	// the values accept the same
+	var m struct{ HeapAlloc, Frees uint64 }
+	m.HeapAlloc = 1 << 40
+	if i := NewJsonInt(m.HeapAlloc); i.Json() != "1099511627776" {
+		t.Errorf("NewJsonInt(uint64) = %s", i.Json())
+	}
+	if f := NewJsonFloat(m.Frees); f.Json() != "0.000000" {
+		t.Errorf("NewJsonFloat(uint64) = %s", f.Json())
+	}
+	if i := NewJsonInt(number("5")); i.Json() != "5" {
+		t.Errorf("NewJsonInt(number) = %s", i.Json())
+	}
+	i := NewJsonInt(1)
+	if e := i.TrySet(uint64(math.MaxUint64)); e == nil || i.Json() != "1" {
+		t.Errorf("JsonInt.TrySet(MaxUint64) = %v, %s", e, i.Json())
+	}
+	if e := i.Parse("2e2"); e != nil || i.Json() != "200" {
+		t.Errorf("JsonInt.Parse(2e2) = %v, %s", e, i.Json())
+	}
+	f := NewJsonFloat(1.0)
+	if e := f.TrySet(math.NaN()); e == nil || f.Json() != "1.000000" {
+		t.Errorf("JsonFloat.TrySet(NaN) = %v, %s", e, f.Json())
+	}
+	for _, s := range []string{"0x1p4", "Inf", "-Inf", "NaN", "1_000.5", "+1.5", ".5", "1.", "01.5", "1e", "1e999"} {
+		if e := f.Parse(s); e == nil || f.Json() != "1.000000" {
+			t.Errorf("JsonFloat.Parse(%+q) = %v, %s", s, e, f.Json())
+		}
+	}
+	if e := f.Parse("-0.5e1"); e != nil || f.Json() != "-5.000000" {
+		t.Errorf("JsonFloat.Parse(-0.5e1) = %v, %s", e, f.Json())
+	}
+
+	// and so do the conversions: errors, not panics
+	for _, v := range []interface{}{math.NaN(), math.Inf(1), float32(math.Inf(-1)), []interface{}{1, math.NaN()}} {
+		if r, e := ToJsonValue(v); e == nil {
+			t.Errorf("ToJsonValue(%v) = %v, no error", v, r)
+		}
+	}
+	if e := NewBuilder().Set("a.b", math.NaN()).Err(); e == nil {
+		t.Errorf("Builder.Set(NaN): no error")
+	}
+}
@@ -0,0 +1,175 @@
+// Editing operations on JsonObject
+package json
+
+import (
+	"errors"
+	"fmt"
+)
+
+// what Rename() and the MergeError strategy fail with
+var ErrExists = errors.New("existing value")
+
+// the number of members, 0 for null
+func (self *JsonObject) Len() int {
+	if self.IsNull() {
+		return 0
+	}
+	return len(*self)
+}
+
+// whether there is such a member (a null one counts)
+func (self *JsonObject) Has(key string) bool {
+	if self.IsNull() {
+		return false
+	}
+	_, found := (*self)[key]
+	return found
+}
+
+// removes the member, tells if it was there
+func (self *JsonObject) Delete(key string) bool {
+	if !self.Has(key) {
+		return false
+	}
+	delete(*self, key)
+	return true
+}
+
+// the member names, sorted
+func (self *JsonObject) Keys() []string {
+	if self.IsNull() {
+		return []string{}
+	}
+	return sortedKeys(*self)
+}
+
+// renames the member; there must be no member with the new name
+func (self *JsonObject) Rename(from, to string) error {
+	if !self.Has(from) {
+		return memberError(from, ErrMissing)
+	}
+	if from == to {
+		return nil
+	}
+	if self.Has(to) {
+		return memberError(to, ErrExists)
+	}
+	(*self)[to] = (*self)[from]
+	delete(*self, from)
+	return nil
+}
+
+// a new object of the members named (the missing ones are ignored); members are shared
+func (self *JsonObject) Pick(keys ...string) *JsonObject {
+	r := JsonObject{}
+	for _, k := range keys {
+		if self.Has(k) {
+			r[k] = (*self)[k]
+		}
+	}
+	return &r
+}
+
+// a new object of the members not named; members are shared
+func (self *JsonObject) Omit(keys ...string) *JsonObject {
+	r := JsonObject{}
+	for _, k := range self.Keys() {
+		r[k] = (*self)[k]
+	}
+	for _, k := range keys {
+		delete(r, k)
+	}
+	return &r
+}
+
+// what to do when both objects have a member with the same name
+type MergeStrategy int
+
+const (
+	MergeOverwrite MergeStrategy = iota // the other one wins
+	MergeKeep                           // this one stays
+	MergeConcat                         // two arrays are concatenated, the other one wins otherwise
+	MergeError                          // the merge fails with ErrExists (and changes nothing)
+)
+
+// merges the other object's members into this one; the members are shared
+func (self *JsonObject) Merge(other *JsonObject, strategy MergeStrategy) error {
+	return self.merge(other, strategy, false)
+}
+
+// like Merge(), but the members that are objects on both sides are merged
+// recursively; the members of the other object are copied
+func (self *JsonObject) DeepMerge(other *JsonObject, strategy MergeStrategy) error {
+	return self.merge(other, strategy, true)
+}
+
+func (self *JsonObject) merge(other *JsonObject, strategy MergeStrategy, deep bool) error {
+	if strategy == MergeError {
+		if e := self.conflict(other, deep); e != nil {
+			return fmt.Errorf("cannot merge: %w", e)
+		}
+	}
+	for _, k := range other.Keys() {
+		v := (*other)[k]
+		if deep {
+			v = deepCopy(v)
+		}
+		if !self.Has(k) {
+			if e := self.TryInsert(k, v); e != nil {
+				return memberError(k, e)
+			}
+			continue
+		}
+		old := (*self)[k]
+		if deep {
+			a, aok := old.(*JsonObject)
+			b, bok := v.(*JsonObject)
+			if aok && bok && !a.IsNull() && !b.IsNull() {
+				if e := a.merge(b, strategy, deep); e != nil {
+					return memberError(k, e)
+				}
+				continue
+			}
+		}
+		switch strategy {
+		case MergeKeep:
+			continue
+		case MergeConcat:
+			a, aok := old.(*JsonArray)
+			b, bok := v.(*JsonArray)
+			if aok && bok {
+				c := make(JsonArray, 0, a.Len()+b.Len())
+				if !a.IsNull() {
+					c = append(c, *a...)
+				}
+				if !b.IsNull() {
+					c = append(c, *b...)
+				}
+				v = &c
+			}
+		}
+		if e := self.TryInsert(k, v); e != nil {
+			return memberError(k, e)
+		}
+	}
+	return nil
+}
+
+// the first member both objects have (and cannot be merged recursively)
+func (self *JsonObject) conflict(other *JsonObject, deep bool) error {
+	for _, k := range other.Keys() {
+		if !self.Has(k) {
+			continue
+		}
+		a, aok := (*self)[k].(*JsonObject)
+		b, bok := (*other)[k].(*JsonObject)
+		if deep && aok && bok && !a.IsNull() && !b.IsNull() {
+			if e := a.conflict(b, deep); e != nil {
+				return memberError(k, e)
+			}
+			continue
+		}
+		return memberError(k, ErrExists)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package json
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestObjectEditing(t *testing.T) {
+	parse := func(s string) *JsonObject { return mustParse(t, s).(*JsonObject) }
+	expect := func(what string, o *JsonObject, expected string) {
+		if o.Json() != expected {
+			t.Errorf("%s: %s, not %s", what, o.Json(), expected)
+		}
+	}
+
+	o := parse(`{ "b": 1, "a": null, "c": [ 1 ] }`)
+	if o.Len() != 3 || new(JsonObject).Len() != 0 {
+		t.Errorf("Len(): %d", o.Len())
+	}
+	if !o.Has("a") || o.Has("x") || new(JsonObject).Has("a") {
+		t.Errorf("Has(): wrong")
+	}
+	if k := strings.Join(o.Keys(), ","); k != "a,b,c" {
+		t.Errorf("Keys(): %s", k)
+	}
+	if !o.Delete("a") || o.Delete("a") || o.Len() != 2 {
+		t.Errorf("Delete(): %s", o.Json())
+	}
+
+	if e := o.Rename("b", "d"); e != nil {
+		t.Errorf("Rename(): %v", e)
+	}
+	expect("Rename()", o, `{ "c": [ 1 ], "d": 1 }`)
+	if e := o.Rename("x", "y"); !errors.Is(e, ErrMissing) {
+		t.Errorf("Rename(missing): %v", e)
+	}
+	if e := o.Rename("c", "d"); !errors.Is(e, ErrExists) {
+		t.Errorf("Rename(existing): %v", e)
+	}
+
+	o = parse(`{ "a": 1, "b": 2, "c": 3 }`)
+	expect("Pick()", o.Pick("c", "a", "x"), `{ "a": 1, "c": 3 }`)
+	expect("Omit()", o.Omit("c", "a", "x"), `{ "b": 2 }`)
+	expect("Pick() and Omit() source", o, `{ "a": 1, "b": 2, "c": 3 }`)
+
+	const base = `{ "a": 1, "l": [ 1 ], "n": { "x": 1, "y": [ 2 ] } }`
+	const other = `{ "a": 2, "l": [ 3 ], "n": { "y": [ 4 ], "z": 5 }, "new": true }`
+	merges := []struct {
+		strategy MergeStrategy
+		deep     bool
+		expected string
+	}{
+		{MergeOverwrite, false, `{ "a": 2, "l": [ 3 ], "n": { "y": [ 4 ], "z": 5 }, "new": true }`},
+		{MergeKeep, false, `{ "a": 1, "l": [ 1 ], "n": { "x": 1, "y": [ 2 ] }, "new": true }`},
+		{MergeConcat, false, `{ "a": 2, "l": [ 1, 3 ], "n": { "y": [ 4 ], "z": 5 }, "new": true }`},
+		{MergeOverwrite, true, `{ "a": 2, "l": [ 3 ], "n": { "x": 1, "y": [ 4 ], "z": 5 }, "new": true }`},
+		{MergeKeep, true, `{ "a": 1, "l": [ 1 ], "n": { "x": 1, "y": [ 2 ], "z": 5 }, "new": true }`},
+		{MergeConcat, true, `{ "a": 2, "l": [ 1, 3 ], "n": { "x": 1, "y": [ 2, 4 ], "z": 5 }, "new": true }`},
+	}
+	for _, m := range merges {
+		o, p := parse(base), parse(other)
+		var e error
+		if m.deep {
+			e = o.DeepMerge(p, m.strategy)
+		} else {
+			e = o.Merge(p, m.strategy)
+		}
+		if e != nil {
+			t.Errorf("merge(%v, %v): %v", m.strategy, m.deep, e)
+		}
+		expect("merge()", o, m.expected)
+		expect("merge() source", p, parse(other).Json())
+	}
+
+	o = parse(base)
+	if e := o.Merge(parse(`{ "new": 1, "a": 1 }`), MergeError); !errors.Is(e, ErrExists) {
+		t.Errorf("Merge(MergeError): %v", e)
+	}
+	if e := o.DeepMerge(parse(`{ "new": 1, "n": { "x": 2 } }`), MergeError); !errors.Is(e, ErrExists) || !strings.Contains(e.Error(), `"n"`) {
+		t.Errorf("DeepMerge(MergeError): %v", e)
+	}
+	expect("failed merges", o, parse(base).Json())
+	if e := o.DeepMerge(parse(`{ "new": 1, "n": { "w": 2 } }`), MergeError); e != nil {
+		t.Errorf("DeepMerge(MergeError): %v", e)
+	}
+	expect("DeepMerge(MergeError)", o, `{ "a": 1, "l": [ 1 ], "n": { "w": 2, "x": 1, "y": [ 2 ] }, "new": 1 }`)
+
+	// deep merge copies, shallow one shares
+	o, p := &JsonObject{}, parse(`{ "k": { "v": 1 } }`)
+	o.DeepMerge(p, MergeOverwrite)
+	(*p)["k"].Insert("v", NewJsonInt(2))
+	expect("DeepMerge() copies", o, `{ "k": { "v": 1 } }`)
+	o.Merge(p, MergeOverwrite)
+	(*p)["k"].Insert("v", NewJsonInt(3))
+	expect("Merge() shares", o, `{ "k": { "v": 3 } }`)
+}
@@ -43,6 +43,11 @@ func parseObject(s string) (v JsonValue, t string, e error) {
 	}
 	v = new(JsonObject)
 	t = strings.TrimSpace(s[1:])
+	if t != "" && t[0] == '}' {
+		v = &JsonObject{}
+		t = strings.TrimSpace(t[1:])
+		return
+	}
 	ok := false
 	for t != "" {
 		if t[0] != '"' {
@@ -72,7 +77,7 @@ func parseObject(s string) (v JsonValue, t string, e error) {
 			t = xt
 			return
 		}
-		v.Insert(name, xv)
+		insertParsed(v.(*JsonObject), name, xv)
 
 		t = strings.TrimSpace(xt)
 		if t == "" {
@@ -99,6 +104,16 @@ func parseObject(s string) (v JsonValue, t string, e error) {
 	}
 	return
 }
+
+// the parsed values are fresh and shared with nothing, they cannot make a
+// cycle: no need for the checks (and the walks) of .Insert() and .Append()
+func insertParsed(o *JsonObject, name string, v JsonValue) {
+	if *o == nil {
+		*o = make(JsonObject)
+	}
+	(*o)[name] = v
+}
+func appendParsed(a *JsonArray, v JsonValue) { *a = append(*a, v) }
 func parseArray(s string) (v JsonValue, t string, e error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -112,6 +127,11 @@ func parseArray(s string) (v JsonValue, t string, e error) {
 	}
 	v = new(JsonArray)
 	t = strings.TrimSpace(s[1:])
+	if t != "" && t[0] == ']' {
+		v = &JsonArray{}
+		t = strings.TrimSpace(t[1:])
+		return
+	}
 	ok := false
 	for t != "" {
 		xv, xt, xe := ParseValue(t)
@@ -120,7 +140,7 @@ func parseArray(s string) (v JsonValue, t string, e error) {
 			t = xt
 			return
 		}
-		v.Append(xv)
+		appendParsed(v.(*JsonArray), xv)
 
 		t = strings.TrimSpace(xt)
 		if t == "" {
@@ -242,15 +262,48 @@ func parseNumber(s string) (v JsonValue, t string, e error) {
 		frac += string(t[0])
 		t = t[1:]
 	}
+	exp := ""
+	if t != "" && (t[0] == 'e' || t[0] == 'E') {
+		isFloat = true
+		exp = "e"
+		t = t[1:]
+		if t != "" && (t[0] == '+' || t[0] == '-') {
+			exp += string(t[0])
+			t = t[1:]
+		}
+		for t != "" && isDigit(t[0]) {
+			exp += string(t[0])
+			t = t[1:]
+		}
+	}
 	if isFloat {
 		v = new(JsonFloat)
-		e = v.Parse(intPart + "." + frac)
+		e = v.Parse(floatLiteral(intPart, frac, exp, s[:len(s)-len(t)]))
 	} else {
 		v = new(JsonInt)
 		e = v.Parse(intPart)
 	}
 	return
 }
+
+// the lenient float syntax ("+01.", ".5") as a JSON number literal, or raw
+// (to fail) if there are no digits
+func floatLiteral(intPart, frac, exp, raw string) string {
+	sign, digits := "", strings.TrimLeft(intPart, "+")
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if digits == "" && frac == "" {
+		return raw
+	}
+	if digits = strings.TrimLeft(digits, "0"); digits == "" {
+		digits = "0"
+	}
+	if frac == "" {
+		frac = "0"
+	}
+	return sign + digits + "." + frac + exp
+}
 func parseBool(s string) (v JsonValue, t string, e error) {
 	s = strings.TrimSpace(s)
 	if s == "" {
@@ -305,6 +358,7 @@ func ParseValue(s string) (v JsonValue, t string, e error) {
 	case 'n':
 		v, t, e = parseNull(s)
 	default:
+		t = s
 		e = BadValue(fmt.Errorf("%+q is not a value for '%c'=%#v", s, s[0], s[0]))
 		return
 	}
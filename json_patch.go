@@ -0,0 +1,202 @@
+// JSON Patch (RFC 6902) application
+package json
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PatchError error
+
+// applies the patch (an array of operation objects) to a copy of doc;
+// the doc itself is never touched, and on error no result is returned
+func ApplyPatch(doc JsonValue, patch *JsonArray) (JsonValue, error) {
+	root := deepCopy(doc)
+	if patch.IsNull() {
+		return root, nil
+	}
+	for i, x := range *patch {
+		op, ok := x.(*JsonObject)
+		if !ok || op.IsNull() {
+			return nil, PatchError(fmt.Errorf("Patch operation #%d is not an object: %s", i, jsonOf(x)))
+		}
+		if e := applyPatchOperation(&root, op); e != nil {
+			return nil, PatchError(fmt.Errorf("Patch operation #%d %s: %v", i, op.Json(), e))
+		}
+	}
+	return root, nil
+}
+
+// fetches a mandatory string member of the operation object
+func patchString(op *JsonObject, name string) (string, error) {
+	x, found := (*op)[name]
+	if !found {
+		return "", fmt.Errorf("No %+q member", name)
+	}
+	s, ok := x.(*JsonString)
+	if !ok || s == nil {
+		return "", fmt.Errorf("Member %+q is not a string", name)
+	}
+	return string(*s), nil
+}
+
+// fetches a mandatory pointer member of the operation object
+func patchPointer(op *JsonObject, name string) (string, []string, error) {
+	p, e := patchString(op, name)
+	if e != nil {
+		return "", nil, e
+	}
+	tokens, e := splitPointer(p)
+	return p, tokens, e
+}
+
+func applyPatchOperation(root *JsonValue, op *JsonObject) error {
+	name, e := patchString(op, "op")
+	if e != nil {
+		return e
+	}
+	path, tokens, e := patchPointer(op, "path")
+	if e != nil {
+		return e
+	}
+	value, hasValue := (*op)["value"]
+
+	switch name {
+	case "add":
+		if !hasValue {
+			return fmt.Errorf("No %+q member", "value")
+		}
+		return patchAdd(root, tokens, deepCopy(value))
+	case "remove":
+		_, e = patchRemove(root, tokens)
+		return e
+	case "replace":
+		if !hasValue {
+			return fmt.Errorf("No %+q member", "value")
+		}
+		return patchReplace(root, tokens, deepCopy(value))
+	case "move":
+		from, fromTokens, e := patchPointer(op, "from")
+		if e != nil {
+			return e
+		}
+		if from == path {
+			_, e = Resolve(*root, from)
+			return e
+		}
+		if strings.HasPrefix(path, from+"/") {
+			return fmt.Errorf("Cannot move %+q into its own child %+q", from, path)
+		}
+		v, e := patchRemove(root, fromTokens)
+		if e != nil {
+			return e
+		}
+		return patchAdd(root, tokens, v)
+	case "copy":
+		from, _, e := patchPointer(op, "from")
+		if e != nil {
+			return e
+		}
+		v, e := Resolve(*root, from)
+		if e != nil {
+			return e
+		}
+		return patchAdd(root, tokens, deepCopy(v))
+	case "test":
+		if !hasValue {
+			return fmt.Errorf("No %+q member", "value")
+		}
+		v, e := Resolve(*root, path)
+		if e != nil {
+			return e
+		}
+		if !equalValues(v, value) {
+			return fmt.Errorf("Test failed: %s != %s", jsonOf(v), jsonOf(value))
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown operation %+q", name)
+}
+
+// resolves the container holding the last token
+func patchParent(root JsonValue, tokens []string) (JsonValue, string, error) {
+	last := len(tokens) - 1
+	parent, e := Resolve(root, joinPointer(tokens[:last]))
+	return parent, tokens[last], e
+}
+
+func patchAdd(root *JsonValue, tokens []string, v JsonValue) error {
+	if len(tokens) == 0 {
+		*root = v
+		return nil
+	}
+	parent, last, e := patchParent(*root, tokens)
+	if e != nil {
+		return e
+	}
+	switch c := parent.(type) {
+	case *JsonObject:
+		if c != nil {
+			c.Insert(last, v)
+			return nil
+		}
+	case *JsonArray:
+		if c != nil {
+			i := len(*c)
+			if last != "-" {
+				if i, e = pointerIndex(last, len(*c)+1); e != nil {
+					return e
+				}
+			}
+			*c = append(*c, nil)
+			copy((*c)[i+1:], (*c)[i:])
+			(*c)[i] = v
+			return nil
+		}
+	}
+	return fmt.Errorf("Cannot add %+q to %s", last, jsonOf(parent))
+}
+
+func patchRemove(root *JsonValue, tokens []string) (JsonValue, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("Cannot remove the root")
+	}
+	parent, last, e := patchParent(*root, tokens)
+	if e != nil {
+		return nil, e
+	}
+	v, e := pointerChild(parent, last)
+	if e != nil {
+		return nil, e
+	}
+	switch c := parent.(type) {
+	case *JsonObject:
+		delete(*c, last)
+	case *JsonArray:
+		i, _ := pointerIndex(last, len(*c))
+		*c = append((*c)[:i], (*c)[i+1:]...)
+	}
+	return v, nil
+}
+
+func patchReplace(root *JsonValue, tokens []string, v JsonValue) error {
+	if len(tokens) == 0 {
+		*root = v
+		return nil
+	}
+	parent, last, e := patchParent(*root, tokens)
+	if e != nil {
+		return e
+	}
+	if _, e = pointerChild(parent, last); e != nil {
+		return e
+	}
+	switch c := parent.(type) {
+	case *JsonObject:
+		c.Insert(last, v)
+	case *JsonArray:
+		i, _ := pointerIndex(last, len(*c))
+		(*c)[i] = v
+	}
+	return nil
+}
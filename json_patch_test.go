@@ -0,0 +1,93 @@
+package json
+
+import "testing"
+
+func TestApplyPatch(t *testing.T) {
+	test := func(doc, patch, expected string) {
+		d := mustParse(t, doc)
+		before := d.Json()
+		r, err := ApplyPatch(d, mustParse(t, patch).(*JsonArray))
+		if err != nil {
+			t.Errorf("ApplyPatch(%s, %s): %v", doc, patch, err)
+			return
+		}
+		if !equalValues(r, mustParse(t, expected)) {
+			t.Errorf("ApplyPatch(%s, %s) = %s, not %s", doc, patch, jsonOf(r), expected)
+		}
+		if d.Json() != before {
+			t.Errorf("ApplyPatch(%s, %s) altered the doc: %s", doc, patch, d.Json())
+		}
+	}
+
+	fail := func(doc, patch string) {
+		d := mustParse(t, doc)
+		before := d.Json()
+		r, err := ApplyPatch(d, mustParse(t, patch).(*JsonArray))
+		if err == nil {
+			t.Errorf("ApplyPatch(%s, %s) = %s: no error", doc, patch, jsonOf(r))
+		}
+		if d.Json() != before {
+			t.Errorf("ApplyPatch(%s, %s) altered the doc: %s", doc, patch, d.Json())
+		}
+	}
+
+	test(`{ "foo": "bar" }`,
+		`[ { "op": "add", "path": "/baz", "value": "qux" } ]`,
+		`{ "baz": "qux", "foo": "bar" }`)
+	test(`{ "foo": [ "bar", "baz" ] }`,
+		`[ { "op": "add", "path": "/foo/1", "value": "qux" } ]`,
+		`{ "foo": [ "bar", "qux", "baz" ] }`)
+	test(`{ "foo": [ "bar" ] }`,
+		`[ { "op": "add", "path": "/foo/-", "value": [ "abc", "def" ] } ]`,
+		`{ "foo": [ "bar", [ "abc", "def" ] ] }`)
+	test(`{ "baz": "qux", "foo": "bar" }`,
+		`[ { "op": "remove", "path": "/baz" } ]`,
+		`{ "foo": "bar" }`)
+	test(`{ "foo": [ "bar", "qux", "baz" ] }`,
+		`[ { "op": "remove", "path": "/foo/1" } ]`,
+		`{ "foo": [ "bar", "baz" ] }`)
+	test(`{ "baz": "qux", "foo": "bar" }`,
+		`[ { "op": "replace", "path": "/baz", "value": "boo" } ]`,
+		`{ "baz": "boo", "foo": "bar" }`)
+	test(`{ "foo": { "bar": "baz", "waldo": "fred" }, "qux": { "corge": "grault" } }`,
+		`[ { "op": "move", "from": "/foo/waldo", "path": "/qux/thud" } ]`,
+		`{ "foo": { "bar": "baz" }, "qux": { "corge": "grault", "thud": "fred" } }`)
+	test(`{ "foo": [ "all", "grass", "cows", "eat" ] }`,
+		`[ { "op": "move", "from": "/foo/1", "path": "/foo/3" } ]`,
+		`{ "foo": [ "all", "cows", "eat", "grass" ] }`)
+	test(`{ "foo": { "bar": 1 } }`,
+		`[ { "op": "copy", "from": "/foo", "path": "/baz" },
+		   { "op": "replace", "path": "/baz/bar", "value": 2 } ]`,
+		`{ "foo": { "bar": 1 }, "baz": { "bar": 2 } }`)
+	test(`{ "baz": "qux", "foo": [ "a", 2, "c" ] }`,
+		`[ { "op": "test", "path": "/baz", "value": "qux" },
+		   { "op": "test", "path": "/foo/1", "value": 2 } ]`,
+		`{ "baz": "qux", "foo": [ "a", 2, "c" ] }`)
+	test(`{ "/": 1, "~": 2 }`,
+		`[ { "op": "replace", "path": "/~1", "value": 3 },
+		   { "op": "remove", "path": "/~0" } ]`,
+		`{ "/": 3 }`)
+	test(`{ "foo": "bar" }`,
+		`[ { "op": "replace", "path": "", "value": [ 1, 2 ] } ]`,
+		`[ 1, 2 ]`)
+	test(`{ "foo": "bar" }`,
+		`[ { "op": "add", "path": "/child", "value": { "grandchild": { "x": null } } } ]`,
+		`{ "foo": "bar", "child": { "grandchild": { "x": null } } }`)
+
+	fail(`{ "baz": "qux" }`, `[ { "op": "test", "path": "/baz", "value": "bar" } ]`)
+	fail(`{ "foo": "bar" }`, `[ { "op": "add", "path": "/baz/bat", "value": "qux" } ]`)
+	fail(`{ "foo": [ 1 ] }`, `[ { "op": "add", "path": "/foo/2", "value": 2 } ]`)
+	fail(`{ "foo": [ 1 ] }`, `[ { "op": "remove", "path": "/foo/01" } ]`)
+	fail(`{ "foo": "bar" }`, `[ { "op": "remove", "path": "/bar" } ]`)
+	fail(`{ "foo": "bar" }`, `[ { "op": "replace", "path": "/bar", "value": 1 } ]`)
+	fail(`{ "foo": "bar" }`, `[ { "op": "add", "path": "/bar" } ]`)
+	fail(`{ "foo": "bar" }`, `[ { "op": "jump", "path": "/bar" } ]`)
+	fail(`{ "foo": "bar" }`, `[ { "path": "/bar" } ]`)
+	fail(`{ "foo": "bar" }`, `[ { "op": "remove", "path": "bar" } ]`)
+	fail(`{ "foo": "bar" }`, `[ { "op": "remove", "path": "/~2" } ]`)
+	fail(`{ "foo": "bar" }`, `[ 1 ]`)
+	fail(`{ "foo": { "bar": 1 } }`, `[ { "op": "move", "from": "/foo", "path": "/foo/bar/x" } ]`)
+	// the first operation succeeds, the second fails: nothing must change
+	fail(`{ "foo": [ 1, 2 ] }`,
+		`[ { "op": "remove", "path": "/foo/0" }, { "op": "test", "path": "/foo/0", "value": 1 } ]`)
+}
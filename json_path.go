@@ -0,0 +1,930 @@
+// JSONPath (RFC 9535) queries over JsonValue trees
+package json
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type PathError error
+
+// a compiled JSONPath query
+type JsonPath struct {
+	source   string
+	segments []pathSegment
+}
+
+// a value found by a query along with its normalized path like $['a'][0]
+type PathMatch struct {
+	Path  string
+	Value JsonValue
+}
+
+// compiles the JSONPath query
+func CompilePath(s string) (*JsonPath, error) {
+	p := &pathParser{s: s}
+	if !p.eat('$') {
+		return nil, p.error("Query must start with '$'")
+	}
+	segments, e := p.segments()
+	if e != nil {
+		return nil, e
+	}
+	p.ws()
+	if p.pos < len(p.s) {
+		return nil, p.error("Unexpected %+q", p.s[p.pos:])
+	}
+	return &JsonPath{source: s, segments: segments}, nil
+}
+
+// like CompilePath() but panics on a bad query; for the queries known in advance
+func MustCompilePath(s string) *JsonPath {
+	p, e := CompilePath(s)
+	if e != nil {
+		panic(e)
+	}
+	return p
+}
+
+func (self *JsonPath) String() string { return self.source }
+
+// runs the query against the value and returns all the matches in order
+func (self *JsonPath) Query(v JsonValue) []PathMatch {
+	nodes := runSegments(self.segments, []pathNode{{v, "$"}}, v)
+	r := make([]PathMatch, len(nodes))
+	for i, n := range nodes {
+		r[i] = PathMatch{Path: n.path, Value: n.value}
+	}
+	return r
+}
+
+// runs the query and returns the matched values only
+func (self *JsonPath) Values(v JsonValue) []JsonValue {
+	var r []JsonValue
+	for _, m := range self.Query(v) {
+		r = append(r, m.Value)
+	}
+	return r
+}
+
+/*----------------------------------------------------------------------------*/
+
+type pathNode struct {
+	value JsonValue
+	path  string
+}
+
+type pathSegment struct {
+	descendant bool // ..[...] rather than [...]
+	selectors  []pathSelector
+}
+
+type pathSelector interface {
+	selectFrom(n pathNode, root JsonValue, out []pathNode) []pathNode
+}
+
+func runSegments(segments []pathSegment, nodes []pathNode, root JsonValue) []pathNode {
+	for _, seg := range segments {
+		var next []pathNode
+		for _, n := range nodes {
+			if seg.descendant {
+				for _, d := range descendants(n, nil) {
+					for _, sel := range seg.selectors {
+						next = sel.selectFrom(d, root, next)
+					}
+				}
+			} else {
+				for _, sel := range seg.selectors {
+					next = sel.selectFrom(n, root, next)
+				}
+			}
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+// the node itself followed by all its descendants in document order
+func descendants(n pathNode, out []pathNode) []pathNode {
+	out = append(out, n)
+	for _, c := range children(n) {
+		out = descendants(c, out)
+	}
+	return out
+}
+
+// the direct children; object members go in the sorted key order
+func children(n pathNode) []pathNode {
+	var r []pathNode
+	switch c := n.value.(type) {
+	case *JsonArray:
+		if !c.IsNull() {
+			for i, v := range *c {
+				r = append(r, pathNode{v, n.path + "[" + strconv.Itoa(i) + "]"})
+			}
+		}
+	case *JsonObject:
+		if !c.IsNull() {
+			keys := make([]string, 0, len(*c))
+			for k := range *c {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				r = append(r, pathNode{(*c)[k], n.path + normalizedName(k)})
+			}
+		}
+	}
+	return r
+}
+
+// the normalized path element for a member name
+func normalizedName(name string) string {
+	var b strings.Builder
+	b.WriteString("['")
+	for _, c := range name {
+		switch c {
+		case '\'':
+			b.WriteString(`\'`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, c)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+	}
+	b.WriteString("']")
+	return b.String()
+}
+
+type nameSelector string
+
+func (self nameSelector) selectFrom(n pathNode, root JsonValue, out []pathNode) []pathNode {
+	if o, ok := n.value.(*JsonObject); ok && !o.IsNull() {
+		if v, found := (*o)[string(self)]; found {
+			out = append(out, pathNode{v, n.path + normalizedName(string(self))})
+		}
+	}
+	return out
+}
+
+type wildcardSelector struct{}
+
+func (wildcardSelector) selectFrom(n pathNode, root JsonValue, out []pathNode) []pathNode {
+	return append(out, children(n)...)
+}
+
+type indexSelector int
+
+func (self indexSelector) selectFrom(n pathNode, root JsonValue, out []pathNode) []pathNode {
+	if a, ok := n.value.(*JsonArray); ok && !a.IsNull() {
+		i := int(self)
+		if i < 0 {
+			i += len(*a)
+		}
+		if i >= 0 && i < len(*a) {
+			out = append(out, pathNode{(*a)[i], n.path + "[" + strconv.Itoa(i) + "]"})
+		}
+	}
+	return out
+}
+
+type sliceSelector struct {
+	start, end, step int
+	hasStart, hasEnd bool
+}
+
+func (self sliceSelector) selectFrom(n pathNode, root JsonValue, out []pathNode) []pathNode {
+	a, ok := n.value.(*JsonArray)
+	if !ok || a.IsNull() || self.step == 0 {
+		return out
+	}
+	size := len(*a)
+	normalize := func(i int) int {
+		if i < 0 {
+			return i + size
+		}
+		return i
+	}
+	clamp := func(i, lo, hi int) int {
+		if i < lo {
+			return lo
+		}
+		if i > hi {
+			return hi
+		}
+		return i
+	}
+	if self.step > 0 {
+		lo, hi := 0, size
+		if self.hasStart {
+			lo = clamp(normalize(self.start), 0, size)
+		}
+		if self.hasEnd {
+			hi = clamp(normalize(self.end), 0, size)
+		}
+		for i := lo; i < hi; i += self.step {
+			out = append(out, pathNode{(*a)[i], n.path + "[" + strconv.Itoa(i) + "]"})
+		}
+	} else {
+		hi, lo := size-1, -1
+		if self.hasStart {
+			hi = clamp(normalize(self.start), -1, size-1)
+		}
+		if self.hasEnd {
+			lo = clamp(normalize(self.end), -1, size-1)
+		}
+		for i := hi; i > lo; i += self.step {
+			out = append(out, pathNode{(*a)[i], n.path + "[" + strconv.Itoa(i) + "]"})
+		}
+	}
+	return out
+}
+
+type filterSelector struct{ expr filterExpr }
+
+func (self filterSelector) selectFrom(n pathNode, root JsonValue, out []pathNode) []pathNode {
+	for _, c := range children(n) {
+		if self.expr.eval(c.value, root).logical() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+/*----------------------------------------------------------------------------*/
+
+// the result of a filter expression: a node list, a value (maybe Nothing) or a logical
+type filterResult struct {
+	nodes     []pathNode
+	isNodes   bool
+	value     JsonValue
+	nothing   bool
+	truth     bool
+	isLogical bool
+}
+
+func (self filterResult) logical() bool {
+	switch {
+	case self.isNodes:
+		return len(self.nodes) > 0
+	case self.isLogical:
+		return self.truth
+	}
+	return !self.nothing
+}
+
+// the single value of the result or Nothing
+func (self filterResult) single() (JsonValue, bool) {
+	switch {
+	case self.isNodes:
+		if len(self.nodes) == 1 {
+			return self.nodes[0].value, true
+		}
+		return nil, false
+	case self.isLogical:
+		return NewJsonBool(self.truth), true
+	}
+	return self.value, !self.nothing
+}
+
+func logicalResult(b bool) filterResult { return filterResult{truth: b, isLogical: true} }
+
+var nothingResult = filterResult{nothing: true}
+
+type filterExpr interface {
+	eval(current, root JsonValue) filterResult
+}
+
+type orExpr []filterExpr
+
+func (self orExpr) eval(current, root JsonValue) filterResult {
+	for _, x := range self {
+		if x.eval(current, root).logical() {
+			return logicalResult(true)
+		}
+	}
+	return logicalResult(false)
+}
+
+type andExpr []filterExpr
+
+func (self andExpr) eval(current, root JsonValue) filterResult {
+	for _, x := range self {
+		if !x.eval(current, root).logical() {
+			return logicalResult(false)
+		}
+	}
+	return logicalResult(true)
+}
+
+type notExpr struct{ expr filterExpr }
+
+func (self notExpr) eval(current, root JsonValue) filterResult {
+	return logicalResult(!self.expr.eval(current, root).logical())
+}
+
+type literalExpr struct{ value JsonValue }
+
+func (self literalExpr) eval(JsonValue, JsonValue) filterResult {
+	return filterResult{value: self.value}
+}
+
+type queryExpr struct {
+	relative bool // @ rather than $
+	segments []pathSegment
+}
+
+func (self queryExpr) eval(current, root JsonValue) filterResult {
+	start := pathNode{root, "$"}
+	if self.relative {
+		start = pathNode{current, "@"}
+	}
+	return filterResult{nodes: runSegments(self.segments, []pathNode{start}, root), isNodes: true}
+}
+
+type compareExpr struct {
+	op          string
+	left, right filterExpr
+}
+
+func (self compareExpr) eval(current, root JsonValue) filterResult {
+	a, aok := self.left.eval(current, root).single()
+	b, bok := self.right.eval(current, root).single()
+	switch self.op {
+	case "==":
+		return logicalResult(pathEqual(a, aok, b, bok))
+	case "!=":
+		return logicalResult(!pathEqual(a, aok, b, bok))
+	case "<":
+		return logicalResult(pathLess(a, aok, b, bok))
+	case ">":
+		return logicalResult(pathLess(b, bok, a, aok))
+	case "<=":
+		return logicalResult(pathLess(a, aok, b, bok) || pathEqual(a, aok, b, bok))
+	case ">=":
+		return logicalResult(pathLess(b, bok, a, aok) || pathEqual(a, aok, b, bok))
+	}
+	return logicalResult(false)
+}
+
+// numbers are compared by value regardless of int vs float
+func pathNumber(v JsonValue) (float64, bool) {
+	switch x := v.(type) {
+	case *JsonInt:
+		if x != nil {
+			return float64(*x), true
+		}
+	case *JsonFloat:
+		if x != nil {
+			return float64(*x), true
+		}
+	}
+	return 0, false
+}
+
+func pathEqual(a JsonValue, aok bool, b JsonValue, bok bool) bool {
+	if !aok || !bok {
+		return aok == bok
+	}
+	if x, ok := pathNumber(a); ok {
+		if y, ok := pathNumber(b); ok {
+			return x == y
+		}
+	}
+	return equalValues(a, b)
+}
+
+func pathLess(a JsonValue, aok bool, b JsonValue, bok bool) bool {
+	if !aok || !bok {
+		return false
+	}
+	if x, ok := pathNumber(a); ok {
+		if y, ok := pathNumber(b); ok {
+			return x < y
+		}
+		return false
+	}
+	x, xok := a.(*JsonString)
+	y, yok := b.(*JsonString)
+	return xok && yok && !x.IsNull() && !y.IsNull() && *x < *y
+}
+
+type functionExpr struct {
+	name string
+	args []filterExpr
+}
+
+func (self functionExpr) eval(current, root JsonValue) filterResult {
+	switch self.name {
+	case "length":
+		v, ok := self.args[0].eval(current, root).single()
+		if ok && !isNullValue(v) {
+			switch x := v.(type) {
+			case *JsonString:
+				return filterResult{value: NewJsonInt(utf8.RuneCountInString(string(*x)))}
+			case *JsonArray:
+				return filterResult{value: NewJsonInt(len(*x))}
+			case *JsonObject:
+				return filterResult{value: NewJsonInt(len(*x))}
+			}
+		}
+		return nothingResult
+	case "count":
+		return filterResult{value: NewJsonInt(len(self.args[0].eval(current, root).nodes))}
+	case "value":
+		if v, ok := self.args[0].eval(current, root).single(); ok {
+			return filterResult{value: v}
+		}
+		return nothingResult
+	case "match", "search":
+		v, vok := self.args[0].eval(current, root).single()
+		r, rok := self.args[1].eval(current, root).single()
+		s, sok := v.(*JsonString)
+		p, pok := r.(*JsonString)
+		if !vok || !rok || !sok || !pok || s == nil || p == nil {
+			return logicalResult(false)
+		}
+		pattern := string(*p)
+		if self.name == "match" {
+			pattern = `^(?:` + pattern + `)$`
+		}
+		re, e := regexp.Compile(pattern)
+		return logicalResult(e == nil && re.MatchString(string(*s)))
+	}
+	return nothingResult
+}
+
+// known functions and their argument counts
+var pathFunctions = map[string]int{
+	"length": 1,
+	"count":  1,
+	"value":  1,
+	"match":  2,
+	"search": 2,
+}
+
+/*----------------------------------------------------------------------------*/
+
+type pathParser struct {
+	s   string
+	pos int
+}
+
+func (self *pathParser) error(format string, args ...interface{}) error {
+	return PathError(fmt.Errorf("JSONPath %+q at %d: %s", self.s, self.pos, fmt.Sprintf(format, args...)))
+}
+
+func (self *pathParser) ws() {
+	for self.pos < len(self.s) && strings.IndexByte(" \t\n\r", self.s[self.pos]) >= 0 {
+		self.pos++
+	}
+}
+
+func (self *pathParser) peek() byte {
+	if self.pos < len(self.s) {
+		return self.s[self.pos]
+	}
+	return 0
+}
+
+func (self *pathParser) eat(c byte) bool {
+	if self.peek() == c {
+		self.pos++
+		return true
+	}
+	return false
+}
+
+func (self *pathParser) eatString(s string) bool {
+	if strings.HasPrefix(self.s[self.pos:], s) {
+		self.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func isNameFirst(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func (self *pathParser) segments() ([]pathSegment, error) {
+	var r []pathSegment
+	for {
+		save := self.pos
+		self.ws()
+		var seg pathSegment
+		switch {
+		case self.eatString(".."):
+			seg.descendant = true
+			if self.peek() == '[' {
+				sels, e := self.bracket()
+				if e != nil {
+					return nil, e
+				}
+				seg.selectors = sels
+			} else if sel, e := self.shorthand(); e != nil {
+				return nil, e
+			} else {
+				seg.selectors = []pathSelector{sel}
+			}
+		case self.eat('.'):
+			sel, e := self.shorthand()
+			if e != nil {
+				return nil, e
+			}
+			seg.selectors = []pathSelector{sel}
+		case self.peek() == '[':
+			sels, e := self.bracket()
+			if e != nil {
+				return nil, e
+			}
+			seg.selectors = sels
+		default:
+			self.pos = save
+			return r, nil
+		}
+		r = append(r, seg)
+	}
+}
+
+// a member name or a wildcard after a dot
+func (self *pathParser) shorthand() (pathSelector, error) {
+	if self.eat('*') {
+		return wildcardSelector{}, nil
+	}
+	start := self.pos
+	if !isNameFirst(self.peek()) {
+		return nil, self.error("Bad member name")
+	}
+	for self.pos < len(self.s) && (isNameFirst(self.s[self.pos]) || isDigit(self.s[self.pos])) {
+		self.pos++
+	}
+	return nameSelector(self.s[start:self.pos]), nil
+}
+
+func (self *pathParser) bracket() ([]pathSelector, error) {
+	self.eat('[')
+	var r []pathSelector
+	for {
+		self.ws()
+		sel, e := self.selector()
+		if e != nil {
+			return nil, e
+		}
+		r = append(r, sel)
+		self.ws()
+		if self.eat(']') {
+			return r, nil
+		}
+		if !self.eat(',') {
+			return nil, self.error("Expected ',' or ']'")
+		}
+	}
+}
+
+func (self *pathParser) selector() (pathSelector, error) {
+	switch c := self.peek(); {
+	case c == '\'' || c == '"':
+		s, e := self.stringLiteral()
+		return nameSelector(s), e
+	case c == '*':
+		self.pos++
+		return wildcardSelector{}, nil
+	case c == '?':
+		self.pos++
+		self.ws()
+		x, e := self.or()
+		return filterSelector{x}, e
+	}
+	var sel sliceSelector
+	var e error
+	if self.peek() != ':' {
+		if sel.start, e = self.integer(); e != nil {
+			return nil, e
+		}
+		sel.hasStart = true
+		self.ws()
+		if self.peek() != ':' {
+			return indexSelector(sel.start), nil
+		}
+	}
+	self.eat(':')
+	self.ws()
+	if c := self.peek(); c == '-' || isDigit(c) {
+		if sel.end, e = self.integer(); e != nil {
+			return nil, e
+		}
+		sel.hasEnd = true
+		self.ws()
+	}
+	sel.step = 1
+	if self.eat(':') {
+		self.ws()
+		if c := self.peek(); c == '-' || isDigit(c) {
+			if sel.step, e = self.integer(); e != nil {
+				return nil, e
+			}
+		}
+	}
+	return sel, nil
+}
+
+func (self *pathParser) integer() (int, error) {
+	start := self.pos
+	self.eat('-')
+	digits := self.pos
+	for self.pos < len(self.s) && isDigit(self.s[self.pos]) {
+		self.pos++
+	}
+	t := self.s[start:self.pos]
+	if self.pos == digits || (self.s[digits] == '0' && (self.pos-digits > 1 || digits > start)) {
+		return 0, self.error("Bad integer %+q", t)
+	}
+	n, e := strconv.Atoi(t)
+	if e != nil {
+		return 0, self.error("Bad integer %+q", t)
+	}
+	return n, nil
+}
+
+func (self *pathParser) stringLiteral() (string, error) {
+	quote := self.s[self.pos]
+	self.pos++
+	var b strings.Builder
+	for self.pos < len(self.s) {
+		c := self.s[self.pos]
+		self.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\':
+			if self.pos >= len(self.s) {
+				return "", self.error("Unterminated string")
+			}
+			c = self.s[self.pos]
+			self.pos++
+			switch c {
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '/', '\\', '\'', '"':
+				b.WriteByte(c)
+			case 'u':
+				if self.pos+4 > len(self.s) {
+					return "", self.error("Bad unicode escape")
+				}
+				v, e := strconv.ParseUint(self.s[self.pos:self.pos+4], 16, 32)
+				if e != nil {
+					return "", self.error("Bad unicode escape")
+				}
+				self.pos += 4
+				b.WriteRune(rune(v))
+			default:
+				return "", self.error("Bad escape '\\%c'", c)
+			}
+		case c < 0x20:
+			return "", self.error("Control character in string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", self.error("Unterminated string")
+}
+
+func (self *pathParser) or() (filterExpr, error) {
+	x, e := self.and()
+	if e != nil {
+		return nil, e
+	}
+	r := orExpr{x}
+	for {
+		self.ws()
+		if !self.eatString("||") {
+			break
+		}
+		self.ws()
+		if x, e = self.and(); e != nil {
+			return nil, e
+		}
+		r = append(r, x)
+	}
+	if len(r) == 1 {
+		return r[0], nil
+	}
+	return r, nil
+}
+
+func (self *pathParser) and() (filterExpr, error) {
+	x, e := self.basic()
+	if e != nil {
+		return nil, e
+	}
+	r := andExpr{x}
+	for {
+		self.ws()
+		if !self.eatString("&&") {
+			break
+		}
+		self.ws()
+		if x, e = self.basic(); e != nil {
+			return nil, e
+		}
+		r = append(r, x)
+	}
+	if len(r) == 1 {
+		return r[0], nil
+	}
+	return r, nil
+}
+
+var compareOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func (self *pathParser) basic() (filterExpr, error) {
+	if self.eat('!') {
+		self.ws()
+		x, e := self.basic()
+		if e != nil {
+			return nil, e
+		}
+		if _, ok := x.(compareExpr); ok {
+			return nil, self.error("Cannot negate a comparison without parentheses")
+		}
+		return notExpr{x}, nil
+	}
+	if self.eat('(') {
+		self.ws()
+		x, e := self.or()
+		if e != nil {
+			return nil, e
+		}
+		self.ws()
+		if !self.eat(')') {
+			return nil, self.error("Expected ')'")
+		}
+		return x, nil
+	}
+	left, e := self.comparable()
+	if e != nil {
+		return nil, e
+	}
+	save := self.pos
+	self.ws()
+	for _, op := range compareOps {
+		if self.eatString(op) {
+			self.ws()
+			right, e := self.comparable()
+			if e != nil {
+				return nil, e
+			}
+			return compareExpr{op, left, right}, nil
+		}
+	}
+	self.pos = save
+	if _, ok := left.(literalExpr); ok {
+		return nil, self.error("A literal is not a test")
+	}
+	return left, nil
+}
+
+// a literal, a query or a function call
+func (self *pathParser) comparable() (filterExpr, error) {
+	c := self.peek()
+	switch {
+	case c == '@' || c == '$':
+		self.pos++
+		segments, e := self.segments()
+		return queryExpr{relative: c == '@', segments: segments}, e
+	case c == '\'' || c == '"':
+		s, e := self.stringLiteral()
+		return literalExpr{NewJsonString(s)}, e
+	case c == '-' || isDigit(c):
+		return self.number()
+	case self.eatString("true"):
+		return literalExpr{NewJsonBool(true)}, nil
+	case self.eatString("false"):
+		return literalExpr{NewJsonBool(false)}, nil
+	case self.eatString("null"):
+		return literalExpr{nil}, nil
+	case c >= 'a' && c <= 'z':
+		return self.function()
+	}
+	return nil, self.error("Expected a comparable")
+}
+
+func (self *pathParser) number() (filterExpr, error) {
+	start := self.pos
+	self.eat('-')
+	for self.pos < len(self.s) && isDigit(self.s[self.pos]) {
+		self.pos++
+	}
+	isFloat := false
+	if self.eat('.') {
+		isFloat = true
+		for self.pos < len(self.s) && isDigit(self.s[self.pos]) {
+			self.pos++
+		}
+	}
+	if c := self.peek(); c == 'e' || c == 'E' {
+		isFloat = true
+		self.pos++
+		if c := self.peek(); c == '+' || c == '-' {
+			self.pos++
+		}
+		for self.pos < len(self.s) && isDigit(self.s[self.pos]) {
+			self.pos++
+		}
+	}
+	t := self.s[start:self.pos]
+	if isFloat {
+		f, e := strconv.ParseFloat(t, 64)
+		if e != nil {
+			return nil, self.error("Bad number %+q", t)
+		}
+		return literalExpr{NewJsonFloat(f)}, nil
+	}
+	n, e := strconv.Atoi(t)
+	if e != nil {
+		return nil, self.error("Bad number %+q", t)
+	}
+	return literalExpr{NewJsonInt(n)}, nil
+}
+
+func (self *pathParser) function() (filterExpr, error) {
+	start := self.pos
+	for self.pos < len(self.s) && (self.s[self.pos] == '_' || isDigit(self.s[self.pos]) ||
+		(self.s[self.pos] >= 'a' && self.s[self.pos] <= 'z')) {
+		self.pos++
+	}
+	name := self.s[start:self.pos]
+	argc, known := pathFunctions[name]
+	if !known {
+		return nil, self.error("Unknown function %+q", name)
+	}
+	if !self.eat('(') {
+		return nil, self.error("Expected '(' after %+q", name)
+	}
+	var args []filterExpr
+	for {
+		self.ws()
+		if len(args) == 0 && self.eat(')') {
+			break
+		}
+		x, e := self.argument()
+		if e != nil {
+			return nil, e
+		}
+		args = append(args, x)
+		self.ws()
+		if self.eat(')') {
+			break
+		}
+		if !self.eat(',') {
+			return nil, self.error("Expected ',' or ')'")
+		}
+	}
+	if len(args) != argc {
+		return nil, self.error("Function %+q takes %d argument(s)", name, argc)
+	}
+	return functionExpr{name, args}, nil
+}
+
+// a function argument: a literal, a query, a function call or a logical expression
+func (self *pathParser) argument() (filterExpr, error) {
+	save := self.pos
+	if c := self.peek(); c != '!' && c != '(' {
+		x, e := self.comparable()
+		if e == nil {
+			self.ws()
+			if c := self.peek(); c == ',' || c == ')' {
+				return x, nil
+			}
+		}
+	}
+	self.pos = save
+	return self.or()
+}
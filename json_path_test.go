@@ -0,0 +1,105 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+const pathSource = `{
+  "store": {
+    "book": [
+      { "category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95 },
+      { "category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99 },
+      { "category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99 },
+      { "category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99 }
+    ],
+    "bicycle": { "color": "red", "price": 399 }
+  },
+  "hosts": [
+    { "name": "alpha", "disks": [ { "name": "sda", "used": 95 }, { "name": "sdb", "used": 12 } ] },
+    { "name": "beta", "disks": [ { "name": "nvme0", "used": 91.5 } ] }
+  ],
+  "o": { "j j": { "k.k": 3 }, "it's": 1 }
+}`
+
+func TestJsonPath(t *testing.T) {
+	doc, _, err := ParseValue(pathSource)
+	if err != nil {
+		t.Fatalf("ParseValue(): %v", err)
+	}
+
+	test := func(query string, expected ...string) {
+		p, err := CompilePath(query)
+		if err != nil {
+			t.Errorf("CompilePath(%+q): %v", query, err)
+			return
+		}
+		var got []string
+		for _, m := range p.Query(doc) {
+			got = append(got, m.Path+"="+jsonOf(m.Value))
+		}
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%s:\n%s\nnot\n%s", query, strings.Join(got, "\n"), strings.Join(expected, "\n"))
+		}
+	}
+
+	test(`$.hosts[*].disks[?@.used > 90].name`,
+		`$['hosts'][0]['disks'][0]['name']="sda"`,
+		`$['hosts'][1]['disks'][0]['name']="nvme0"`)
+	test(`$.store.book[*].author`,
+		`$['store']['book'][0]['author']="Nigel Rees"`,
+		`$['store']['book'][1]['author']="Evelyn Waugh"`,
+		`$['store']['book'][2]['author']="Herman Melville"`,
+		`$['store']['book'][3]['author']="J. R. R. Tolkien"`)
+	test(`$..book[2].title`, `$['store']['book'][2]['title']="Moby Dick"`)
+	test(`$..book[-1].title`, `$['store']['book'][3]['title']="The Lord of the Rings"`)
+	test(`$..book[0,1].price`,
+		`$['store']['book'][0]['price']=8.950000`,
+		`$['store']['book'][1]['price']=12.990000`)
+	test(`$..book[:2].category`,
+		`$['store']['book'][0]['category']="reference"`,
+		`$['store']['book'][1]['category']="fiction"`)
+	test(`$.store.book[::-2].price`,
+		`$['store']['book'][3]['price']=22.990000`,
+		`$['store']['book'][1]['price']=12.990000`)
+	test(`$.store.book[1:-1:1].title`,
+		`$['store']['book'][1]['title']="Sword of Honour"`,
+		`$['store']['book'][2]['title']="Moby Dick"`)
+	test(`$..book[?@.isbn].title`,
+		`$['store']['book'][2]['title']="Moby Dick"`,
+		`$['store']['book'][3]['title']="The Lord of the Rings"`)
+	test(`$..book[?!@.isbn].title`,
+		`$['store']['book'][0]['title']="Sayings of the Century"`,
+		`$['store']['book'][1]['title']="Sword of Honour"`)
+	test(`$..book[?@.price < 10 && @.category == 'fiction'].title`,
+		`$['store']['book'][2]['title']="Moby Dick"`)
+	test(`$..book[?(@.price >= 22 || @.author == "Nigel Rees")].price`,
+		`$['store']['book'][0]['price']=8.950000`,
+		`$['store']['book'][3]['price']=22.990000`)
+	test(`$..book[?@.price > $.store.bicycle.price]`)
+	test(`$.store.*.price`, `$['store']['bicycle']['price']=399`)
+	test(`$..[?@.price == 399].color`, `$['store']['bicycle']['color']="red"`)
+	test(`$..*[?@.used == 95.0].name`, `$['hosts'][0]['disks'][0]['name']="sda"`)
+	test(`$.hosts[?count(@.disks[*]) > 1].name`, `$['hosts'][0]['name']="alpha"`)
+	test(`$.hosts[?length(@.name) == 4].name`, `$['hosts'][1]['name']="beta"`)
+	test(`$.hosts[?match(@.name, 'a.*')].name`, `$['hosts'][0]['name']="alpha"`)
+	test(`$.hosts[?search(@.name, 'et')].name`, `$['hosts'][1]['name']="beta"`)
+	test(`$.hosts[?value(@..used) == 91.5].name`, `$['hosts'][1]['name']="beta"`)
+	test(`$.o['j j']['k.k']`, `$['o']['j j']['k.k']=3`)
+	test(`$.o["it's"]`, `$['o']['it\'s']=1`)
+	test(`$["o"]["nope"]`)
+	test(`$.store.book[10]`)
+	test(`$`, `$=`+doc.Json())
+
+	for _, bad := range []string{
+		``, `store`, `$.`, `$[`, `$[1`, `$['a`, `$[01]`, `$[-0]`, `$.a[?@.b ==]`,
+		`$[?1]`, `$[?foo(@)]`, `$[?length(@, 1)]`, `$[?(@.a]`, `$..`, `$ x`, `$[?!@.a == 1]`,
+	} {
+		if p, err := CompilePath(bad); err == nil {
+			t.Errorf("CompilePath(%+q) = %s: no error", bad, p)
+		}
+	}
+	if v := MustCompilePath(`$.o.*`).Values(doc); len(v) != 2 {
+		t.Errorf("Values() = %v", v)
+	}
+}
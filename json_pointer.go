@@ -0,0 +1,123 @@
+// JSON Pointer (RFC 6901) helpers used to address values inside a tree
+package json
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type PointerError error
+
+// splits a JSON Pointer into its unescaped reference tokens ("" is the root)
+func splitPointer(p string) ([]string, error) {
+	if p == "" {
+		return nil, nil
+	}
+	if p[0] != '/' {
+		return nil, PointerError(fmt.Errorf("Bad pointer %+q", p))
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		for j := 0; j < len(t); j++ {
+			if t[j] == '~' && (j+1 == len(t) || (t[j+1] != '0' && t[j+1] != '1')) {
+				return nil, PointerError(fmt.Errorf("Bad escape in pointer %+q", p))
+			}
+		}
+		tokens[i] = strings.Replace(strings.Replace(t, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// escapes a single reference token
+func escapePointerToken(t string) string {
+	return strings.Replace(strings.Replace(t, "~", "~0", -1), "/", "~1", -1)
+}
+
+// builds a JSON Pointer out of (unescaped) reference tokens
+func joinPointer(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteByte('/')
+		b.WriteString(escapePointerToken(t))
+	}
+	return b.String()
+}
+
+// parses an array index token; "-" and leading zeros are not allowed here
+func pointerIndex(t string, size int) (int, error) {
+	if t == "" || (len(t) > 1 && t[0] == '0') {
+		return 0, PointerError(fmt.Errorf("Bad array index %+q", t))
+	}
+	for i := 0; i < len(t); i++ {
+		if !isDigit(t[i]) {
+			return 0, PointerError(fmt.Errorf("Bad array index %+q", t))
+		}
+	}
+	n, e := strconv.Atoi(t)
+	if e != nil || n >= size {
+		return 0, PointerError(fmt.Errorf("Array index %+q out of range", t))
+	}
+	return n, nil
+}
+
+// fetches the direct member of a container addressed by the token
+func pointerChild(v JsonValue, t string) (JsonValue, error) {
+	switch c := v.(type) {
+	case *JsonObject:
+		if !c.IsNull() {
+			if x, ok := (*c)[t]; ok {
+				return x, nil
+			}
+		}
+		return nil, PointerError(fmt.Errorf("No member %+q", t))
+	case *JsonArray:
+		if c.IsNull() {
+			return nil, PointerError(fmt.Errorf("Array index %+q out of range", t))
+		}
+		i, e := pointerIndex(t, len(*c))
+		if e != nil {
+			return nil, e
+		}
+		return (*c)[i], nil
+	}
+	return nil, PointerError(fmt.Errorf("Cannot step into %s with %+q", jsonOf(v), t))
+}
+
+// resolves the JSON Pointer p against the value v
+func Resolve(v JsonValue, p string) (JsonValue, error) {
+	tokens, e := splitPointer(p)
+	if e != nil {
+		return nil, e
+	}
+	for _, t := range tokens {
+		if v, e = pointerChild(v, t); e != nil {
+			return nil, e
+		}
+	}
+	return v, nil
+}
+
+// .Json() that tolerates the untyped nil
+func jsonOf(v JsonValue) string {
+	if v == nil {
+		return "null"
+	}
+	return v.Json()
+}
+
+// .Equal() that tolerates the untyped nil on either side
+func equalValues(a, b JsonValue) bool {
+	if a == nil || a.IsNull() {
+		return b == nil || b.IsNull()
+	}
+	return a.Equal(b)
+}
+
+// .Clone() that tolerates the untyped nil
+func deepCopy(v JsonValue) JsonValue {
+	if v == nil {
+		return nil
+	}
+	return v.Clone()
+}
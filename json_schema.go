@@ -0,0 +1,814 @@
+// JSON Schema (draft 2020-12) validation
+//
+//	Supported: type, enum, const, numeric and string constraints, pattern,
+//	properties, patternProperties, additionalProperties, propertyNames,
+//	required, dependentRequired, min/maxProperties, prefixItems, items,
+//	contains, min/maxContains, min/maxItems, uniqueItems, allOf, anyOf,
+//	oneOf, not, if/then/else, $defs and local $ref ("#/..." only).
+//	Nulls are what this library considers null, i.e. what .Json() sends as null.
+package json
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type SchemaError error
+
+// a compiled schema
+type Schema struct {
+	root *schemaNode
+}
+
+// a single failure of an instance to conform to the schema
+type Violation struct {
+	InstancePath string // JSON Pointer to the offending value
+	SchemaPath   string // JSON Pointer to the failed keyword (through $refs)
+	Message      string
+}
+
+func (self Violation) String() string {
+	return fmt.Sprintf("%+q: %s (%+q)", self.InstancePath, self.Message, self.SchemaPath)
+}
+
+// compiles the schema; all the subschemas and $refs are checked in advance
+func CompileSchema(schema JsonValue) (*Schema, error) {
+	c := &schemaCompiler{root: schema, nodes: make(map[string]*schemaNode)}
+	node, e := c.compile(schema, nil)
+	if e != nil {
+		return nil, e
+	}
+	return &Schema{root: node}, nil
+}
+
+// validates the value and returns all the violations found (nil if it conforms)
+func (self *Schema) Validate(v JsonValue) []Violation {
+	out := &schemaRun{active: make(map[schemaVisit]bool)}
+	self.root.validate(v, nil, nil, out)
+	return out.violations
+}
+
+// true if the value conforms to the schema
+func (self *Schema) IsValid(v JsonValue) bool { return len(self.Validate(v)) == 0 }
+
+/*----------------------------------------------------------------------------*/
+
+// the state of one validation: the violations and the $refs being followed
+type schemaRun struct {
+	violations []Violation
+	active     map[schemaVisit]bool
+}
+
+// a $ref target with the value it validates
+type schemaVisit struct {
+	node *schemaNode
+	v    JsonValue
+}
+
+type schemaCheck func(v JsonValue, ipath, spath []string, out *schemaRun)
+
+type schemaKeyword struct {
+	name  string
+	check schemaCheck
+}
+
+type schemaNode struct {
+	boolean  *bool // true and false are schemas too
+	keywords []schemaKeyword
+}
+
+func (self *schemaNode) validate(v JsonValue, ipath, spath []string, out *schemaRun) {
+	if self.boolean != nil {
+		if !*self.boolean {
+			violate(out, ipath, spath, "Nothing is allowed here")
+		}
+		return
+	}
+	for _, k := range self.keywords {
+		k.check(v, ipath, subPath(spath, k.name), out)
+	}
+}
+
+// true if the value conforms to the node; the details are dropped
+func (self *schemaNode) accepts(v JsonValue, out *schemaRun) bool {
+	sub := &schemaRun{active: out.active}
+	self.validate(v, nil, nil, sub)
+	return len(sub.violations) == 0
+}
+
+func violate(out *schemaRun, ipath, spath []string, format string, args ...interface{}) {
+	out.violations = append(out.violations, Violation{
+		InstancePath: joinPointer(ipath),
+		SchemaPath:   joinPointer(spath),
+		Message:      fmt.Sprintf(format, args...),
+	})
+}
+
+// the JSON Schema type name of the value
+func schemaType(v JsonValue) string {
+	if isNullValue(v) {
+		return "null"
+	}
+	switch v.(type) {
+	case *JsonBool:
+		return "boolean"
+	case *JsonInt:
+		return "integer"
+	case *JsonFloat:
+		return "number"
+	case *JsonString:
+		return "string"
+	case *JsonArray:
+		return "array"
+	case *JsonObject:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func schemaTypeMatches(v JsonValue, name string) bool {
+	t := schemaType(v)
+	switch {
+	case t == name:
+		return true
+	case name == "number":
+		return t == "integer"
+	case name == "integer" && t == "number":
+		f, _ := pathNumber(v)
+		return f == math.Trunc(f) && !math.IsInf(f, 0)
+	}
+	return false
+}
+
+// equality as JSON Schema sees it: 1 and 1.0 are the same number
+func schemaEqual(a, b JsonValue) bool {
+	if x, ok := pathNumber(a); ok {
+		if y, ok := pathNumber(b); ok {
+			return x == y
+		}
+	}
+	switch x := a.(type) {
+	case *JsonArray:
+		y, ok := b.(*JsonArray)
+		if !ok || x.IsNull() || y.IsNull() {
+			break
+		}
+		if len(*x) != len(*y) {
+			return false
+		}
+		for i := range *x {
+			if !schemaEqual((*x)[i], (*y)[i]) {
+				return false
+			}
+		}
+		return true
+	case *JsonObject:
+		y, ok := b.(*JsonObject)
+		if !ok || x.IsNull() || y.IsNull() {
+			break
+		}
+		if len(*x) != len(*y) {
+			return false
+		}
+		for k, o := range *x {
+			p, found := (*y)[k]
+			if !found || !schemaEqual(o, p) {
+				return false
+			}
+		}
+		return true
+	}
+	return equalValues(a, b)
+}
+
+/*----------------------------------------------------------------------------*/
+
+type schemaCompiler struct {
+	root  JsonValue
+	nodes map[string]*schemaNode // by the schema pointer, for $ref
+}
+
+func (self *schemaCompiler) error(at []string, format string, args ...interface{}) error {
+	return SchemaError(fmt.Errorf("Schema %+q: %s", joinPointer(at), fmt.Sprintf(format, args...)))
+}
+
+func (self *schemaCompiler) compile(s JsonValue, at []string) (*schemaNode, error) {
+	key := joinPointer(at)
+	if node, found := self.nodes[key]; found {
+		return node, nil
+	}
+	node := new(schemaNode)
+	self.nodes[key] = node // register first: $refs may loop back
+
+	switch x := s.(type) {
+	case *JsonBool:
+		if x != nil {
+			b := bool(*x)
+			node.boolean = &b
+			return node, nil
+		}
+	case *JsonObject:
+		if x.IsNull() {
+			break // the empty schema
+		}
+		keys := make([]string, 0, len(*x))
+		for k := range *x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			check, e := self.keyword(k, (*x)[k], subPath(at, k))
+			if e != nil {
+				return nil, e
+			}
+			if check != nil {
+				node.keywords = append(node.keywords, schemaKeyword{k, check})
+			}
+		}
+		return node, nil
+	}
+	if !isNullValue(s) {
+		return nil, self.error(at, "Not a schema: %s", s.Json())
+	}
+	return node, nil
+}
+
+func (self *schemaCompiler) number(v JsonValue, at []string) (float64, error) {
+	if f, ok := pathNumber(v); ok {
+		return f, nil
+	}
+	return 0, self.error(at, "Not a number: %s", jsonOf(v))
+}
+
+func (self *schemaCompiler) count(v JsonValue, at []string) (int, error) {
+	f, e := self.number(v, at)
+	if e == nil && (f < 0 || f != math.Trunc(f)) {
+		e = self.error(at, "Not a non-negative integer: %s", jsonOf(v))
+	}
+	return int(f), e
+}
+
+func (self *schemaCompiler) string(v JsonValue, at []string) (string, error) {
+	if s, ok := v.(*JsonString); ok && s != nil {
+		return string(*s), nil
+	}
+	return "", self.error(at, "Not a string: %s", jsonOf(v))
+}
+
+func (self *schemaCompiler) strings(v JsonValue, at []string) ([]string, error) {
+	if isNullValue(v) {
+		return nil, nil
+	}
+	a, ok := v.(*JsonArray)
+	if !ok {
+		return nil, self.error(at, "Not an array: %s", jsonOf(v))
+	}
+	r := make([]string, len(*a))
+	for i, o := range *a {
+		s, e := self.string(o, subPath(at, fmt.Sprint(i)))
+		if e != nil {
+			return nil, e
+		}
+		r[i] = s
+	}
+	return r, nil
+}
+
+func (self *schemaCompiler) regexp(v JsonValue, at []string) (*regexp.Regexp, error) {
+	s, e := self.string(v, at)
+	if e != nil {
+		return nil, e
+	}
+	re, e := regexp.Compile(s)
+	if e != nil {
+		return nil, self.error(at, "Bad pattern %+q: %v", s, e)
+	}
+	return re, nil
+}
+
+func (self *schemaCompiler) schemas(v JsonValue, at []string) ([]*schemaNode, error) {
+	a, ok := v.(*JsonArray)
+	if !ok || a.IsNull() {
+		return nil, self.error(at, "Not a non-empty array: %s", jsonOf(v))
+	}
+	r := make([]*schemaNode, len(*a))
+	for i, o := range *a {
+		n, e := self.compile(o, subPath(at, fmt.Sprint(i)))
+		if e != nil {
+			return nil, e
+		}
+		r[i] = n
+	}
+	return r, nil
+}
+
+// the member schemas of an object, by name
+func (self *schemaCompiler) schemaMap(v JsonValue, at []string) (map[string]*schemaNode, []string, error) {
+	if isNullValue(v) {
+		return nil, nil, nil
+	}
+	o, ok := v.(*JsonObject)
+	if !ok {
+		return nil, nil, self.error(at, "Not an object: %s", jsonOf(v))
+	}
+	r := make(map[string]*schemaNode)
+	var names []string
+	for k, s := range *o {
+		n, e := self.compile(s, subPath(at, k))
+		if e != nil {
+			return nil, nil, e
+		}
+		r[k] = n
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return r, names, nil
+}
+
+func (self *schemaCompiler) ref(v JsonValue, at []string) (*schemaNode, error) {
+	s, e := self.string(v, at)
+	if e != nil {
+		return nil, e
+	}
+	if !strings.HasPrefix(s, "#") {
+		return nil, self.error(at, "Only local $ref is supported: %+q", s)
+	}
+	p, e := url.PathUnescape(s[1:])
+	if e != nil {
+		return nil, self.error(at, "Bad $ref %+q: %v", s, e)
+	}
+	tokens, e := splitPointer(p)
+	if e != nil {
+		return nil, self.error(at, "Bad $ref %+q: %v", s, e)
+	}
+	target, e := Resolve(self.root, p)
+	if e != nil {
+		return nil, self.error(at, "Bad $ref %+q: %v", s, e)
+	}
+	return self.compile(target, tokens)
+}
+
+// compiles a single keyword; unknown (and annotation) keywords produce no check
+func (self *schemaCompiler) keyword(name string, v JsonValue, at []string) (schemaCheck, error) {
+	switch name {
+	case "$ref":
+		n, e := self.ref(v, at)
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			// a $ref back to itself over the same value would never end
+			visit := schemaVisit{n, v}
+			if out.active[visit] {
+				violate(out, ipath, spath, "Endless $ref loop")
+				return
+			}
+			out.active[visit] = true
+			n.validate(v, ipath, spath, out)
+			delete(out.active, visit)
+		}, e
+
+	case "$defs":
+		_, _, e := self.schemaMap(v, at)
+		return nil, e
+
+	case "type":
+		var names []string
+		if s, ok := v.(*JsonString); ok && s != nil {
+			names = []string{string(*s)}
+		} else if ss, e := self.strings(v, at); e != nil {
+			return nil, e
+		} else {
+			names = ss
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			for _, t := range names {
+				if schemaTypeMatches(v, t) {
+					return
+				}
+			}
+			violate(out, ipath, spath, "Type %s is not %s", schemaType(v), strings.Join(names, " or "))
+		}, nil
+
+	case "enum":
+		a, ok := v.(*JsonArray)
+		if !ok || a.IsNull() {
+			return nil, self.error(at, "Not a non-empty array: %s", jsonOf(v))
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			for _, o := range *a {
+				if schemaEqual(v, o) {
+					return
+				}
+			}
+			violate(out, ipath, spath, "%s is not one of %s", jsonOf(v), a.Json())
+		}, nil
+
+	case "const":
+		return func(x JsonValue, ipath, spath []string, out *schemaRun) {
+			if !schemaEqual(x, v) {
+				violate(out, ipath, spath, "%s is not %s", jsonOf(x), jsonOf(v))
+			}
+		}, nil
+
+	case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+		limit, e := self.number(v, at)
+		if e != nil {
+			return nil, e
+		}
+		if name == "multipleOf" && limit <= 0 {
+			return nil, self.error(at, "Not a positive number: %s", jsonOf(v))
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			f, ok := pathNumber(v)
+			if !ok {
+				return
+			}
+			switch {
+			case name == "minimum" && f < limit:
+				violate(out, ipath, spath, "%s is less than %v", v.Json(), limit)
+			case name == "maximum" && f > limit:
+				violate(out, ipath, spath, "%s is greater than %v", v.Json(), limit)
+			case name == "exclusiveMinimum" && f <= limit:
+				violate(out, ipath, spath, "%s is not greater than %v", v.Json(), limit)
+			case name == "exclusiveMaximum" && f >= limit:
+				violate(out, ipath, spath, "%s is not less than %v", v.Json(), limit)
+			case name == "multipleOf":
+				q := f / limit
+				if math.IsInf(q, 0) || math.Abs(q-math.Round(q)) > 1e-9*math.Max(1, math.Abs(q)) {
+					violate(out, ipath, spath, "%s is not a multiple of %v", v.Json(), limit)
+				}
+			}
+		}, nil
+
+	case "minLength", "maxLength":
+		limit, e := self.count(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			s, ok := v.(*JsonString)
+			if !ok || s.IsNull() {
+				return
+			}
+			n := utf8.RuneCountInString(string(*s))
+			if name == "minLength" && n < limit {
+				violate(out, ipath, spath, "String is shorter than %d", limit)
+			} else if name == "maxLength" && n > limit {
+				violate(out, ipath, spath, "String is longer than %d", limit)
+			}
+		}, nil
+
+	case "pattern":
+		re, e := self.regexp(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if s, ok := v.(*JsonString); ok && !s.IsNull() && !re.MatchString(string(*s)) {
+				violate(out, ipath, spath, "%s does not match %+q", s.Json(), re.String())
+			}
+		}, nil
+
+	case "minItems", "maxItems", "minProperties", "maxProperties":
+		limit, e := self.count(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			n := -1
+			if a, ok := v.(*JsonArray); ok && !a.IsNull() && strings.HasSuffix(name, "Items") {
+				n = len(*a)
+			}
+			if o, ok := v.(*JsonObject); ok && !o.IsNull() && strings.HasSuffix(name, "Properties") {
+				n = len(*o)
+			}
+			if n >= 0 && strings.HasPrefix(name, "min") && n < limit {
+				violate(out, ipath, spath, "Size %d is less than %d", n, limit)
+			} else if n >= 0 && strings.HasPrefix(name, "max") && n > limit {
+				violate(out, ipath, spath, "Size %d is greater than %d", n, limit)
+			}
+		}, nil
+
+	case "uniqueItems":
+		b, ok := v.(*JsonBool)
+		if !ok || b == nil {
+			return nil, self.error(at, "Not a boolean: %s", jsonOf(v))
+		}
+		if !*b {
+			return nil, nil
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if a, ok := v.(*JsonArray); ok && !a.IsNull() {
+				for i := range *a {
+					for j := 0; j < i; j++ {
+						if schemaEqual((*a)[i], (*a)[j]) {
+							violate(out, ipath, spath, "Items %d and %d are equal", j, i)
+							return
+						}
+					}
+				}
+			}
+		}, nil
+
+	case "required":
+		names, e := self.strings(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if o, ok := v.(*JsonObject); ok && !o.IsNull() {
+				for _, n := range names {
+					if _, found := (*o)[n]; !found {
+						violate(out, ipath, spath, "Required property %+q is missing", n)
+					}
+				}
+			}
+		}, nil
+
+	case "dependentRequired":
+		o, ok := v.(*JsonObject)
+		if !ok || o.IsNull() {
+			return nil, self.error(at, "Not an object: %s", jsonOf(v))
+		}
+		deps := make(map[string][]string)
+		for k, x := range *o {
+			names, e := self.strings(x, subPath(at, k))
+			if e != nil {
+				return nil, e
+			}
+			deps[k] = names
+		}
+		keys := sortedKeys(*o)
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if o, ok := v.(*JsonObject); ok && !o.IsNull() {
+				for _, k := range keys {
+					if _, found := (*o)[k]; !found {
+						continue
+					}
+					for _, n := range deps[k] {
+						if _, found := (*o)[n]; !found {
+							violate(out, ipath, subPath(spath, k), "Property %+q requires %+q", k, n)
+						}
+					}
+				}
+			}
+		}, nil
+
+	case "properties":
+		props, names, e := self.schemaMap(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if o, ok := v.(*JsonObject); ok && !o.IsNull() {
+				for _, k := range names {
+					if x, found := (*o)[k]; found {
+						props[k].validate(x, subPath(ipath, k), subPath(spath, k), out)
+					}
+				}
+			}
+		}, nil
+
+	case "patternProperties":
+		props, names, e := self.schemaMap(v, at)
+		if e != nil {
+			return nil, e
+		}
+		res := make([]*regexp.Regexp, len(names))
+		for i, k := range names {
+			if res[i], e = self.regexp(NewJsonString(k), subPath(at, k)); e != nil {
+				return nil, e
+			}
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if o, ok := v.(*JsonObject); ok && !o.IsNull() {
+				for _, k := range sortedKeys(*o) {
+					for i, re := range res {
+						if re.MatchString(k) {
+							props[names[i]].validate((*o)[k], subPath(ipath, k), subPath(spath, names[i]), out)
+						}
+					}
+				}
+			}
+		}, nil
+
+	case "additionalProperties":
+		n, e := self.compile(v, at)
+		if e != nil {
+			return nil, e
+		}
+		// the siblings tell which properties are not "additional"
+		parent, _ := Resolve(self.root, joinPointer(at[:len(at)-1]))
+		var known map[string]bool
+		var patterns []*regexp.Regexp
+		if p, ok := parent.(*JsonObject); ok && !p.IsNull() {
+			if props, ok := (*p)["properties"].(*JsonObject); ok && !props.IsNull() {
+				known = make(map[string]bool)
+				for k := range *props {
+					known[k] = true
+				}
+			}
+			if pp, ok := (*p)["patternProperties"].(*JsonObject); ok && !pp.IsNull() {
+				for k := range *pp {
+					if re, e := regexp.Compile(k); e == nil {
+						patterns = append(patterns, re)
+					}
+				}
+			}
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			o, ok := v.(*JsonObject)
+			if !ok || o.IsNull() {
+				return
+			}
+		next:
+			for _, k := range sortedKeys(*o) {
+				if known[k] {
+					continue
+				}
+				for _, re := range patterns {
+					if re.MatchString(k) {
+						continue next
+					}
+				}
+				n.validate((*o)[k], subPath(ipath, k), spath, out)
+			}
+		}, nil
+
+	case "propertyNames":
+		n, e := self.compile(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if o, ok := v.(*JsonObject); ok && !o.IsNull() {
+				for _, k := range sortedKeys(*o) {
+					if !n.accepts(NewJsonString(k), out) {
+						violate(out, subPath(ipath, k), spath, "Bad property name %+q", k)
+					}
+				}
+			}
+		}, nil
+
+	case "prefixItems":
+		items, e := self.schemas(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if a, ok := v.(*JsonArray); ok && !a.IsNull() {
+				for i := 0; i < len(items) && i < len(*a); i++ {
+					items[i].validate((*a)[i], subPath(ipath, fmt.Sprint(i)), subPath(spath, fmt.Sprint(i)), out)
+				}
+			}
+		}, nil
+
+	case "items":
+		n, e := self.compile(v, at)
+		if e != nil {
+			return nil, e
+		}
+		// the items covered by the sibling prefixItems are not checked here
+		skip := 0
+		if parent, _ := Resolve(self.root, joinPointer(at[:len(at)-1])); parent != nil {
+			if p, ok := parent.(*JsonObject); ok && !p.IsNull() {
+				if prefix, ok := (*p)["prefixItems"].(*JsonArray); ok && !prefix.IsNull() {
+					skip = len(*prefix)
+				}
+			}
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if a, ok := v.(*JsonArray); ok && !a.IsNull() {
+				for i := skip; i < len(*a); i++ {
+					n.validate((*a)[i], subPath(ipath, fmt.Sprint(i)), spath, out)
+				}
+			}
+		}, nil
+
+	case "contains":
+		n, e := self.compile(v, at)
+		if e != nil {
+			return nil, e
+		}
+		// the sibling minContains and maxContains tune this keyword
+		min, max := 1, -1
+		if parent, _ := Resolve(self.root, joinPointer(at[:len(at)-1])); parent != nil {
+			if p, ok := parent.(*JsonObject); ok && !p.IsNull() {
+				if x, found := (*p)["minContains"]; found {
+					if min, e = self.count(x, subPath(at[:len(at)-1], "minContains")); e != nil {
+						return nil, e
+					}
+				}
+				if x, found := (*p)["maxContains"]; found {
+					if max, e = self.count(x, subPath(at[:len(at)-1], "maxContains")); e != nil {
+						return nil, e
+					}
+				}
+			}
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			a, ok := v.(*JsonArray)
+			if !ok {
+				return
+			}
+			matched := 0
+			if !a.IsNull() {
+				for _, x := range *a {
+					if n.accepts(x, out) {
+						matched++
+					}
+				}
+			}
+			if matched < min {
+				violate(out, ipath, spath, "Contains %d matching items, less than %d", matched, min)
+			} else if max >= 0 && matched > max {
+				violate(out, ipath, spath, "Contains %d matching items, more than %d", matched, max)
+			}
+		}, nil
+
+	case "allOf":
+		nodes, e := self.schemas(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			for i, n := range nodes {
+				n.validate(v, ipath, subPath(spath, fmt.Sprint(i)), out)
+			}
+		}, nil
+
+	case "anyOf", "oneOf":
+		nodes, e := self.schemas(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			matched := 0
+			for _, n := range nodes {
+				if n.accepts(v, out) {
+					matched++
+				}
+			}
+			if matched == 0 {
+				violate(out, ipath, spath, "No subschema matches")
+			} else if name == "oneOf" && matched > 1 {
+				violate(out, ipath, spath, "%d subschemas match instead of one", matched)
+			}
+		}, nil
+
+	case "not":
+		n, e := self.compile(v, at)
+		if e != nil {
+			return nil, e
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			if n.accepts(v, out) {
+				violate(out, ipath, spath, "Must not match the subschema")
+			}
+		}, nil
+
+	case "if":
+		n, e := self.compile(v, at)
+		if e != nil {
+			return nil, e
+		}
+		// then and else are checked here, they mean nothing without if
+		var then, otherwise *schemaNode
+		parent, _ := Resolve(self.root, joinPointer(at[:len(at)-1]))
+		if p, ok := parent.(*JsonObject); ok && !p.IsNull() {
+			if x, found := (*p)["then"]; found {
+				if then, e = self.compile(x, subPath(at[:len(at)-1], "then")); e != nil {
+					return nil, e
+				}
+			}
+			if x, found := (*p)["else"]; found {
+				if otherwise, e = self.compile(x, subPath(at[:len(at)-1], "else")); e != nil {
+					return nil, e
+				}
+			}
+		}
+		return func(v JsonValue, ipath, spath []string, out *schemaRun) {
+			parent := spath[:len(spath)-1]
+			if n.accepts(v, out) {
+				if then != nil {
+					then.validate(v, ipath, subPath(parent, "then"), out)
+				}
+			} else if otherwise != nil {
+				otherwise.validate(v, ipath, subPath(parent, "else"), out)
+			}
+		}, nil
+	}
+	return nil, nil
+}
+
+func sortedKeys(o JsonObject) []string {
+	keys := make([]string, 0, len(o))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
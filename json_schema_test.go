@@ -0,0 +1,156 @@
+package json
+
+import (
+	"strings"
+	"testing"
+)
+
+const schemaSource = `{
+  "$defs": {
+    "disk": {
+      "type": "object",
+      "required": [ "name", "used" ],
+      "properties": {
+        "name": { "type": "string", "pattern": "^[a-z]+[0-9]*$" },
+        "used": { "type": "number", "minimum": 0, "maximum": 100 }
+      },
+      "additionalProperties": false
+    },
+    "node": {
+      "type": "object",
+      "properties": { "next": { "$ref": "#/$defs/node" }, "v": { "type": "integer" } }
+    }
+  },
+  "type": "object",
+  "required": [ "host", "disks" ],
+  "properties": {
+    "host": { "type": "string", "minLength": 2, "maxLength": 8 },
+    "kind": { "enum": [ "agent", "probe" ] },
+    "version": { "const": 2 },
+    "load": { "type": "array", "prefixItems": [ { "type": "number" } ], "items": { "type": "integer" }, "maxItems": 3 },
+    "disks": { "type": "array", "items": { "$ref": "#/$defs/disk" }, "uniqueItems": true, "contains": { "properties": { "used": { "exclusiveMinimum": 50 } } }, "maxContains": 1 },
+    "tags": { "type": "array", "items": { "type": "string" }, "minItems": 1 },
+    "chain": { "$ref": "#/$defs/node" },
+    "step": { "multipleOf": 0.5 },
+    "id": { "oneOf": [ { "type": "integer" }, { "type": "string" } ] },
+    "port": { "anyOf": [ { "type": "integer", "maximum": 1023 }, { "type": "integer", "minimum": 8000 } ] },
+    "mode": { "not": { "const": "debug" } },
+    "labels": { "type": "object", "propertyNames": { "pattern": "^[a-z]+$" }, "maxProperties": 2 },
+    "limits": { "patternProperties": { "^max_": { "type": "integer" } }, "additionalProperties": { "type": "string" } }
+  },
+  "dependentRequired": { "port": [ "host" ] },
+  "if": { "properties": { "kind": { "const": "probe" } }, "required": [ "kind" ] },
+  "then": { "required": [ "target" ] },
+  "else": { "properties": { "target": false } },
+  "allOf": [ { "properties": { "uptime": { "type": [ "integer", "null" ] } } } ]
+}`
+
+func TestSchema(t *testing.T) {
+	schema, err := CompileSchema(mustParse(t, schemaSource))
+	if err != nil {
+		t.Fatalf("CompileSchema(): %v", err)
+	}
+
+	// the expected violations as "instance path schema path" pairs
+	test := func(doc string, expected ...string) {
+		var got []string
+		for _, v := range schema.Validate(mustParse(t, doc)) {
+			got = append(got, v.InstancePath+" "+v.SchemaPath)
+		}
+		if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("Validate(%s):\n%s\nnot\n%s\n%v", doc, strings.Join(got, "\n"), strings.Join(expected, "\n"),
+				schema.Validate(mustParse(t, doc)))
+		}
+	}
+
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 }, { "name": "sdb1", "used": 12.5 } ] }`)
+	test(`{ "host": "alpha", "kind": "probe", "target": "x", "version": 2.0, "load": [ 0.5, 1 ],
+	        "disks": [ { "name": "sda", "used": 95 } ], "tags": [ "a" ], "chain": { "v": 1, "next": { "v": 2 } },
+	        "step": 2.5, "id": "x", "port": 8080, "mode": "prod", "labels": { "a": 1 },
+	        "limits": { "max_cpu": 4, "owner": "me" }, "uptime": null }`)
+
+	test(`[ 1 ]`, ` /type`)
+	test(`{ "disks": [ { "name": "sda", "used": 95 } ] }`, ` /required`)
+	test(`{ "host": "a", "disks": [ { "name": "sda", "used": 95 } ] }`, `/host /properties/host/minLength`)
+	test(`{ "host": "alpha", "disks": [ { "name": "Sda", "used": 195, "x": 1 }, { "name": "sdb", "used": 95 } ] }`,
+		`/disks /properties/disks/contains`,
+		`/disks/0/x /properties/disks/items/$ref/additionalProperties`,
+		`/disks/0/name /properties/disks/items/$ref/properties/name/pattern`,
+		`/disks/0/used /properties/disks/items/$ref/properties/used/maximum`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 9 } ] }`, `/disks /properties/disks/contains`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 }, { "name": "sda", "used": 95 } ] }`,
+		`/disks /properties/disks/contains`,
+		`/disks /properties/disks/uniqueItems`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ], "kind": "probe" }`, ` /then/required`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ], "target": 1 }`, `/target /else/properties/target`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ], "kind": "other", "version": 3 }`,
+		`/kind /properties/kind/enum`,
+		`/version /properties/version/const`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ], "load": [ "x", 1.5, 2, 3 ] }`,
+		`/load/1 /properties/load/items/type`,
+		`/load /properties/load/maxItems`,
+		`/load/0 /properties/load/prefixItems/0/type`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ], "chain": { "next": { "next": { "v": "x" } } } }`,
+		`/chain/next/next/v /properties/chain/$ref/properties/next/$ref/properties/next/$ref/properties/v/type`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ], "step": 0.7, "id": 1.5, "port": 2000 }`,
+		`/id /properties/id/oneOf`,
+		`/port /properties/port/anyOf`,
+		`/step /properties/step/multipleOf`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ], "mode": "debug", "uptime": 1.5 }`,
+		`/uptime /allOf/0/properties/uptime/type`,
+		`/mode /properties/mode/not`)
+	test(`{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ], "labels": { "a": 1, "B": 2, "c": 3 },
+	        "limits": { "max_cpu": "x", "owner": 1 } }`,
+		`/labels /properties/labels/maxProperties`,
+		`/labels/B /properties/labels/propertyNames`,
+		`/limits/owner /properties/limits/additionalProperties/type`,
+		`/limits/max_cpu /properties/limits/patternProperties/^max_/type`)
+	test(`{ "port": 1 }`, ` /dependentRequired/port`, ` /required`, ` /required`)
+
+	if !schema.IsValid(mustParse(t, `{ "host": "alpha", "disks": [ { "name": "sda", "used": 95 } ] }`)) {
+		t.Errorf("IsValid(): false")
+	}
+	if v := schema.Validate(mustParse(t, `[ 1 ]`)); len(v) != 1 || v[0].String() != `"": Type array is not object ("/type")` {
+		t.Errorf("Validate([ 1 ]) = %v", v)
+	}
+
+	for _, bad := range []string{
+		`1`, `{ "type": 1 }`, `{ "minLength": -1 }`, `{ "pattern": "(" }`, `{ "$ref": "#/nowhere" }`,
+		`{ "$ref": "http://example.com/schema" }`, `{ "allOf": 1 }`, `{ "properties": { "a": 1 } }`,
+		`{ "multipleOf": 0 }`, `{ "enum": 1 }`, `{ "required": [ 1 ] }`,
+	} {
+		if _, err := CompileSchema(mustParse(t, bad)); err == nil {
+			t.Errorf("CompileSchema(%s): no error", bad)
+		}
+	}
+	for s, ok := range map[string]bool{`true`: true, `false`: false} {
+		schema, err := CompileSchema(mustParse(t, s))
+		if err != nil || schema.IsValid(NewJsonInt(1)) != ok {
+			t.Errorf("CompileSchema(%s): %v", s, err)
+		}
+	}
+
+	// the $refs looping back over the same value fail instead of recursing forever
+	for _, s := range []string{
+		`{ "$ref": "#" }`,
+		`{ "$defs": { "a": { "$ref": "#/$defs/b" }, "b": { "allOf": [ { "$ref": "#/$defs/a" } ] } }, "$ref": "#/$defs/a" }`,
+		`{ "not": { "$ref": "#" } }`,
+	} {
+		schema, err := CompileSchema(mustParse(t, s))
+		if err != nil {
+			t.Errorf("CompileSchema(%s): %v", s, err)
+		} else if schema.IsValid(mustParse(t, `{ "a": [ 1 ] }`)) {
+			t.Errorf("%s accepts a loop", s)
+		}
+	}
+	if schema, err := CompileSchema(mustParse(t, `{ "$ref": "#" }`)); err != nil {
+		t.Errorf("CompileSchema(): %v", err)
+	} else if v := schema.Validate(NewJsonInt(1)); len(v) != 1 || v[0].String() != `"": Endless $ref loop ("/$ref/$ref")` {
+		t.Errorf("Validate() = %v", v)
+	}
+	// yet a recursive schema consuming the value still works
+	tree := `{ "type": "array", "items": { "$ref": "#" } }`
+	if schema, err := CompileSchema(mustParse(t, tree)); err != nil || !schema.IsValid(mustParse(t, `[ [], [ [ [] ] ] ]`)) || schema.IsValid(mustParse(t, `[ [ 1 ] ]`)) {
+		t.Errorf("CompileSchema(%s): %v", tree, err)
+	}
+}
@@ -0,0 +1,87 @@
+// Support for database/sql: JSON columns
+package json
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+var (
+	_ sql.Scanner   = (*JsonArray)(nil)
+	_ sql.Scanner   = (*JsonObject)(nil)
+	_ sql.Scanner   = (*JsonColumn)(nil)
+	_ driver.Valuer = JsonColumn{}
+)
+
+// parses the column value: JSON text as []byte or string; NULL is nil
+func scanJson(src interface{}) (JsonValue, error) {
+	switch x := src.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return parseAll(x)
+	case string:
+		return parseAll([]byte(x))
+	}
+	return nil, fmt.Errorf("%w: cannot scan %T as JSON", ErrType, src)
+}
+
+// scans a JSON array column; NULL (as well as JSON null) makes it null
+func (self *JsonArray) Scan(src interface{}) error {
+	v, e := scanJson(src)
+	if e != nil {
+		return e
+	}
+	if v == nil {
+		*self = nil
+		return nil
+	}
+	a, e := AsArray(v)
+	if e != nil {
+		return e
+	}
+	*self = *a
+	return nil
+}
+
+// scans a JSON object column; NULL (as well as JSON null) makes it null
+func (self *JsonObject) Scan(src interface{}) error {
+	v, e := scanJson(src)
+	if e != nil {
+		return e
+	}
+	if v == nil {
+		*self = nil
+		return nil
+	}
+	o, e := AsObject(v)
+	if e != nil {
+		return e
+	}
+	*self = *o
+	return nil
+}
+
+// a JSON column holding any JsonValue (nil for NULL); the value types have
+// their own Value() already, so this one is what goes to the queries
+type JsonColumn struct {
+	Data JsonValue
+}
+
+func (self *JsonColumn) Scan(src interface{}) error {
+	v, e := scanJson(src)
+	if e != nil {
+		return e
+	}
+	self.Data = v
+	return nil
+}
+
+// the JSON text as a string; a null makes NULL
+func (self JsonColumn) Value() (driver.Value, error) {
+	if isNullValue(self.Data) {
+		return nil, nil
+	}
+	return Encode(self.Data)
+}
@@ -0,0 +1,147 @@
+package json
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+// an in-memory driver: "INSERT" stores the argument as a new row, "SELECT"
+// returns the rows as they are stored, "SELECT BYTES" returns strings as []byte
+type stubDriver struct {
+	mu   sync.Mutex
+	rows []driver.Value
+}
+
+type stubConn struct{ d *stubDriver }
+type stubStmt struct {
+	c     *stubConn
+	query string
+}
+type stubRows struct {
+	values []driver.Value
+	bytes  bool
+}
+
+var stub = &stubDriver{}
+
+func init() { sql.Register("jsonstub", stub) }
+
+func (self *stubDriver) Open(string) (driver.Conn, error) { return &stubConn{self}, nil }
+
+func (self *stubConn) Prepare(q string) (driver.Stmt, error) { return &stubStmt{self, q}, nil }
+func (self *stubConn) Close() error                          { return nil }
+func (self *stubConn) Begin() (driver.Tx, error)             { return nil, errors.New("no transactions") }
+
+func (self *stubStmt) Close() error  { return nil }
+func (self *stubStmt) NumInput() int { return -1 }
+func (self *stubStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := self.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch self.query {
+	case "INSERT":
+		d.rows = append(d.rows, args[0])
+	case "DELETE":
+		d.rows = nil
+	}
+	return driver.RowsAffected(1), nil
+}
+func (self *stubStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := self.c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &stubRows{append([]driver.Value{}, d.rows...), self.query == "SELECT BYTES"}, nil
+}
+
+func (self *stubRows) Columns() []string { return []string{"doc"} }
+func (self *stubRows) Close() error      { return nil }
+func (self *stubRows) Next(dest []driver.Value) error {
+	if len(self.values) == 0 {
+		return io.EOF
+	}
+	dest[0] = self.values[0]
+	if s, ok := dest[0].(string); ok && self.bytes {
+		dest[0] = []byte(s)
+	}
+	self.values = self.values[1:]
+	return nil
+}
+
+func TestSql(t *testing.T) {
+	db, err := sql.Open("jsonstub", "")
+	if err != nil {
+		t.Fatalf("Open(): %v", err)
+	}
+	defer db.Close()
+
+	obj, _, _ := ParseValue(`{ "host": "box", "disks": [ "sda", "sdb" ] }`)
+	for _, v := range []JsonValue{obj, nil, NewJsonInt(42)} {
+		if _, err := db.Exec("INSERT", JsonColumn{v}); err != nil {
+			t.Fatalf("Exec(%s): %v", jsonOf(v), err)
+		}
+	}
+
+	for _, query := range []string{"SELECT", "SELECT BYTES"} {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatalf("Query(): %v", err)
+		}
+		var got []JsonValue
+		for rows.Next() {
+			var c JsonColumn
+			if err := rows.Scan(&c); err != nil {
+				t.Errorf("%s: Scan(): %v", query, err)
+			}
+			got = append(got, c.Data)
+		}
+		rows.Close()
+		if len(got) != 3 || !equalValues(got[0], obj) || got[1] != nil || jsonOf(got[2]) != "42" {
+			t.Errorf("%s: %s", query, NewJsonArray(got).Json())
+		}
+
+		rows, _ = db.Query(query)
+		rows.Next()
+		var o JsonObject
+		if err := rows.Scan(&o); err != nil || !o.Equal(obj) {
+			t.Errorf("%s: JsonObject.Scan() = %v, %s", query, err, o.Json())
+		}
+		rows.Next()
+		o = JsonObject{"x": nil}
+		if err := rows.Scan(&o); err != nil || !o.IsNull() {
+			t.Errorf("%s: JsonObject.Scan(NULL) = %v, %s", query, err, o.Json())
+		}
+		rows.Next()
+		if err := rows.Scan(&o); !errors.Is(err, ErrType) {
+			t.Errorf("%s: JsonObject.Scan(42): %v", query, err)
+		}
+		rows.Close()
+	}
+
+	db.Exec("DELETE")
+	db.Exec("INSERT", JsonColumn{NewJsonArray([]JsonValue{NewJsonInt(1), &JsonObject{}})})
+	var a JsonArray
+	if err := db.QueryRow("SELECT BYTES").Scan(&a); err != nil || a.Json() != "[ 1, {  } ]" {
+		t.Errorf("JsonArray.Scan() = %v, %s", err, a.Json())
+	}
+	var o JsonObject
+	if err := db.QueryRow("SELECT").Scan(&o); !errors.Is(err, ErrType) {
+		t.Errorf("JsonObject.Scan(array): %v", err)
+	}
+
+	db.Exec("DELETE")
+	db.Exec("INSERT", "{ not json")
+	var c JsonColumn
+	if err := db.QueryRow("SELECT").Scan(&c); err == nil {
+		t.Errorf("Scan(garbage): no error")
+	}
+	if err := c.Scan(3.5); !errors.Is(err, ErrType) {
+		t.Errorf("Scan(float64): %v", err)
+	}
+	if v, err := (JsonColumn{NewJsonString("")}).Value(); v != nil || err != nil {
+		t.Errorf("Value(null) = %v, %v", v, err)
+	}
+}
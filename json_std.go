@@ -0,0 +1,129 @@
+// Interoperability with encoding/json
+package json
+
+import (
+	stdjson "encoding/json"
+	"strconv"
+	"strings"
+)
+
+// the encoding/json interfaces are implemented by every value type
+var (
+	_ stdjson.Marshaler   = (*JsonInt)(nil)
+	_ stdjson.Unmarshaler = (*JsonInt)(nil)
+	_ stdjson.Marshaler   = (*JsonFloat)(nil)
+	_ stdjson.Unmarshaler = (*JsonFloat)(nil)
+	_ stdjson.Marshaler   = (*JsonBool)(nil)
+	_ stdjson.Unmarshaler = (*JsonBool)(nil)
+	_ stdjson.Marshaler   = (*JsonString)(nil)
+	_ stdjson.Unmarshaler = (*JsonString)(nil)
+	_ stdjson.Marshaler   = (*JsonArray)(nil)
+	_ stdjson.Unmarshaler = (*JsonArray)(nil)
+	_ stdjson.Marshaler   = (*JsonObject)(nil)
+	_ stdjson.Unmarshaler = (*JsonObject)(nil)
+	_ stdjson.Marshaler   = JsonAny{}
+	_ stdjson.Unmarshaler = (*JsonAny)(nil)
+)
+
+func (self *JsonInt) MarshalJSON() ([]byte, error)    { return marshal(self) }
+func (self *JsonFloat) MarshalJSON() ([]byte, error)  { return marshal(self) }
+func (self *JsonBool) MarshalJSON() ([]byte, error)   { return marshal(self) }
+func (self *JsonString) MarshalJSON() ([]byte, error) { return marshal(self) }
+func (self *JsonArray) MarshalJSON() ([]byte, error)  { return marshal(self) }
+func (self *JsonObject) MarshalJSON() ([]byte, error) { return marshal(self) }
+
+// the other kind of number is fine as long as it converts exactly (see .Set());
+// null leaves the value as it is, the way encoding/json does
+func (self *JsonInt) UnmarshalJSON(b []byte) error    { return unmarshal(self, b) }
+func (self *JsonFloat) UnmarshalJSON(b []byte) error  { return unmarshal(self, b) }
+func (self *JsonBool) UnmarshalJSON(b []byte) error   { return unmarshal(self, b) }
+func (self *JsonString) UnmarshalJSON(b []byte) error { return unmarshal(self, b) }
+func (self *JsonArray) UnmarshalJSON(b []byte) error  { return unmarshal(self, b) }
+func (self *JsonObject) UnmarshalJSON(b []byte) error { return unmarshal(self, b) }
+
+// compact, with the floats in full precision (unlike .Json()'s "%f")
+func marshal(v JsonValue) ([]byte, error) {
+	s, e := Format(v, "")
+	if e != nil {
+		return nil, e
+	}
+	return []byte(s), nil
+}
+
+// parses the whole text, see Parse()
+func parseAll(b []byte) (JsonValue, error) { return Parse(string(b)) }
+
+func unmarshal(self JsonValue, b []byte) error {
+	v, e := parseAll(b)
+	if e != nil || v == nil {
+		return e
+	}
+	switch v.(type) {
+	case *JsonArray, *JsonObject:
+		return self.TrySet(v)
+	}
+	return self.TrySet(v.Value())
+}
+
+// holds any JsonValue (nil for null) for encoding/json, say, as a struct field
+type JsonAny struct {
+	Value JsonValue
+}
+
+func (self JsonAny) MarshalJSON() ([]byte, error) {
+	if self.Value == nil {
+		return []byte("null"), nil
+	}
+	return marshal(self.Value)
+}
+
+func (self *JsonAny) UnmarshalJSON(b []byte) error {
+	v, e := parseAll(b)
+	if e != nil {
+		return e
+	}
+	self.Value = v
+	return nil
+}
+
+// the JSON text of the value as a json.RawMessage
+func ToRawMessage(v JsonValue) (stdjson.RawMessage, error) {
+	if v == nil {
+		return stdjson.RawMessage("null"), nil
+	}
+	return marshal(v)
+}
+
+// parses the json.RawMessage (nil for null)
+func FromRawMessage(m stdjson.RawMessage) (JsonValue, error) { return parseAll(m) }
+
+// the number as a json.Number, floats with no precision lost
+func ToNumber(v JsonValue) (stdjson.Number, error) {
+	switch x := v.(type) {
+	case *JsonInt:
+		if x != nil {
+			return stdjson.Number(strconv.Itoa(int(*x))), nil
+		}
+	case *JsonFloat:
+		if x != nil {
+			return stdjson.Number(strconv.FormatFloat(float64(*x), 'g', -1, 64)), nil
+		}
+	}
+	return "", accessError(v, "number")
+}
+
+// a JsonInt for an integer literal, a JsonFloat for the others (see ToInt() and ToFloat())
+func FromNumber(n stdjson.Number) (JsonValue, error) {
+	if strings.ContainsAny(string(n), ".eE") {
+		f, e := ToFloat(string(n))
+		if e != nil {
+			return nil, e
+		}
+		return NewJsonFloat(f), nil
+	}
+	i, e := ToInt(string(n))
+	if e != nil {
+		return nil, e
+	}
+	return NewJsonInt(i), nil
+}
@@ -0,0 +1,145 @@
+package json
+
+import (
+	stdjson "encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStdJson(t *testing.T) {
+	type report struct {
+		Host  *JsonString `json:"host"`
+		Count *JsonInt    `json:"count"`
+		Load  *JsonFloat  `json:"load"`
+		Up    *JsonBool   `json:"up"`
+		Disks *JsonArray  `json:"disks"`
+		Extra *JsonObject `json:"extra"`
+		Any   JsonAny     `json:"any"`
+		None  JsonAny     `json:"none"`
+	}
+	extra := &JsonObject{}
+	extra.Insert("tab\there", NewJsonString("ctl\x01"))
+	r := report{
+		Host:  NewJsonString("box"),
+		Count: NewJsonInt(3),
+		Load:  NewJsonFloat(0.5),
+		Up:    NewJsonBool(true),
+		Disks: NewJsonArray([]JsonValue{NewJsonString("sda"), &JsonArray{}}),
+		Extra: extra,
+		Any:   JsonAny{NewJsonInt(7)},
+	}
+	b, err := stdjson.Marshal(r)
+	if err != nil {
+		t.Fatalf("Marshal(): %v", err)
+	}
+	const expected = `{"host":"box","count":3,"load":0.5,"up":true,"disks":["sda",[]],"extra":{"tab\there":"ctl\u0001"},"any":7,"none":null}`
+	if string(b) != expected {
+		t.Errorf("Marshal(): %s", b)
+	}
+
+	var back report
+	if err := stdjson.Unmarshal(b, &back); err != nil {
+		t.Fatalf("Unmarshal(): %v", err)
+	}
+	if !back.Host.Equal(r.Host) || !back.Count.Equal(r.Count) || !back.Load.Equal(r.Load) ||
+		!back.Up.Equal(r.Up) || !back.Disks.Equal(r.Disks) || !back.Extra.Equal(r.Extra) ||
+		!equalValues(back.Any.Value, r.Any.Value) || back.None.Value != nil {
+		b2, _ := stdjson.Marshal(back)
+		t.Errorf("Unmarshal(): %s", b2)
+	}
+
+	// the floats keep all their digits (and stay floats)
+	for _, x := range []float64{1e-9, 1e300, -2.5e-300, 1, 0.1} {
+		b, err := stdjson.Marshal(NewJsonFloat(x))
+		back := new(JsonFloat)
+		if err != nil || stdjson.Unmarshal(b, back) != nil || float64(*back) != x {
+			t.Errorf("Marshal(%g) = %s, %v, back %v", x, b, err, float64(*back))
+		} else if v, _ := Parse(string(b)); v == nil || v.Json() != NewJsonFloat(x).Json() {
+			t.Errorf("Marshal(%g) = %s parses as %T", x, b, v)
+		}
+	}
+
+	// the kinds must match, numbers convert when exact
+	i, f := NewJsonInt(1), NewJsonFloat(1.5)
+	if err := stdjson.Unmarshal([]byte(`2.0`), i); err != nil || *i != 2 {
+		t.Errorf("JsonInt.UnmarshalJSON(2.0) = %v, %s", err, i.Json())
+	}
+	if err := stdjson.Unmarshal([]byte(`2.5`), i); err == nil {
+		t.Errorf("JsonInt.UnmarshalJSON(2.5): no error")
+	}
+	if err := stdjson.Unmarshal([]byte(`3`), f); err != nil || *f != 3 {
+		t.Errorf("JsonFloat.UnmarshalJSON(3) = %v, %s", err, f.Json())
+	}
+	if err := stdjson.Unmarshal([]byte(`1.5e3`), f); err != nil || *f != 1500 {
+		t.Errorf("JsonFloat.UnmarshalJSON(1.5e3) = %v, %s", err, f.Json())
+	}
+	if err := stdjson.Unmarshal([]byte(`"x"`), i); err == nil {
+		t.Errorf(`JsonInt.UnmarshalJSON("x"): no error`)
+	}
+	if err := stdjson.Unmarshal([]byte(`null`), i); err != nil || *i != 2 {
+		t.Errorf("JsonInt.UnmarshalJSON(null) = %v, %s", err, i.Json())
+	}
+	o := new(JsonObject)
+	if err := stdjson.Unmarshal([]byte(`{"a":{}, "b":[]}`), o); err != nil || o.Json() != `{ "a": {  }, "b": [  ] }` {
+		t.Errorf("JsonObject.UnmarshalJSON() = %v, %s", err, o.Json())
+	}
+	if err := o.UnmarshalJSON([]byte(`{"a":1} x`)); err == nil {
+		t.Errorf("JsonObject.UnmarshalJSON(tail): no error")
+	}
+
+	// a cycle is an error, not a stack overflow
+	c := &JsonObject{}
+	(*c)["self"] = c
+	if _, err := stdjson.Marshal(c); err == nil {
+		t.Errorf("Marshal(cycle): no error")
+	}
+
+	m, err := ToRawMessage(r.Extra)
+	if err != nil {
+		t.Errorf("ToRawMessage(): %v", err)
+	}
+	if v, err := FromRawMessage(m); err != nil || !v.Equal(r.Extra) {
+		t.Errorf("FromRawMessage(%s) = %s, %v", m, jsonOf(v), err)
+	}
+	if m, _ := ToRawMessage(nil); string(m) != "null" {
+		t.Errorf("ToRawMessage(nil) = %s", m)
+	}
+
+	numbers := []struct {
+		v        JsonValue
+		expected stdjson.Number
+	}{
+		{NewJsonInt(-12), "-12"},
+		{NewJsonFloat(1e-9), "1e-09"},
+		{NewJsonFloat(0.1), "0.1"},
+	}
+	for _, x := range numbers {
+		n, err := ToNumber(x.v)
+		if err != nil || n != x.expected {
+			t.Errorf("ToNumber(%s) = %s, %v", x.v.Json(), n, err)
+		}
+		if v, err := FromNumber(n); err != nil || !v.Equal(x.v) {
+			t.Errorf("FromNumber(%s) = %s, %v", n, jsonOf(v), err)
+		}
+	}
+	if _, err := ToNumber(NewJsonString("1")); err == nil {
+		t.Errorf("ToNumber(string): no error")
+	}
+	if _, err := FromNumber("99999999999999999999"); err == nil {
+		t.Errorf("FromNumber(huge int): no error")
+	}
+
+	// decoded with UseNumber() and converted
+	var decoded interface{}
+	d := stdjson.NewDecoder(strings.NewReader(`{"n": 12345678901234567, "f": 0.25, "raw": [1]}`))
+	d.UseNumber()
+	if err := d.Decode(&decoded); err != nil {
+		t.Fatalf("Decode(): %v", err)
+	}
+	if v, err := ToJsonValue(decoded); err != nil || v.Json() != `{ "f": 0.250000, "n": 12345678901234567, "raw": [ 1 ] }` {
+		t.Errorf("ToJsonValue(decoded) = %s, %v", jsonOf(v), err)
+	}
+	if v, err := ToJsonValue(stdjson.RawMessage(`[true]`)); err != nil || v.Json() != `[ true ]` {
+		t.Errorf("ToJsonValue(RawMessage) = %s, %v", jsonOf(v), err)
+	}
+}
@@ -1,7 +1,7 @@
 package json
 
+import "errors"
 import "testing"
-import "github.com/stretchr/testify/assert"
 
 import ( // for Example*
 	"fmt"
@@ -59,35 +59,38 @@ func TestPanics(t *testing.T) {
 	// The only parser that panics is parseString for wrong \uXXXX things.
 
 	i1 := new(JsonInt)
-	assert.Panics(t, func() { i1.Set(123.123) }, "Int.Set(Float)")
-	assert.Panics(t, func() { i1.Append(123) }, "Int.Append()")
-	assert.Panics(t, func() { i1.Insert("xyz", 123) }, "Int.Insert()")
+	mustPanic(t, "Int.Set(Float)", func() { i1.Set(123.123) })
+	mustPanic(t, "Int.Append()", func() { i1.Append(123) })
+	mustPanic(t, "Int.Insert()", func() { i1.Insert("xyz", 123) })
 
 	f1 := new(JsonFloat)
-	assert.Panics(t, func() { f1.Set(123) }, "Float.Set(Int)")
-	assert.Panics(t, func() { f1.Append(123.123) }, "Float.Append()")
-	assert.Panics(t, func() { f1.Insert("xyz", 123.123) }, "Float.Insert()")
+	mustPanic(t, "Float.Set(inexact)", func() { f1.Set(uint64(1<<60 + 1)) })
+	mustPanic(t, "Float.Append()", func() { f1.Append(123.123) })
+	mustPanic(t, "Float.Insert()", func() { f1.Insert("xyz", 123.123) })
 
 	b1 := new(JsonBool)
-	assert.Panics(t, func() { b1.Set(123.123) }, "Bool.Set(Float)")
-	assert.Panics(t, func() { b1.Parse("never") }, "Bool.Parse(garbage)")
-	assert.Panics(t, func() { b1.Append(true) }, "Bool.Append()")
-	assert.Panics(t, func() { b1.Insert("xyz", true) }, "Bool.Insert()")
+	mustPanic(t, "Bool.Set(Float)", func() { b1.Set(123.123) })
+	if b1.Parse("never") == nil {
+		t.Errorf("Bool.Parse(garbage) did not fail")
+	}
+	mustPanic(t, "Bool.Set(garbage)", func() { b1.Set("never") })
+	mustPanic(t, "Bool.Append()", func() { b1.Append(true) })
+	mustPanic(t, "Bool.Insert()", func() { b1.Insert("xyz", true) })
 
 	s1 := new(JsonString)
-	assert.Panics(t, func() { s1.Set(123.123) }, "String.Set(Float)")
-	assert.Panics(t, func() { s1.Append("123") }, "String.Append()")
-	assert.Panics(t, func() { s1.Insert("xyz", "123") }, "String.Insert()")
-	assert.Panics(t, func() { s1.Parse(`"zzz\u123zzz"`) }, `String.Parse("\u123z")`)
+	mustPanic(t, "String.Set(Float)", func() { s1.Set(123.123) })
+	mustPanic(t, "String.Append()", func() { s1.Append("123") })
+	mustPanic(t, "String.Insert()", func() { s1.Insert("xyz", "123") })
+	mustPanic(t, `String.Parse("\u123z")`, func() { s1.Parse(`"zzz\u123zzz"`) })
 
 	a1 := new(JsonArray)
-	assert.Panics(t, func() { a1.Set(123.123) }, "Array.Set(Float)")
-	assert.Panics(t, func() { a1.Insert("xyz", 123) }, "Array.Insert()")
+	mustPanic(t, "Array.Set(Float)", func() { a1.Set(123.123) })
+	mustPanic(t, "Array.Insert()", func() { a1.Insert("xyz", 123) })
 
 	o1 := new(JsonObject)
-	assert.Panics(t, func() { o1.Set(123.123) }, "Object.Set(Float)")
-	assert.Panics(t, func() { o1.Append(123) }, "Object.Append()")
-	assert.Panics(t, func() { o1.Insert("xyz", o1) }, "Object looped")
+	mustPanic(t, "Object.Set(Float)", func() { o1.Set(123.123) })
+	mustPanic(t, "Object.Append()", func() { o1.Append(123) })
+	mustPanic(t, "Object looped", func() { o1.Insert("xyz", o1) })
 
 	t.Logf("All panics performed")
 }
@@ -1115,3 +1118,154 @@ func TestAll(t *testing.T) {
 	}
 	t.Logf("%s", json.Json())
 }
+
+func TestClone(t *testing.T) {
+	src, _, err := ParseValue(`{ "a": [ 1, 2.5, { "b": "c" } ], "d": true, "e": null }`)
+	if err != nil {
+		t.Fatalf("ParseValue(): %v", err)
+	}
+	o := src.(*JsonObject)
+	c := o.Clone().(*JsonObject)
+	if !c.Equal(o) {
+		t.Errorf("Clone(): %s != %s", c.Json(), o.Json())
+	}
+	(*(*c)["a"].(*JsonArray))[2].Insert("b", NewJsonString("x"))
+	(*c)["a"].Append(NewJsonInt(3))
+	(*c)["d"].Set(false)
+	if o.Json() != `{ "a": [ 1, 2.500000, { "b": "c" } ], "d": true, "e": null }` {
+		t.Errorf("Clone() shares: %s", o.Json())
+	}
+
+	var i0 *JsonInt
+	if v := i0.Clone(); v.(*JsonInt) != nil {
+		t.Errorf("nil.Clone() = %s", v.Json())
+	}
+	for _, v := range []JsonValue{NewJsonInt(1), NewJsonFloat(1.5), NewJsonBool(true), NewJsonString("s"),
+		new(JsonArray), new(JsonObject), (*JsonFloat)(nil), (*JsonBool)(nil), (*JsonString)(nil),
+		(*JsonArray)(nil), (*JsonObject)(nil)} {
+		if c := v.Clone(); !c.Equal(v) {
+			t.Errorf("%T.Clone(): %s != %s", v, c.Json(), v.Json())
+		}
+	}
+
+	a1 := NewJsonArray([]JsonValue{NewJsonInt(1), NewJsonObject(map[string]JsonValue{"x": NewJsonInt(1)})})
+	shared, copied := new(JsonArray), new(JsonArray)
+	shared.Set(a1)
+	copied.SetCopy(a1)
+	(*a1)[0].Set(2)
+	(*a1)[1].Insert("x", NewJsonInt(2))
+	if shared.Json() != `[ 2, { "x": 2 } ]` || copied.Json() != `[ 1, { "x": 1 } ]` {
+		t.Errorf("Set(): %s, SetCopy(): %s", shared.Json(), copied.Json())
+	}
+
+	o1 := NewJsonObject(map[string]JsonValue{"x": NewJsonInt(1)})
+	oshared, ocopied := new(JsonObject), new(JsonObject)
+	oshared.Set(o1)
+	ocopied.SetCopy(o1)
+	o1.Insert("y", NewJsonInt(2))
+	if oshared.Json() != `{ "x": 1, "y": 2 }` || ocopied.Json() != `{ "x": 1 }` {
+		t.Errorf("Set(): %s, SetCopy(): %s", oshared.Json(), ocopied.Json())
+	}
+}
+
+func TestTryMutations(t *testing.T) {
+	check := func(what string, e error, fails bool) {
+		if fails && e == nil {
+			t.Errorf("%s: no error", what)
+		}
+		if !fails && e != nil {
+			t.Errorf("%s: %v", what, e)
+		}
+		if fails && e != nil && !errors.Is(e, ErrValue) {
+			t.Errorf("%s: %v is not a ValueError", what, e)
+		}
+	}
+
+	i := new(JsonInt)
+	check("Int.TrySet(float)", i.TrySet(1.5), true)
+	check("Int.TrySet(int16)", i.TrySet(int16(7)), false)
+	check("Int.TryAppend()", i.TryAppend(1), true)
+	check("Int.TryInsert()", i.TryInsert("x", 1), true)
+	if e := i.TrySet("12x"); e == nil || int(*i) != 7 {
+		t.Errorf("Int.TrySet(%+q) = %v, %s", "12x", e, i.Json())
+	}
+
+	f := new(JsonFloat)
+	check("Float.TrySet(bool)", f.TrySet(true), true)
+	check("Float.TrySet(string)", f.TrySet("2.5"), false)
+	if e := f.TrySet("two"); e == nil || float64(*f) != 2.5 {
+		t.Errorf("Float.TrySet(%+q) = %v, %s", "two", e, f.Json())
+	}
+
+	b := new(JsonBool)
+	check("Bool.TrySet(int)", b.TrySet(1), true)
+	check("Bool.TrySet(true)", b.TrySet("True"), false)
+	check("Bool.Parse(never)", b.Parse("never"), true)
+	if !bool(*b) {
+		t.Errorf("Bool.Parse(never) altered the value")
+	}
+
+	s := new(JsonString)
+	check("String.TrySet(int)", s.TrySet(1), true)
+	check("String.TrySet(*JsonString)", s.TrySet(NewJsonString("x")), false)
+	check("String.TryAppend()", s.TryAppend("y"), true)
+	check("String.TrySet(nil *JsonString)", s.TrySet((*JsonString)(nil)), false)
+	if !s.IsNull() {
+		t.Errorf("String.TrySet(nil): %s", s.Json())
+	}
+
+	// no typed nil makes any of them panic
+	nils := []interface{}{(*JsonInt)(nil), (*JsonFloat)(nil), (*JsonBool)(nil), (*JsonString)(nil),
+		(*JsonArray)(nil), (*JsonObject)(nil)}
+	for _, v := range []JsonValue{NewJsonInt(1), NewJsonFloat(1), NewJsonBool(true), NewJsonString("x"),
+		NewJsonArray([]JsonValue{}), NewJsonObject(nil)} {
+		for _, x := range nils {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("%T with %T panics: %v", v, x, r)
+					}
+				}()
+				v.TrySet(x)
+				v.TryAppend(x)
+				v.TryInsert("k", x)
+			}()
+		}
+	}
+
+	a := new(JsonArray)
+	check("Array.TrySet(float)", a.TrySet(1.5), true)
+	check("Array.TryAppend(JsonValue)", a.TryAppend(NewJsonInt(1)), false)
+	check("Array.TryAppend(nil)", a.TryAppend(nil), false)
+	check("Array.TryAppend(int)", a.TryAppend(1), true)
+	check("Array.TryInsert()", a.TryInsert("x", nil), true)
+	if a.Json() != "[ 1, null ]" {
+		t.Errorf("Array: %s", a.Json())
+	}
+
+	o := new(JsonObject)
+	check("Object.TrySet(float)", o.TrySet(1.5), true)
+	check("Object.TryAppend()", o.TryAppend(nil), true)
+	check("Object.TryInsert(JsonValue)", o.TryInsert("a", NewJsonInt(1)), false)
+	check("Object.TryInsert(nil)", o.TryInsert("b", nil), false)
+	check("Object.TryInsert(int)", o.TryInsert("c", 1), true)
+	if e := o.TryInsert("d", o); e == nil || errors.Is(e, ErrValue) {
+		t.Errorf("Object.TryInsert(self): %v", e)
+	}
+	if e := o.TrySet(map[string]JsonValue{"x": NewJsonInt(1), "y": o}); e == nil {
+		t.Errorf("Object.TrySet(map with self): no error")
+	}
+	if o.Json() != `{ "a": 1, "b": null }` {
+		t.Errorf("Object: %s", o.Json())
+	}
+	if e := a.TrySet([]JsonValue{NewJsonInt(2), a}); e == nil || a.Json() != "[ 1, null ]" {
+		t.Errorf("Array.TrySet([]JsonValue with self) = %v, %s", e, a.Json())
+	}
+
+	// the nil pointers are nulls
+	check("Array.TrySet(nil *JsonArray)", a.TrySet((*JsonArray)(nil)), false)
+	check("Object.TrySet(nil *JsonObject)", o.TrySet((*JsonObject)(nil)), false)
+	if !a.IsNull() || !o.IsNull() {
+		t.Errorf("TrySet(nil): %s, %s", a.Json(), o.Json())
+	}
+}
@@ -1,12 +1,14 @@
 // Internal representation of JSON values:
-//   Scalars: integers, floats, bools, strings
-//   Structural: arrays and objects
-//   null: do not need special implementation
+//
+//	Scalars: integers, floats, bools, strings
+//	Structural: arrays and objects
+//	null: do not need special implementation
 package json
 
 // https://golangbot.com/interfaces-part-2/#implementinginterfacesusingpointerreceiversvsvaluereceivers
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
@@ -28,6 +30,33 @@ type JsonValue interface {
 	Insert(string, interface{}) // updates a JsonObject
 	Equal(JsonValue) bool       // compares two JsonValue to be equal
 	IsNull() bool               // compares this JsonValue to be zero
+	Clone() JsonValue           // makes a deep copy sharing nothing with this one
+
+	// the error-returning counterparts of the above, these never panic
+	TrySet(interface{}) error            // like Set()
+	TryAppend(interface{}) error         // like Append()
+	TryInsert(string, interface{}) error // like Insert()
+}
+
+// what the Try...() methods return instead of panic
+type ValueError error
+
+// every ValueError is errors.Is() this one
+var ErrValue = errors.New("bad value")
+
+type valueError struct{ error }
+
+func (valueError) Is(e error) bool { return e == ErrValue }
+
+func newValueError(format string, args ...interface{}) error {
+	return ValueError(valueError{fmt.Errorf(format, args...)})
+}
+
+// the panic-free mutations share the panicking ones' logic
+func must(e error) {
+	if e != nil {
+		panic(e)
+	}
 }
 
 /******************************************************************************/
@@ -59,38 +88,41 @@ func (self *JsonInt) Json() string {
 	return fmt.Sprintf("%d", *self)
 }
 
-// one can .Set() JsonInt from any Go int (not an uint) or from a string
+// one can .Set() JsonInt from any Go integer or integral float (see ToInt()) or
+// from a string; it panics on anything else, on overflow, as well as on a bad string
 func (self *JsonInt) Set(v interface{}) JsonValue {
-	var t int
-	switch iv := v.(type) {
-	case int:
-		t = iv
-	case int8:
-		t = int(v.(int8))
-	case int16:
-		t = int(v.(int16))
-	case int32:
-		t = int(v.(int32))
-	case int64:
-		t = int(v.(int64))
+	must(self.TrySet(v))
+	return self
+}
+
+// like .Set(), but returns an error instead of panic
+func (self *JsonInt) TrySet(v interface{}) error {
+	switch v.(type) {
 	case string:
-		self.Parse(v.(string))
-		return self
-	default:
-		panic(v)
+		return self.Parse(v.(string))
+	case bool, nil:
+		return newValueError("cannot %T.Set(%T)", self, v)
+	}
+	t, e := ToInt(v)
+	if e != nil {
+		return newValueError("cannot %T.Set(%T): %v", self, v, e)
 	}
 	*self = (JsonInt)(t)
-	return self
+	return nil
 }
 
-// parse the string as decimal int64 and replace the current value
+// parse the string as decimal int64 (or an integral number like "1e3")
+// and replace the current value
 func (self *JsonInt) Parse(s string) error {
 	v, e := strconv.ParseInt(s, 10, 64)
 	if e != nil {
-		return e
+		i, ne := parseInt(s)
+		if ne != nil {
+			return ne
+		}
+		return self.TrySet(i)
 	}
-	self.Set(v)
-	return nil
+	return self.TrySet(v)
 }
 
 // return the value as Go's int
@@ -98,6 +130,22 @@ func (self *JsonInt) Value() interface{}    { return int(*self) }
 func (*JsonInt) Append(interface{})         { panic("Int is immutable") }
 func (*JsonInt) Insert(string, interface{}) { panic("Int is immutable") }
 
+func (*JsonInt) TryAppend(interface{}) error {
+	return newValueError("Int is immutable")
+}
+func (*JsonInt) TryInsert(string, interface{}) error {
+	return newValueError("Int is immutable")
+}
+
+// a nil stays nil (yet typed)
+func (self *JsonInt) Clone() JsonValue {
+	if self == nil {
+		return self
+	}
+	c := *self
+	return &c
+}
+
 // creates a new JsonInt from any compatible value (see the .Set() method)
 func NewJsonInt(v interface{}) *JsonInt { return new(JsonInt).Set(v).(*JsonInt) }
 
@@ -129,38 +177,55 @@ func (self *JsonFloat) Json() string {
 	return fmt.Sprintf("%f", *self)
 }
 
-// one can .Set() JsonFloat from any Go float (float32 or float64) or from a string
+// one can .Set() JsonFloat from any Go float or integer (see ToFloat()) or from
+// a string; it panics on anything else, on precision loss, as well as on a bad string
 func (self *JsonFloat) Set(v interface{}) JsonValue {
-	var t float64
-	switch iv := v.(type) {
-	case float32:
-		t = float64(v.(float32))
-	case float64:
-		t = iv
+	must(self.TrySet(v))
+	return self
+}
+
+// like .Set(), but returns an error instead of panic
+func (self *JsonFloat) TrySet(v interface{}) error {
+	switch v.(type) {
 	case string:
-		self.Parse(v.(string))
-		return self
-	default:
-		panic(v)
+		return self.Parse(v.(string))
+	case bool, nil:
+		return newValueError("cannot %T.Set(%T)", self, v)
+	}
+	t, e := ToFloat(v)
+	if e != nil {
+		return newValueError("cannot %T.Set(%T): %v", self, v, e)
 	}
 	*self = (JsonFloat)(t)
-	return self
+	return nil
 }
 
 // return the value as Go's float64
 func (self *JsonFloat) Value() interface{} { return float64(*self) }
 
-// parse the string as decimal float64 and replace the current value
+// parse the string as a JSON number literal and replace the current value
 func (self *JsonFloat) Parse(s string) error {
-	v, e := strconv.ParseFloat(s, 64)
+	v, e := parseFloat(s) // no hex, NaN or infinities
 	if e != nil {
 		return e
 	}
-	*self = (JsonFloat)(v)
-	return nil
+	return self.TrySet(v)
 }
 func (*JsonFloat) Append(interface{})         { panic("Float is immutable") }
 func (*JsonFloat) Insert(string, interface{}) { panic("Float is immutable") }
+func (*JsonFloat) TryAppend(interface{}) error {
+	return newValueError("Float is immutable")
+}
+func (*JsonFloat) TryInsert(string, interface{}) error {
+	return newValueError("Float is immutable")
+}
+func (self *JsonFloat) Clone() JsonValue {
+	if self == nil {
+		return self
+	}
+	c := *self
+	return &c
+}
 
 // creates a new JsonFloat from any compatible value (see the .Set() method)
 func NewJsonFloat(v interface{}) *JsonFloat { return new(JsonFloat).Set(v).(*JsonFloat) }
@@ -193,27 +258,43 @@ func (self *JsonBool) Json() string {
 	return fmt.Sprintf("%v", *self)
 }
 func (self *JsonBool) Set(v interface{}) JsonValue {
+	must(self.TrySet(v))
+	return self
+}
+func (self *JsonBool) TrySet(v interface{}) error {
 	switch v.(type) {
 	case bool:
 		*self = (JsonBool)(v.(bool))
 	case string:
-		self.Parse(v.(string))
+		return self.Parse(v.(string))
 	default:
-		panic(v)
+		return newValueError("cannot %T.Set(%T)", self, v)
 	}
-	return self
+	return nil
 }
 func (self *JsonBool) Value() interface{} { return bool(*self) }
 func (self *JsonBool) Parse(s string) error {
 	v, found := boolStringValues[strings.ToLower(strings.TrimSpace(s))]
 	if !found {
-		panic(fmt.Sprintf("Bool: bad literal %+q", s))
+		return newValueError("Bool: bad literal %+q", s)
 	}
-	self.Set(v)
-	return nil
+	return self.TrySet(v)
 }
 func (*JsonBool) Append(interface{})         { panic("Bool is immutable") }
 func (*JsonBool) Insert(string, interface{}) { panic("Bool is immutable") }
+func (*JsonBool) TryAppend(interface{}) error {
+	return newValueError("Bool is immutable")
+}
+func (*JsonBool) TryInsert(string, interface{}) error {
+	return newValueError("Bool is immutable")
+}
+func (self *JsonBool) Clone() JsonValue {
+	if self == nil {
+		return self
+	}
+	c := *self
+	return &c
+}
 
 func NewJsonBool(v interface{}) *JsonBool { return new(JsonBool).Set(v).(*JsonBool) }
 
@@ -237,19 +318,59 @@ func (self *JsonString) Json() string {
 	if self.IsNull() {
 		return "null"
 	}
-	return fmt.Sprintf("%q", *self)
+	return quote(string(*self))
+}
+
+// like %q, but with the JSON escapes only
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if c < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, c)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
 }
 func (self *JsonString) Set(v interface{}) JsonValue {
+	must(self.TrySet(v))
+	return self
+}
+func (self *JsonString) TrySet(v interface{}) error {
 	switch v.(type) {
 	case string:
 		*self = (JsonString)(v.(string))
 	case *JsonString:
 		oth := v.(*JsonString)
-		self.Set(fmt.Sprintf("%s", *oth)) // not the best conversion...
+		if oth == nil {
+			*self = "" // null
+			return nil
+		}
+		return self.TrySet(fmt.Sprintf("%s", *oth)) // not the best conversion...
 	default:
-		panic(fmt.Sprintf("cannot %T.Set(%T)", self, v))
+		return newValueError("cannot %T.Set(%T)", self, v)
 	}
-	return self
+	return nil
 }
 func (self *JsonString) Value() interface{} { return string(*self) }
 func (self *JsonString) Parse(s string) error {
@@ -260,11 +381,23 @@ func (self *JsonString) Parse(s string) error {
 	if tail != "" {
 		return SyntaxError(fmt.Errorf("Bad string %+q", s))
 	}
-	self.Set(obj)
-	return nil
+	return self.TrySet(obj)
 }
 func (self *JsonString) Append(interface{})         { panic("String is immutable") }
 func (self *JsonString) Insert(string, interface{}) { panic("String is immutable") }
+func (*JsonString) TryAppend(interface{}) error {
+	return newValueError("String is immutable")
+}
+func (*JsonString) TryInsert(string, interface{}) error {
+	return newValueError("String is immutable")
+}
+func (self *JsonString) Clone() JsonValue {
+	if self == nil {
+		return self
+	}
+	c := *self
+	return &c
+}
 
 func NewJsonString(v interface{}) *JsonString { return new(JsonString).Set(v).(*JsonString) }
 
@@ -272,8 +405,11 @@ func NewJsonString(v interface{}) *JsonString { return new(JsonString).Set(v).(*
 
 type JsonArray []JsonValue
 
-func (self *JsonArray) IsNull() bool { return self == nil || (*self) == nil }
-func (self *JsonArray) Equal(v JsonValue) bool {
+func (self *JsonArray) IsNull() bool           { return self == nil || (*self) == nil }
+func (self *JsonArray) Equal(v JsonValue) bool { return self.equal(v, map[[2]interface{}]bool{}) }
+
+// the seen pairs of containers are taken as equal, so that cycles end
+func (self *JsonArray) equal(v JsonValue, seen map[[2]interface{}]bool) bool {
 	switch v.(type) {
 	case nil:
 		return self.IsNull()
@@ -288,6 +424,11 @@ func (self *JsonArray) Equal(v JsonValue) bool {
 		if len(*self) != len(*other) {
 			return false
 		}
+		pair := [2]interface{}{nodeKey(self), nodeKey(other)}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
 		for i, v := range *self {
 			o := (*other)[i]
 			if (v == nil || v.IsNull()) && (o == nil || o.IsNull()) {
@@ -296,7 +437,7 @@ func (self *JsonArray) Equal(v JsonValue) bool {
 			if v == nil || o == nil {
 				return false
 			}
-			if !v.Equal(o) {
+			if !equalMember(v, o, seen) {
 				return false
 			}
 		}
@@ -304,32 +445,50 @@ func (self *JsonArray) Equal(v JsonValue) bool {
 	}
 	return false
 }
-func (self *JsonArray) Json() string {
+
+// panics with a CycleError on a cyclic tree, see Encode()
+func (self *JsonArray) Json() string { return self.json(map[interface{}]bool{}) }
+func (self *JsonArray) json(path map[interface{}]bool) string {
 	if self.IsNull() {
 		return "null"
 	}
+	defer enter(path, self)()
 	var r []string
 	for _, o := range *self {
-		if o == nil {
-			r = append(r, "null")
-		} else {
-			r = append(r, (o.(JsonValue)).Json())
-		}
+		r = append(r, memberJson(o, path))
 	}
 	return "[ " + strings.Join(r, ", ") + " ]"
 }
+
+// NB: the *JsonArray is shared (both see the same elements), use .SetCopy() to avoid it
 func (self *JsonArray) Set(v interface{}) JsonValue {
-	switch v.(type) {
+	must(self.TrySet(v))
+	return self
+}
+func (self *JsonArray) TrySet(v interface{}) error {
+	switch x := v.(type) {
 	case *JsonArray:
-		*self = *(v.(*JsonArray))
+		if x == nil {
+			*self = nil // null
+			return nil
+		}
+		for _, o := range *x {
+			if reaches(o, self) {
+				return CycleError(fmt.Errorf("cannot %T.Set(): a cycle", self))
+			}
+		}
+		*self = *x
 	case []JsonValue:
-		for _, x := range v.([]JsonValue) {
-			self.Append(x)
+		for _, o := range x { // all or nothing
+			if o != nil && reaches(o, self) {
+				return CycleError(fmt.Errorf("cannot %T.Append(): a cycle", self))
+			}
 		}
+		*self = append(*self, x...)
 	default:
-		panic("cannot")
+		return newValueError("cannot %T.Set(%T)", self, v)
 	}
-	return self
+	return nil
 }
 func (self *JsonArray) Value() interface{} { return *self }
 func (self *JsonArray) Parse(s string) error {
@@ -340,17 +499,52 @@ func (self *JsonArray) Parse(s string) error {
 	if tail != "" {
 		return SyntaxError(fmt.Errorf("Bad array %+q", s))
 	}
-	self.Set(obj)
-	return nil
+	return self.TrySet(obj)
 }
-func (self *JsonArray) Append(v interface{}) {
+func (self *JsonArray) Append(v interface{}) { must(self.TryAppend(v)) }
+func (self *JsonArray) TryAppend(v interface{}) error {
 	if v == nil {
 		*self = append(*self, nil)
-	} else {
-		*self = append(*self, v.(JsonValue))
+		return nil
 	}
+	x, ok := v.(JsonValue)
+	if !ok {
+		return newValueError("cannot %T.Append(%T)", self, v)
+	}
+	if reaches(x, self) {
+		return CycleError(fmt.Errorf("cannot %T.Append(): a cycle", self))
+	}
+	*self = append(*self, x)
+	return nil
 }
 func (*JsonArray) Insert(string, interface{}) { panic("arrays are not insertable") }
+func (*JsonArray) TryInsert(string, interface{}) error {
+	return newValueError("arrays are not insertable")
+}
+
+// the elements are cloned as well
+func (self *JsonArray) Clone() JsonValue {
+	if self == nil {
+		return self
+	}
+	if *self == nil {
+		return new(JsonArray)
+	}
+	c := make(JsonArray, len(*self))
+	for i, o := range *self {
+		if o != nil {
+			c[i] = o.Clone()
+		}
+	}
+	return &c
+}
+
+// like .Set(), but the result shares nothing with v
+func (self *JsonArray) SetCopy(v interface{}) JsonValue {
+	self.Set(v)
+	*self = *self.Clone().(*JsonArray)
+	return self
+}
 
 func NewJsonArray(v interface{}) *JsonArray { return new(JsonArray).Set(v).(*JsonArray) }
 
@@ -359,7 +553,7 @@ type JsonObject map[string]JsonValue
 
 func (self *JsonObject) IsNull() bool { return self == nil || (*self) == nil }
 
-func cmpMap(m1, m2 map[string]JsonValue) bool {
+func cmpMap(m1, m2 map[string]JsonValue, seen map[[2]interface{}]bool) bool {
 	if len(m1) != len(m2) {
 		return false
 	}
@@ -371,14 +565,20 @@ func cmpMap(m1, m2 map[string]JsonValue) bool {
 		if (v == nil || v.IsNull()) && (o == nil || o.IsNull()) {
 			continue
 		}
-		if !v.Equal(o) {
+		if v == nil || o == nil {
+			return false
+		}
+		if !equalMember(v, o, seen) {
 			return false
 		}
 	}
 	return true
 }
 
-func (self *JsonObject) Equal(v JsonValue) bool {
+func (self *JsonObject) Equal(v JsonValue) bool { return self.equal(v, map[[2]interface{}]bool{}) }
+
+// the seen pairs of containers are taken as equal, so that cycles end
+func (self *JsonObject) equal(v JsonValue, seen map[[2]interface{}]bool) bool {
 	switch v.(type) {
 	case nil:
 		return self.IsNull()
@@ -390,49 +590,69 @@ func (self *JsonObject) Equal(v JsonValue) bool {
 		if self.IsNull() {
 			return false
 		}
-		if cmpMap(*self, *other) && cmpMap(*other, *self) {
+		pair := [2]interface{}{nodeKey(self), nodeKey(other)}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+		if cmpMap(*self, *other, seen) && cmpMap(*other, *self, seen) {
 			return true
 		}
 	}
 	return false
 }
-func (self *JsonObject) Json() string {
+
+// panics with a CycleError on a cyclic tree, see Encode()
+func (self *JsonObject) Json() string { return self.json(map[interface{}]bool{}) }
+func (self *JsonObject) json(path map[interface{}]bool) string {
 	if self == nil || *self == nil {
 		return "null"
 	}
+	defer enter(path, self)()
 	var r, keys []string
 	for k, _ := range *self {
 		keys = append(keys, k)
 	}
 	sort.Strings(keys)
 	for _, k := range keys {
-		o := (*self)[k]
-		var v string
-		if o == nil {
-			v = fmt.Sprintf("%q: null", k)
-		} else {
-			v = fmt.Sprintf("%q: %s", k, (o.(JsonValue)).Json())
-		}
-		r = append(r, v)
+		r = append(r, quote(k)+": "+memberJson((*self)[k], path))
 	}
 	return "{ " + strings.Join(r, ", ") + " }"
 }
+
+// NB: the *JsonObject and JsonObject are shared (both see the same map), use .SetCopy() to avoid it
 func (self *JsonObject) Set(v interface{}) JsonValue {
-	switch v.(type) {
+	must(self.TrySet(v))
+	return self
+}
+func (self *JsonObject) TrySet(v interface{}) error {
+	switch x := v.(type) {
 	case *JsonObject:
-		*self = *(v.(*JsonObject))
+		if x == nil {
+			*self = nil // null
+			return nil
+		}
+		return self.TrySet(*x)
 	case JsonObject:
-		*self = v.(JsonObject)
+		for _, o := range x {
+			if reaches(o, self) {
+				return CycleError(fmt.Errorf("cannot %T.Set(): a cycle", self))
+			}
+		}
+		*self = x
 	case map[string]JsonValue:
-		*self = nil
-		*self = make(map[string]JsonValue)
-		for k, v := range v.(map[string]JsonValue) {
-			self.Insert(k, v)
+		m := make(map[string]JsonValue, len(x)) // self stays intact on errors
+		for k, o := range x {
+			if o != nil && reaches(o, self) {
+				return CycleError(fmt.Errorf("cannot %T.Insert(%+q): a cycle", self, k))
+			}
+			m[k] = o
 		}
+		*self = m
 	default:
-		panic(v)
+		return newValueError("cannot %T.Set(%T)", self, v)
 	}
-	return self
+	return nil
 }
 func (self *JsonObject) Value() interface{} { return map[string]JsonValue(*self) }
 func (self *JsonObject) Parse(s string) error {
@@ -443,25 +663,55 @@ func (self *JsonObject) Parse(s string) error {
 	if tail != "" {
 		return SyntaxError(fmt.Errorf("Bad object %+q", s))
 	}
-	self.Set(obj)
-	return nil
+	return self.TrySet(obj)
 }
 func (JsonObject) Append(v interface{}) { panic("objects are not appendable") }
-func (self *JsonObject) Insert(n string, v interface{}) {
+func (JsonObject) TryAppend(v interface{}) error {
+	return newValueError("objects are not appendable")
+}
+func (self *JsonObject) Insert(n string, v interface{}) { must(self.TryInsert(n, v)) }
+func (self *JsonObject) TryInsert(n string, v interface{}) error {
+	var x JsonValue
+	if v != nil {
+		var ok bool
+		if x, ok = v.(JsonValue); !ok {
+			return newValueError("cannot %T.Insert(%+q, %T)", self, n, v)
+		}
+		if reaches(x, self) {
+			return CycleError(fmt.Errorf("cannot %T.Insert(%+q): a cycle", self, n))
+		}
+	}
 	if *self == nil {
 		*self = make(map[string]JsonValue)
 	}
-	if v == nil {
-		(*self)[n] = nil
-	} else {
-		switch v.(type) {
-		case *JsonObject:
-			if self == v.(*JsonObject) {
-				panic("Ooops!")
-			}
+	(*self)[n] = x
+	return nil
+}
+
+// the members are cloned as well
+func (self *JsonObject) Clone() JsonValue {
+	if self == nil {
+		return self
+	}
+	if *self == nil {
+		return new(JsonObject)
+	}
+	c := make(JsonObject, len(*self))
+	for k, o := range *self {
+		if o != nil {
+			c[k] = o.Clone()
+		} else {
+			c[k] = nil
 		}
-		(*self)[n] = v.(JsonValue)
 	}
+	return &c
+}
+
+// like .Set(), but the result shares nothing with v
+func (self *JsonObject) SetCopy(v interface{}) JsonValue {
+	self.Set(v)
+	*self = *self.Clone().(*JsonObject)
+	return self
 }
 
 func NewJsonObject(o map[string]JsonValue) *JsonObject {
@@ -0,0 +1,124 @@
+// Walking over JsonValue trees and rebuilding them
+package json
+
+import (
+	"errors"
+	"fmt"
+)
+
+type WalkOrder int
+
+const (
+	PreOrder  WalkOrder = iota // a node is visited before its children
+	PostOrder                  // a node is visited after its children
+)
+
+// the WalkFunc may return these to control the walk
+var (
+	SkipChildren = errors.New("skip children") // do not descend (pre-order only)
+	StopWalk     = errors.New("stop walk")     // stop right now, Walk() returns nil
+)
+
+// the Transform() function may return this to remove the node from its parent
+var DropValue = errors.New("drop value")
+
+// called for every node with its JSON Pointer and the container holding it (nil for the root)
+type WalkFunc func(path string, v, parent JsonValue) error
+
+// visits every node in pre-order, see WalkWith()
+func Walk(v JsonValue, fn WalkFunc) error { return WalkWith(v, PreOrder, fn) }
+
+// visits every node (nulls included) in a deterministic order: array elements
+// by index, object members by the sorted keys; any error but SkipChildren and
+// StopWalk ends the walk and is returned
+func WalkWith(v JsonValue, order WalkOrder, fn WalkFunc) error {
+	e := walk(nil, v, nil, order, fn)
+	if e == StopWalk {
+		return nil
+	}
+	return e
+}
+
+func walk(path []string, v, parent JsonValue, order WalkOrder, fn WalkFunc) error {
+	p := joinPointer(path)
+	if order == PreOrder {
+		if e := fn(p, v, parent); e == SkipChildren {
+			return nil
+		} else if e != nil {
+			return e
+		}
+	}
+	switch c := v.(type) {
+	case *JsonArray:
+		if !c.IsNull() {
+			for i, o := range *c {
+				if e := walk(subPath(path, fmt.Sprint(i)), o, v, order, fn); e != nil {
+					return e
+				}
+			}
+		}
+	case *JsonObject:
+		if !c.IsNull() {
+			for _, k := range sortedKeys(*c) {
+				if e := walk(subPath(path, k), (*c)[k], v, order, fn); e != nil {
+					return e
+				}
+			}
+		}
+	}
+	if order == PostOrder {
+		if e := fn(p, v, parent); e != nil && e != SkipChildren {
+			return e
+		}
+	}
+	return nil
+}
+
+// called by Transform() for every node, its result replaces the node
+type TransformFunc func(path string, v JsonValue) (JsonValue, error)
+
+// rebuilds a copy of the tree bottom-up: fn gets every node (after its children
+// have been replaced) and returns the replacement, or DropValue to remove it;
+// any other error stops the transformation and is returned; v is never touched
+func Transform(v JsonValue, fn TransformFunc) (JsonValue, error) {
+	r, e := transform(nil, deepCopy(v), fn)
+	if e == DropValue {
+		return nil, nil
+	}
+	return r, e
+}
+
+func transform(path []string, v JsonValue, fn TransformFunc) (JsonValue, error) {
+	switch c := v.(type) {
+	case *JsonArray:
+		if !c.IsNull() {
+			r := make(JsonArray, 0, len(*c))
+			for i, o := range *c {
+				x, e := transform(subPath(path, fmt.Sprint(i)), o, fn)
+				if e == DropValue {
+					continue
+				}
+				if e != nil {
+					return nil, e
+				}
+				r = append(r, x)
+			}
+			*c = r
+		}
+	case *JsonObject:
+		if !c.IsNull() {
+			for _, k := range sortedKeys(*c) {
+				x, e := transform(subPath(path, k), (*c)[k], fn)
+				if e == DropValue {
+					delete(*c, k)
+					continue
+				}
+				if e != nil {
+					return nil, e
+				}
+				(*c)[k] = x
+			}
+		}
+	}
+	return fn(joinPointer(path), v)
+}
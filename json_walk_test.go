@@ -0,0 +1,98 @@
+package json
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	doc, _, err := ParseValue(`{ "b": [ 1, { "x": null } ], "a": "s", "c": { "d": true } }`)
+	if err != nil {
+		t.Fatalf("ParseValue(): %v", err)
+	}
+
+	test := func(order WalkOrder, skip, stop string, expected string) {
+		var seen []string
+		err := WalkWith(doc, order, func(path string, v, parent JsonValue) error {
+			if path != "" && parent == nil {
+				t.Errorf("%+q has no parent", path)
+			}
+			seen = append(seen, path)
+			switch path {
+			case skip:
+				return SkipChildren
+			case stop:
+				return StopWalk
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("WalkWith(): %v", err)
+		}
+		if s := strings.Join(seen, " "); s != expected {
+			t.Errorf("WalkWith(%v, %+q, %+q): %s, not %s", order, skip, stop, s, expected)
+		}
+	}
+	test(PreOrder, "-", "-", ` /a /b /b/0 /b/1 /b/1/x /c /c/d`)
+	test(PostOrder, "-", "-", `/a /b/0 /b/1/x /b/1 /b /c/d /c `)
+	test(PreOrder, "/b", "-", ` /a /b /c /c/d`)
+	test(PreOrder, "-", "/b/0", ` /a /b /b/0`)
+	test(PostOrder, "/b/1", "/b", `/a /b/0 /b/1/x /b/1 /b`)
+
+	oops := errors.New("oops")
+	if err := Walk(doc, func(path string, v, parent JsonValue) error { return oops }); err != oops {
+		t.Errorf("Walk(): %v", err)
+	}
+
+	// count the leaves, redact the strings, drop the nulls
+	leaves := 0
+	Walk(doc, func(path string, v, parent JsonValue) error {
+		switch v.(type) {
+		case *JsonArray, *JsonObject:
+		default:
+			leaves++
+		}
+		return nil
+	})
+	if leaves != 4 {
+		t.Errorf("Walk(): %d leaves", leaves)
+	}
+	before := doc.Json()
+	r, err := Transform(doc, func(path string, v JsonValue) (JsonValue, error) {
+		if v == nil {
+			return nil, DropValue
+		}
+		if _, ok := v.(*JsonString); ok {
+			return NewJsonString("***"), nil
+		}
+		if path == "/b/0" {
+			return nil, DropValue
+		}
+		return v, nil
+	})
+	if err != nil || r.Json() != `{ "a": "***", "b": [ {  } ], "c": { "d": true } }` {
+		t.Errorf("Transform() = %s, %v", jsonOf(r), err)
+	}
+	if doc.Json() != before {
+		t.Errorf("Transform() altered the doc: %s", doc.Json())
+	}
+	if r, err := Transform(doc, func(string, JsonValue) (JsonValue, error) { return nil, oops }); err != oops || r != nil {
+		t.Errorf("Transform() = %s, %v", jsonOf(r), err)
+	}
+	if r, err := Transform(doc, func(string, JsonValue) (JsonValue, error) { return nil, DropValue }); err != nil || r != nil {
+		t.Errorf("Transform() = %s, %v", jsonOf(r), err)
+	}
+
+	// an array emptied of all its elements stays an array
+	ints := NewJsonObject(map[string]JsonValue{"a": NewJsonArray([]JsonValue{NewJsonInt(1), NewJsonInt(2)})})
+	r, err = Transform(ints, func(path string, v JsonValue) (JsonValue, error) {
+		if _, ok := v.(*JsonInt); ok {
+			return nil, DropValue
+		}
+		return v, nil
+	})
+	if s, _ := Format(r, ""); err != nil || s != `{"a":[]}` {
+		t.Errorf("Transform() = %s, %v", s, err)
+	}
+}
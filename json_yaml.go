@@ -0,0 +1,1059 @@
+// YAML 1.2 input and output
+package json
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type YamlError error
+
+// parses one YAML 1.2 document: block and flow collections, plain, quoted and
+// block (| and >) scalars, anchors and aliases, the core schema (plain 1 is
+// an int, 1.0 a float, true a bool, ~ a null...) and the !!str, !!int,
+// !!float, !!bool, !!null, !!seq and !!map tags; an alias gets a copy of
+// the anchored value (the copies are capped, see yamlAliasNodes); the errors
+// are *PositionError
+func ParseYaml(s string) (JsonValue, error) {
+	p := &yamlParser{s: strings.ReplaceAll(s, "\r\n", "\n"), anchors: map[string]JsonValue{}}
+	if strings.HasPrefix(p.s, "\ufeff") {
+		p.pos = len("\ufeff")
+	}
+	return p.document()
+}
+
+// renders the value as block-style YAML with the members sorted by key; the
+// strings that would read as something else are quoted, the floats keep
+// their ".0" and the multi-line strings go as | blocks
+func FormatYaml(v JsonValue) (string, error) {
+	if e := FindCycle(v); e != nil {
+		return "", e
+	}
+	var b strings.Builder
+	if yamlCollection(v) {
+		yamlMembers(&b, v, "", false)
+	} else {
+		b.WriteString(yamlScalar(v) + "\n")
+	}
+	return b.String(), nil
+}
+
+/*----------------------------------------------------------------------------*/
+
+// non-empty arrays and objects go as blocks, the rest is written in line
+func yamlCollection(v JsonValue) bool {
+	switch x := v.(type) {
+	case *JsonArray:
+		return !isNullValue(v) && len(*x) > 0
+	case *JsonObject:
+		return !isNullValue(v) && len(*x) > 0
+	}
+	return false
+}
+
+// the members (or the elements) one per line at the indentation; the first
+// one goes right where the writing is when inline (after "- ")
+func yamlMembers(b *strings.Builder, v JsonValue, indent string, inline bool) {
+	first := true
+	line := func() {
+		if !first || !inline {
+			b.WriteString(indent)
+		}
+		first = false
+	}
+	switch x := v.(type) {
+	case *JsonObject:
+		for _, k := range sortedKeys(*x) {
+			line()
+			b.WriteString(yamlString(k) + ":")
+			yamlValue(b, (*x)[k], indent, false)
+		}
+	case *JsonArray:
+		for _, o := range *x {
+			line()
+			b.WriteString("-")
+			yamlValue(b, o, indent, true)
+		}
+	}
+}
+
+// the value after "key:" or "-": the scalars on the same line, a collection
+// on the lines below (or compact after "-"), a multi-line string as a block
+func yamlValue(b *strings.Builder, v JsonValue, indent string, dash bool) {
+	switch {
+	case yamlCollection(v) && dash:
+		b.WriteString(" ")
+		yamlMembers(b, v, indent+"  ", true)
+	case yamlCollection(v):
+		b.WriteString("\n")
+		yamlMembers(b, v, indent+"  ", false)
+	default:
+		if s, ok := v.(*JsonString); ok && yamlLiteral(string(*s)) {
+			yamlBlock(b, string(*s), indent+"  ")
+			return
+		}
+		b.WriteString(" " + yamlScalar(v) + "\n")
+	}
+}
+
+// whether the string can go as a | block: several lines, all printable, the
+// first one not starting with a space (that would need an indentation indicator)
+func yamlLiteral(s string) bool {
+	t := strings.TrimLeft(s, "\n")
+	if !strings.Contains(strings.TrimRight(s, "\n"), "\n") || t == "" || t[0] == ' ' || t[0] == '\t' {
+		return false
+	}
+	for _, c := range s {
+		if c != '\n' && c != '\t' && !unicode.IsPrint(c) {
+			return false
+		}
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if line != "" && strings.TrimSpace(line) == "" {
+			return false // would go as the trailing or the indentation spaces
+		}
+	}
+	return true
+}
+
+func yamlBlock(b *strings.Builder, s, indent string) {
+	body := strings.TrimRight(s, "\n")
+	switch trailing := len(s) - len(body); {
+	case trailing == 0:
+		b.WriteString(" |-\n")
+	case trailing == 1:
+		b.WriteString(" |\n")
+	default:
+		b.WriteString(" |+\n")
+		body = s[:len(s)-1]
+	}
+	for _, line := range strings.Split(body, "\n") {
+		if line != "" {
+			b.WriteString(indent + line)
+		}
+		b.WriteString("\n")
+	}
+}
+
+func yamlScalar(v JsonValue) string {
+	if isNullValue(v) {
+		return "null"
+	}
+	switch x := v.(type) {
+	case *JsonFloat:
+		return formatFloat(float64(*x))
+	case *JsonString:
+		return yamlString(string(*x))
+	case *JsonArray:
+		return "[]"
+	case *JsonObject:
+		return "{}"
+	}
+	return v.Json()
+}
+
+// the string plain if it reads back as the same string, quoted otherwise
+func yamlString(s string) string {
+	if yamlPlain(s) {
+		return s
+	}
+	return quote(s)
+}
+
+// the plain scalars that YAML 1.1 readers take for booleans
+var yaml11Bools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true,
+	"n": true, "N": true, "no": true, "No": true, "NO": true,
+	"on": true, "On": true, "ON": true, "off": true, "Off": true, "OFF": true,
+}
+
+func yamlPlain(s string) bool {
+	if s == "" || s != strings.TrimSpace(s) || yaml11Bools[s] || s == "<<" {
+		return false
+	}
+	if v, e := yamlResolve(s); e != nil || v == nil {
+		return false
+	} else if _, isString := v.(*JsonString); !isString {
+		return false
+	}
+	if strings.IndexByte("-?:,[]{}#&*!|>'\"%@`", s[0]) >= 0 {
+		return false
+	}
+	// the numbers, dates and times of YAML 1.1 and anything like them
+	if isDigit(s[0]) || len(s) > 1 && strings.IndexByte("+-.", s[0]) >= 0 && (isDigit(s[1]) || s[1] == '.') {
+		return false
+	}
+	if strings.Contains(s, ": ") || strings.Contains(s, " #") || strings.HasSuffix(s, ":") {
+		return false
+	}
+	for _, c := range s {
+		if c != ' ' && !unicode.IsPrint(c) {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	yamlNull  = regexp.MustCompile(`^(~|null|Null|NULL|)$`)
+	yamlBool  = regexp.MustCompile(`^(true|True|TRUE|false|False|FALSE)$`)
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlOct   = regexp.MustCompile(`^0o[0-7]+$`)
+	yamlHex   = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlInf   = regexp.MustCompile(`^([-+]?\.(inf|Inf|INF)|\.(nan|NaN|NAN))$`)
+)
+
+// the value of a plain scalar by the core schema; fails on the numbers
+// that JSON cannot have (infinities, NaN, overflows)
+func yamlResolve(s string) (JsonValue, error) {
+	switch {
+	case yamlNull.MatchString(s):
+		return nil, nil
+	case yamlBool.MatchString(s):
+		return NewJsonBool(s[0] == 't' || s[0] == 'T'), nil
+	case yamlInt.MatchString(s), yamlOct.MatchString(s), yamlHex.MatchString(s):
+		base, digits := 10, s
+		if yamlOct.MatchString(s) {
+			base, digits = 8, s[2:]
+		} else if yamlHex.MatchString(s) {
+			base, digits = 16, s[2:]
+		}
+		i, e := strconv.ParseInt(digits, base, 0)
+		if e != nil {
+			return nil, NumberError(fmt.Errorf("%s overflows int", s))
+		}
+		return NewJsonInt(int(i)), nil
+	case yamlFloat.MatchString(s):
+		f, e := strconv.ParseFloat(s, 64)
+		if e != nil || math.IsInf(f, 0) {
+			return nil, NumberError(fmt.Errorf("%s overflows float", s))
+		}
+		return NewJsonFloat(f), nil
+	case yamlInf.MatchString(s):
+		return nil, NumberError(fmt.Errorf("%s is not a JSON number", s))
+	}
+	return NewJsonString(s), nil
+}
+
+/*----------------------------------------------------------------------------*/
+
+type yamlParser struct {
+	s       string
+	pos     int
+	anchors map[string]JsonValue
+	copied  int // the nodes the aliases made so far
+}
+
+// how many nodes the aliases may make: the nested ones grow exponentially
+const yamlAliasNodes = 100000
+
+// what a node is before its tag and anchor apply: a value done already (a
+// collection or an alias) or the text of a scalar
+type yamlNode struct {
+	value JsonValue
+	done  bool
+	text  string
+	plain bool
+}
+
+func (self *yamlParser) error(format string, args ...interface{}) error {
+	return positionError(self.s, self.pos, YamlError(fmt.Errorf(format, args...)))
+}
+
+func (self *yamlParser) at(i int) byte {
+	if i += self.pos; i >= 0 && i < len(self.s) {
+		return self.s[i]
+	}
+	return 0
+}
+
+func (self *yamlParser) peek() byte { return self.at(0) }
+
+// whether there is a space, a line break or the end at pos+i
+func (self *yamlParser) blankAt(i int) bool {
+	c := self.at(i)
+	return c == 0 || c == ' ' || c == '\t' || c == '\n'
+}
+
+func (self *yamlParser) atBreak() bool { return self.pos >= len(self.s) || self.peek() == '\n' }
+
+func (self *yamlParser) column() int {
+	return self.pos - (strings.LastIndexByte(self.s[:self.pos], '\n') + 1)
+}
+
+// "---" or "..." starting a line
+func (self *yamlParser) marker(m string) bool {
+	return self.column() == 0 && strings.HasPrefix(self.s[self.pos:], m) && self.blankAt(len(m))
+}
+
+// skips the spaces on the line
+func (self *yamlParser) space() {
+	for c := self.peek(); c == ' ' || c == '\t'; c = self.peek() {
+		self.pos++
+	}
+}
+
+// skips the comment up to the line break
+func (self *yamlParser) comment() {
+	if self.peek() == '#' && (self.pos == 0 || self.blankAt(-1)) {
+		for !self.atBreak() {
+			self.pos++
+		}
+	}
+}
+
+// skips the spaces, the comments and the line breaks up to the next content
+func (self *yamlParser) skip() error {
+	for self.pos < len(self.s) {
+		switch self.peek() {
+		case ' ', '\t', '\n':
+			self.pos++
+		case '#':
+			self.comment()
+		default:
+			start := strings.LastIndexByte(self.s[:self.pos], '\n') + 1
+			if indent := self.s[start:self.pos]; strings.Contains(indent, "\t") && strings.TrimSpace(indent) == "" {
+				return self.error("Tabs are not allowed for indentation")
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// the name of an anchor or an alias
+func (self *yamlParser) name() (string, error) {
+	start := self.pos
+	for !self.blankAt(0) && strings.IndexByte(",[]{}", self.peek()) < 0 {
+		self.pos++
+	}
+	if self.pos == start {
+		return "", self.error("Expected a name")
+	}
+	return self.s[start:self.pos], nil
+}
+
+// the &anchor and the !tag of a node, if any
+func (self *yamlParser) properties() (anchor, tag string, e error) {
+	for {
+		switch self.peek() {
+		case '&':
+			if anchor != "" {
+				return "", "", self.error("Two anchors")
+			}
+			self.pos++
+			if anchor, e = self.name(); e != nil {
+				return "", "", e
+			}
+		case '!':
+			if tag != "" {
+				return "", "", self.error("Two tags")
+			}
+			start := self.pos
+			for !self.blankAt(0) && strings.IndexByte(",[]{}", self.peek()) < 0 {
+				self.pos++
+			}
+			tag = self.s[start:self.pos]
+			if strings.HasPrefix(tag, "!<") {
+				self.pos = start
+				return "", "", self.error("Unsupported tag %s", tag)
+			}
+		default:
+			return
+		}
+		self.space()
+	}
+}
+
+func (self *yamlParser) document() (JsonValue, error) {
+	if e := self.skip(); e != nil {
+		return nil, e
+	}
+	for self.peek() == '%' { // the directives (%YAML 1.2) change nothing here
+		for !self.atBreak() {
+			self.pos++
+		}
+		if e := self.skip(); e != nil {
+			return nil, e
+		}
+	}
+	if self.marker("---") {
+		self.pos += 3
+	}
+	v, e := self.block(-1, false)
+	if e != nil {
+		return nil, e
+	}
+	if e := self.skip(); e != nil {
+		return nil, e
+	}
+	if self.marker("...") {
+		self.pos += 3
+		if e := self.skip(); e != nil {
+			return nil, e
+		}
+	}
+	if self.marker("---") {
+		return nil, self.error("Only one document is supported")
+	}
+	if self.pos < len(self.s) {
+		return nil, self.error("Unexpected %+q", self.line())
+	}
+	return v, nil
+}
+
+// the rest of the line, for the messages
+func (self *yamlParser) line() string {
+	r := self.s[self.pos:]
+	if i := strings.IndexByte(r, '\n'); i >= 0 {
+		r = r[:i]
+	}
+	return r
+}
+
+// a node deeper than indent: on this line (a scalar, a flow collection, or,
+// when compact, a block collection starting right here) or on the lines below
+func (self *yamlParser) block(indent int, compact bool) (JsonValue, error) {
+	self.space()
+	anchor, tag, e := self.properties()
+	if e != nil {
+		return nil, e
+	}
+	self.space()
+	self.comment()
+	if self.atBreak() {
+		if e := self.skip(); e != nil {
+			return nil, e
+		}
+		if self.pos >= len(self.s) || self.column() <= indent || self.marker("---") || self.marker("...") {
+			return self.node(anchor, tag, yamlNode{plain: true}) // empty
+		}
+		compact = true
+	}
+	if start := strings.LastIndexByte(self.s[:self.pos], '\n') + 1; strings.TrimSpace(self.s[start:self.pos]) == "" {
+		compact = true // the first thing on its line
+	}
+	col := self.column()
+	var n yamlNode
+	switch c := self.peek(); {
+	case c == '-' && self.blankAt(1) && compact:
+		n.value, e = self.sequence(col)
+		n.done = true
+	case c == '|' || c == '>':
+		n.text, e = self.literal(indent)
+	default:
+		if n, e = self.inline(); e != nil {
+			return nil, e
+		}
+		self.space()
+		if self.peek() == ':' && self.blankAt(1) {
+			if !compact {
+				return nil, self.error("Mapping values are not allowed here")
+			}
+			var key string
+			if key, e = self.key(n); e != nil {
+				return nil, e
+			}
+			n = yamlNode{done: true}
+			n.value, e = self.mapping(col, key)
+		} else if n.plain {
+			n.text = self.plainMore(indent, n.text)
+		}
+	}
+	if e != nil {
+		return nil, e
+	}
+	return self.node(anchor, tag, n)
+}
+
+// the value of the node with its tag, remembered under its anchor
+func (self *yamlParser) node(anchor, tag string, n yamlNode) (JsonValue, error) {
+	v := n.value
+	if !n.done {
+		var e error
+		if v, e = self.scalar(n.text, n.plain, tag); e != nil {
+			return nil, e
+		}
+	} else if tag != "" {
+		_, isMap := v.(*JsonObject)
+		_, isSeq := v.(*JsonArray)
+		if !(tag == "!!map" && isMap || tag == "!!seq" && isSeq) {
+			return nil, self.error("Tag %s does not fit %s", tag, jsonOf(v))
+		}
+	}
+	if anchor != "" {
+		self.anchors[anchor] = v
+	}
+	return v, nil
+}
+
+func (self *yamlParser) scalar(text string, plain bool, tag string) (JsonValue, error) {
+	switch tag {
+	case "":
+		if !plain {
+			return NewJsonString(text), nil
+		}
+		v, e := yamlResolve(text)
+		if e != nil {
+			return nil, self.error("%v", e)
+		}
+		return v, nil
+	case "!", "!!str":
+		return NewJsonString(text), nil
+	case "!!null", "!!bool", "!!int", "!!float":
+		v, e := yamlResolve(text)
+		if e != nil {
+			return nil, self.error("%v", e)
+		}
+		var ok bool
+		switch x := v.(type) {
+		case nil:
+			ok = tag == "!!null"
+		case *JsonBool:
+			ok = tag == "!!bool"
+		case *JsonInt:
+			if ok = tag == "!!float"; ok {
+				v = NewJsonFloat(float64(*x))
+			} else {
+				ok = tag == "!!int"
+			}
+		case *JsonFloat:
+			ok = tag == "!!float"
+		}
+		if !ok {
+			return nil, self.error("%+q is not %s", text, tag)
+		}
+		return v, nil
+	case "!!seq", "!!map":
+		if plain && text == "" {
+			if tag == "!!seq" {
+				return &JsonArray{}, nil
+			}
+			return &JsonObject{}, nil
+		}
+		return nil, self.error("%+q is not %s", text, tag)
+	}
+	return nil, self.error("Unsupported tag %s", tag)
+}
+
+// a mapping key: a scalar (or an alias of a string)
+func (self *yamlParser) key(n yamlNode) (string, error) {
+	if !n.done {
+		return n.text, nil
+	}
+	if s, ok := n.value.(*JsonString); ok && s != nil {
+		return string(*s), nil
+	}
+	return "", self.error("Only scalar keys are supported")
+}
+
+// the block mapping at the column, at the ':' after the first key
+func (self *yamlParser) mapping(col int, key string) (JsonValue, error) {
+	r := JsonObject{}
+	for {
+		if _, exists := r[key]; exists {
+			return nil, self.error("Duplicate key %+q", key)
+		}
+		self.pos++ // ':'
+		self.space()
+		self.comment()
+		var v JsonValue
+		var e error
+		if self.atBreak() { // a sequence may be right under the key
+			save := self.pos
+			if e = self.skip(); e != nil {
+				return nil, e
+			}
+			if self.column() == col && self.peek() == '-' && self.blankAt(1) && !self.marker("---") {
+				v, e = self.sequence(col)
+			} else {
+				self.pos = save
+				v, e = self.block(col, false)
+			}
+		} else {
+			v, e = self.block(col, false)
+		}
+		if e != nil {
+			return nil, e
+		}
+		r[key] = v
+
+		if e := self.skip(); e != nil {
+			return nil, e
+		}
+		if self.pos >= len(self.s) || self.column() < col || self.marker("---") || self.marker("...") {
+			return &r, nil
+		}
+		if self.column() > col {
+			return nil, self.error("Unexpected %+q", self.line())
+		}
+		n, e := self.inline()
+		if e != nil {
+			return nil, e
+		}
+		self.space()
+		if self.peek() != ':' || !self.blankAt(1) {
+			return nil, self.error("Expected ':'")
+		}
+		if key, e = self.key(n); e != nil {
+			return nil, e
+		}
+	}
+}
+
+// the block sequence at the column, at the first '-'
+func (self *yamlParser) sequence(col int) (JsonValue, error) {
+	r := JsonArray{}
+	for {
+		self.pos++ // '-'
+		v, e := self.block(col, true)
+		if e != nil {
+			return nil, e
+		}
+		r = append(r, v)
+
+		if e := self.skip(); e != nil {
+			return nil, e
+		}
+		if self.pos >= len(self.s) || self.column() < col || self.marker("---") || self.marker("...") {
+			return &r, nil
+		}
+		if self.column() > col {
+			return nil, self.error("Unexpected %+q", self.line())
+		}
+		if self.peek() != '-' || !self.blankAt(1) {
+			return &r, nil // the mapping the sequence is in goes on
+		}
+	}
+}
+
+// a scalar, a flow collection or an alias on the current line
+func (self *yamlParser) inline() (yamlNode, error) {
+	switch c := self.peek(); {
+	case c == '[' || c == '{':
+		v, e := self.flow()
+		return yamlNode{value: v, done: true}, e
+	case c == '"':
+		s, e := self.doubleQuoted()
+		return yamlNode{text: s}, e
+	case c == '\'':
+		s, e := self.singleQuoted()
+		return yamlNode{text: s}, e
+	case c == '*':
+		v, e := self.alias()
+		return yamlNode{value: v, done: true}, e
+	case c == '?' && self.blankAt(1):
+		return yamlNode{}, self.error("Complex keys are not supported")
+	case c == '-' && self.blankAt(1):
+		return yamlNode{}, self.error("Sequence entries are not allowed here")
+	case strings.IndexByte("]},#|>@`%&!", c) >= 0:
+		return yamlNode{}, self.error("Unexpected %+q", self.line())
+	}
+	s := self.plain(false)
+	if s == "" {
+		return yamlNode{}, self.error("Expected a value")
+	}
+	return yamlNode{text: s, plain: true}, nil
+}
+
+func (self *yamlParser) alias() (JsonValue, error) {
+	self.pos++
+	start := self.pos
+	name, e := self.name()
+	if e != nil {
+		return nil, e
+	}
+	v, found := self.anchors[name]
+	if !found {
+		self.pos = start
+		return nil, self.error("Unknown alias *%s", name)
+	}
+	if self.copied += yamlCount(v, yamlAliasNodes-self.copied+1); self.copied > yamlAliasNodes {
+		self.pos = start
+		return nil, self.error("The aliases make over %d nodes", yamlAliasNodes)
+	}
+	return deepCopy(v), nil
+}
+
+// the nodes of the value, counted up to the limit
+func yamlCount(v JsonValue, limit int) int {
+	n := 1
+	for _, x := range members(v) {
+		if n >= limit {
+			break
+		}
+		n += yamlCount(x, limit-n)
+	}
+	return n
+}
+
+// one line of a plain scalar, up to ": ", " #" (and the flow indicators in flow)
+func (self *yamlParser) plain(flow bool) string {
+	start, end := self.pos, self.pos
+	for !self.atBreak() {
+		c := self.peek()
+		if c == ':' && (self.blankAt(1) || flow && strings.IndexByte(",[]{}", self.at(1)) >= 0) ||
+			c == '#' && self.pos > start && self.blankAt(-1) ||
+			flow && strings.IndexByte(",[]{}", c) >= 0 {
+			break
+		}
+		self.pos++
+		if c != ' ' && c != '\t' {
+			end = self.pos
+		}
+	}
+	self.pos = end
+	return self.s[start:end]
+}
+
+// the lines of a multi-line plain scalar after its first one: deeper than
+// indent, a line break makes a space, an empty line makes a line break
+func (self *yamlParser) plainMore(indent int, text string) string {
+	for {
+		save := self.pos
+		self.space()
+		if !self.atBreak() {
+			self.pos = save
+			return text
+		}
+		breaks := 0
+		for self.atBreak() && self.pos < len(self.s) {
+			self.pos++
+			breaks++
+			self.space()
+		}
+		if self.pos >= len(self.s) || self.column() <= indent || self.peek() == '#' ||
+			self.marker("---") || self.marker("...") {
+			self.pos = save
+			return text
+		}
+		next := self.plain(false)
+		if next == "" {
+			self.pos = save
+			return text
+		}
+		if breaks == 1 {
+			text += " " + next
+		} else {
+			text += strings.Repeat("\n", breaks-1) + next
+		}
+	}
+}
+
+// in a quoted scalar, at a line break: the break and the spaces around it
+// make a space, or the line breaks of the empty lines
+func (self *yamlParser) fold(b []byte) []byte {
+	b = []byte(strings.TrimRight(string(b), " \t"))
+	breaks := 0
+	for self.atBreak() && self.pos < len(self.s) {
+		self.pos++
+		breaks++
+		self.space()
+	}
+	if breaks == 1 {
+		return append(b, ' ')
+	}
+	return append(b, strings.Repeat("\n", breaks-1)...)
+}
+
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n", 'v': "\v", 'f': "\f",
+	'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"", '/': "/", '\\': "\\",
+	'N': "\u0085", '_': "\u00a0", 'L': "\u2028", 'P': "\u2029",
+}
+
+func (self *yamlParser) doubleQuoted() (string, error) {
+	self.pos++
+	var b []byte
+	for {
+		switch c := self.peek(); {
+		case self.pos >= len(self.s):
+			return "", self.error("Unterminated string")
+		case c == '"':
+			self.pos++
+			return string(b), nil
+		case c == '\n':
+			b = self.fold(b)
+		case c == '\\':
+			self.pos++
+			c = self.peek()
+			self.pos++
+			if s, found := yamlEscapes[c]; found {
+				b = append(b, s...)
+				continue
+			}
+			size := map[byte]int{'x': 2, 'u': 4, 'U': 8}[c]
+			switch {
+			case c == '\n': // an escaped line break joins the lines
+				self.space()
+			case size > 0 && self.pos+size <= len(self.s):
+				r, e := strconv.ParseUint(self.s[self.pos:self.pos+size], 16, 32)
+				if e != nil || !utf8.ValidRune(rune(r)) {
+					return "", self.error("Bad escape \\%c%s", c, self.s[self.pos:self.pos+size])
+				}
+				self.pos += size
+				b = append(b, string(rune(r))...)
+			default:
+				self.pos--
+				return "", self.error("Bad escape '\\%c'", c)
+			}
+		default:
+			b = append(b, c)
+			self.pos++
+		}
+	}
+}
+
+func (self *yamlParser) singleQuoted() (string, error) {
+	self.pos++
+	var b []byte
+	for {
+		switch c := self.peek(); {
+		case self.pos >= len(self.s):
+			return "", self.error("Unterminated string")
+		case c == '\'' && self.at(1) == '\'':
+			b = append(b, '\'')
+			self.pos += 2
+		case c == '\'':
+			self.pos++
+			return string(b), nil
+		case c == '\n':
+			b = self.fold(b)
+		default:
+			b = append(b, c)
+			self.pos++
+		}
+	}
+}
+
+// a | or > block scalar of the node deeper than indent
+func (self *yamlParser) literal(indent int) (string, error) {
+	folded := self.peek() == '>'
+	self.pos++
+	var chomp byte
+	explicit := 0
+	for i := 0; i < 2; i++ {
+		switch c := self.peek(); {
+		case (c == '-' || c == '+') && chomp == 0:
+			chomp = c
+			self.pos++
+		case c >= '1' && c <= '9' && explicit == 0:
+			explicit = int(c - '0')
+			self.pos++
+		}
+	}
+	self.space()
+	self.comment()
+	if !self.atBreak() {
+		return "", self.error("Bad block scalar header")
+	}
+	if self.pos < len(self.s) {
+		self.pos++
+	}
+	// the indentation of the content: given, or that of its first non-empty line
+	least := indent + 1
+	width := 0
+	if explicit > 0 {
+		width = explicit
+		if indent > 0 {
+			width += indent
+		}
+	} else {
+		for _, line := range strings.Split(self.s[self.pos:], "\n") {
+			if strings.TrimLeft(line, " ") != "" {
+				width = len(line) - len(strings.TrimLeft(line, " "))
+				break
+			}
+		}
+	}
+	var lines []string
+	for width >= least && self.pos < len(self.s) {
+		end := strings.IndexByte(self.s[self.pos:], '\n')
+		if end < 0 {
+			end = len(self.s)
+		} else {
+			end += self.pos
+		}
+		line := self.s[self.pos:end]
+		if strings.TrimLeft(line, " ") == "" {
+			if len(line) > width {
+				lines = append(lines, line[width:])
+			} else {
+				lines = append(lines, "")
+			}
+		} else if len(line)-len(strings.TrimLeft(line, " ")) < width {
+			break
+		} else {
+			lines = append(lines, line[width:])
+		}
+		self.pos = end
+		if end < len(self.s) {
+			self.pos++
+		}
+	}
+	last := len(lines)
+	for last > 0 && strings.TrimSpace(lines[last-1]) == "" {
+		last--
+	}
+	trailing := len(lines) - last
+
+	var b strings.Builder
+	empty, previous := 0, ""
+	for i, line := range lines[:last] {
+		if line == "" {
+			empty++
+			continue
+		}
+		more := line[0] == ' ' || line[0] == '\t'
+		switch {
+		case i == empty: // the first one
+			b.WriteString(strings.Repeat("\n", empty))
+		case folded && !more && previous != "" && previous[0] != ' ' && previous[0] != '\t':
+			if empty == 0 {
+				b.WriteByte(' ')
+			} else {
+				b.WriteString(strings.Repeat("\n", empty))
+			}
+		default:
+			b.WriteString(strings.Repeat("\n", empty+1))
+		}
+		b.WriteString(line)
+		empty, previous = 0, line
+	}
+	switch chomp {
+	case '+':
+		if last > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.Repeat("\n", trailing))
+	case 0:
+		if last > 0 {
+			b.WriteByte('\n')
+		}
+	}
+	return b.String(), nil
+}
+
+// skips the spaces, the line breaks and the comments inside a flow collection
+func (self *yamlParser) flowSpace() {
+	for {
+		switch self.peek() {
+		case ' ', '\t', '\n':
+			self.pos++
+		case '#':
+			self.comment()
+			if !self.atBreak() {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// a [...] or {...} collection, any lines long
+func (self *yamlParser) flow() (JsonValue, error) {
+	seq := self.peek() == '['
+	end := byte('}')
+	if seq {
+		end = ']'
+	}
+	self.pos++
+	a, o := JsonArray{}, JsonObject{}
+	for {
+		self.flowSpace()
+		if self.eat(end) {
+			break
+		}
+		if self.pos >= len(self.s) {
+			return nil, self.error("Unterminated flow collection")
+		}
+		n, v, e := self.flowNode()
+		if e != nil {
+			return nil, e
+		}
+		self.flowSpace()
+		var value JsonValue
+		pair := false
+		if self.peek() == ':' {
+			pair = true
+			self.pos++
+			self.flowSpace()
+			if c := self.peek(); c != ',' && c != end {
+				if _, value, e = self.flowNode(); e != nil {
+					return nil, e
+				}
+			}
+		}
+		if seq && !pair {
+			a = append(a, v)
+		} else {
+			key, e := self.key(n)
+			if e != nil {
+				return nil, e
+			}
+			if seq {
+				a = append(a, &JsonObject{key: value})
+			} else if _, exists := o[key]; exists {
+				return nil, self.error("Duplicate key %+q", key)
+			} else {
+				o[key] = value
+			}
+		}
+		self.flowSpace()
+		if !self.eat(',') && self.peek() != end {
+			return nil, self.error("Expected ',' or '%c'", end)
+		}
+	}
+	if seq {
+		return &a, nil
+	}
+	return &o, nil
+}
+
+func (self *yamlParser) eat(c byte) bool {
+	if self.peek() == c {
+		self.pos++
+		return true
+	}
+	return false
+}
+
+// a node in a flow collection: what it is (for the keys) and its value
+func (self *yamlParser) flowNode() (yamlNode, JsonValue, error) {
+	anchor, tag, e := self.properties()
+	if e != nil {
+		return yamlNode{}, nil, e
+	}
+	self.flowSpace()
+	var n yamlNode
+	switch c := self.peek(); {
+	case c == '[' || c == '{':
+		n.value, e = self.flow()
+		n.done = true
+	case c == '"':
+		n.text, e = self.doubleQuoted()
+	case c == '\'':
+		n.text, e = self.singleQuoted()
+	case c == '*':
+		n.value, e = self.alias()
+		n.done = true
+	case c == ',' || c == ']' || c == '}' || c == ':':
+		n.plain = true // empty
+	case strings.IndexByte("#|>@`%&!", c) >= 0 || c == '-' && self.blankAt(1):
+		return yamlNode{}, nil, self.error("Unexpected %+q", self.line())
+	default:
+		n.text, n.plain = self.plain(true), true
+	}
+	if e != nil {
+		return yamlNode{}, nil, e
+	}
+	v, e := self.node(anchor, tag, n)
+	return n, v, e
+}
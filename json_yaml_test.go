@@ -0,0 +1,227 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestYaml(t *testing.T) {
+	config := `%YAML 1.2
+---
+# the service
+name: api   # trailing comment
+version: 1.10
+replicas: 3
+enabled: yes
+debug: false
+timeout: ~
+ratio: 1e3
+mask: 0x1F
+mode: 0o755
+labels: {app: api, tier: "back end", empty: {}}
+ports: [80, 443]
+hosts:
+- a.example.com
+- "b.example.com"
+env:
+  - name: HOME
+    value: /root
+  - {name: PATH, value: '/bin:/usr/bin'}
+defaults: &defaults
+  retries: 2
+  backoff: 1.5
+override: *defaults
+script: |
+  echo one
+    echo two
+
+summary: >-
+  folded
+  text
+
+  here
+quoted: "tab\there \u00e9 \"q\""
+single: 'it''s'
+multi: plain text
+  goes on
+tagged: !!str 123
+forced: !!float 2
+nested:
+  - - 1
+    - 2
+  -
+    - 3
+...
+`
+	want := `{"name": "api", "version": 1.1, "replicas": 3, "enabled": "yes", "debug": false,
+		"timeout": null, "ratio": 1000.0, "mask": 31, "mode": 493,
+		"labels": {"app": "api", "tier": "back end", "empty": {}}, "ports": [80, 443],
+		"hosts": ["a.example.com", "b.example.com"],
+		"env": [{"name": "HOME", "value": "/root"}, {"name": "PATH", "value": "/bin:/usr/bin"}],
+		"defaults": {"retries": 2, "backoff": 1.5}, "override": {"retries": 2, "backoff": 1.5},
+		"script": "echo one\n  echo two\n", "summary": "folded text\nhere",
+		"quoted": "tab\there \u00e9 \"q\"", "single": "it's", "multi": "plain text goes on",
+		"tagged": "123", "forced": 2.0, "nested": [[1, 2], [3]]}`
+	v, e := ParseYaml(config)
+	if e != nil {
+		t.Fatalf("ParseYaml: %v", e)
+	}
+	if !v.Equal(mustParse(t, want)) {
+		s, _ := Format(v, "")
+		t.Errorf("ParseYaml:\n%s", s)
+	}
+	o := v.(*JsonObject)
+	if _, ok := (*o)["ratio"].(*JsonFloat); !ok {
+		t.Errorf("1e3 is %T", (*o)["ratio"])
+	}
+	if _, ok := (*o)["replicas"].(*JsonInt); !ok {
+		t.Errorf("3 is %T", (*o)["replicas"])
+	}
+	// the aliases are copies
+	(*(*o)["override"].(*JsonObject))["retries"] = NewJsonInt(5)
+	if d, _ := o.GetObject("defaults"); d.GetIntOr("retries", 0) != 2 {
+		t.Errorf("the alias shares the anchored value")
+	}
+
+	for _, x := range []struct{ yaml, json string }{
+		{``, `null`},
+		{`42`, `42`},
+		{`- 1`, `[1]`},
+		{"--- [1, {a: b}, [c], d: e]", `[1, {"a": "b"}, ["c"], {"d": "e"}]`},
+		{"a:\n  b:\n    c: 1\n  d: 2\ne: 3", `{"a": {"b": {"c": 1}, "d": 2}, "e": 3}`},
+		{"a: |+\n  x\n\nb: |2\n    y\n", `{"a": "x\n\n", "b": "  y\n"}`},
+		{"- >\n  one\n  two\n\n   more\n  three\n", `["one two\n\n more\nthree\n"]`},
+		{"{a: [1, 2,], \"b\": {c: d}}", `{"a": [1, 2], "b": {"c": "d"}}`},
+		{"a: \"x\n  y\"", `{"a": "x y"}`},
+		{"a: &x 1\nb: [*x, *x]", `{"a": 1, "b": [1, 1]}`},
+		{"? no", ``},
+	} {
+		v, e := ParseYaml(x.yaml)
+		if x.json == "" {
+			if e == nil {
+				t.Errorf("ParseYaml(%+q) did not fail", x.yaml)
+			}
+			continue
+		}
+		if e != nil {
+			t.Errorf("ParseYaml(%+q): %v", x.yaml, e)
+			continue
+		}
+		if w := mustParse(t, x.json); !equalValues(v, w) {
+			s, _ := Format(v, "")
+			t.Errorf("ParseYaml(%+q): %s, not %s", x.yaml, s, x.json)
+		}
+	}
+
+	for _, x := range []struct {
+		yaml         string
+		line, column int
+	}{
+		{"a: 1\nb: *nope", 2, 5},
+		{"a: 1\na: 2", 2, 2},
+		{"a:\n\tb: 1", 2, 2},
+		{"a: 1\n  b: 2", 2, 4},
+		{"a: \"open", 1, 9},
+		{"a: .inf", 1, 8},
+		{"a: 99999999999999999999", 1, 24},
+		{"- 0x8000000000000000", 1, 21},
+		{"a: 1e999", 1, 9},
+		{"a: b: c", 1, 5},
+		{"a: !!int x", 1, 11},
+		{"a: 1\n---\nb: 2", 2, 1},
+		{"[1, 2", 1, 6},
+	} {
+		_, e := ParseYaml(x.yaml)
+		var pe *PositionError
+		if !errors.As(e, &pe) {
+			t.Errorf("ParseYaml(%+q): %v", x.yaml, e)
+		} else if pe.Line != x.line || pe.Column != x.column {
+			t.Errorf("ParseYaml(%+q): at %d:%d, not %d:%d (%v)", x.yaml, pe.Line, pe.Column, x.line, x.column, e)
+		}
+	}
+
+	// the nested aliases are capped, not expanded exponentially
+	bomb := "a0: &a0 [x, x, x, x, x, x, x, x, x, x]\n"
+	for i := 1; i < 10; i++ {
+		bomb += fmt.Sprintf("a%d: &a%d [%s]\n", i, i, strings.TrimSuffix(strings.Repeat(fmt.Sprintf("*a%d, ", i-1), 10), ", "))
+	}
+	started := time.Now()
+	_, e = ParseYaml(bomb)
+	var pe *PositionError
+	if !errors.As(e, &pe) || pe.Line != 5 {
+		t.Errorf("ParseYaml(bomb): %v", e)
+	}
+	if d := time.Since(started); d > time.Second {
+		t.Errorf("ParseYaml(bomb) took %v", d)
+	}
+	if v, e := ParseYaml(strings.Join(strings.Split(bomb, "\n")[:3], "\n")); e != nil || jsonOf(v) == "" {
+		t.Errorf("ParseYaml(small aliases): %v", e)
+	}
+
+	out := mustParse(t, `{"name": "x", "tricky": ["yes", "1.0", "null", "", "- a", "a: b", "#c", "2024-01-01", " pad"],
+		"numbers": [1, 1.0, -2.5, 1e21], "flags": [true, false, null], "empty": {}, "none": [],
+		"text": "line one\nline two\n", "rows": [{"a": 1, "b": [2, 3]}, [4, [5]]]}`)
+	s, e := FormatYaml(out)
+	if e != nil {
+		t.Fatalf("FormatYaml: %v", e)
+	}
+	expect := `empty: {}
+flags:
+  - true
+  - false
+  - null
+name: x
+none: []
+numbers:
+  - 1
+  - 1.0
+  - -2.5
+  - 1e+21
+rows:
+  - a: 1
+    b:
+      - 2
+      - 3
+  - - 4
+    - - 5
+text: |
+  line one
+  line two
+tricky:
+  - "yes"
+  - "1.0"
+  - "null"
+  - null
+  - "- a"
+  - "a: b"
+  - "#c"
+  - "2024-01-01"
+  - " pad"
+`
+	if s != expect {
+		t.Errorf("FormatYaml:\n%s", s)
+	}
+	back, e := ParseYaml(s)
+	if e != nil || !back.Equal(out) {
+		t.Errorf("round trip: %v\n%s", e, jsonOf(back))
+	}
+	if x, _ := Lookup(back, "numbers.1"); x == nil {
+		t.Errorf("no numbers.1")
+	} else if _, ok := x.(*JsonFloat); !ok {
+		t.Errorf("1.0 came back as %T", x)
+	}
+
+	for _, x := range []JsonValue{nil, NewJsonInt(7), NewJsonString("a\nb"), &JsonArray{}, mustParse(t, `[[], {}, "x\n\ny\n\n"]`)} {
+		s, e := FormatYaml(x)
+		if e != nil {
+			t.Errorf("FormatYaml(%s): %v", jsonOf(x), e)
+			continue
+		}
+		if back, e := ParseYaml(s); e != nil || !equalValues(back, x) {
+			t.Errorf("round trip of %s via %+q: %v", jsonOf(x), s, e)
+		}
+	}
+}